@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/templates"
+)
+
+// All API URLs start with this prefix.
+const apiPrefix = "/api/v1/"
+
+// Default and maximum number of tables returned by one request to the tables API.
+const defaultAPITableLimit = 100
+const maxAPITableLimit = 1000
+
+// Sorts for the tables API sort parameter.
+var apiTableOrders = map[string]bqdb.TableOrder{
+	"bytes":         bqdb.OrderTablesByBytes,
+	"long_term":     bqdb.OrderTablesByLongTermBytes,
+	"rows":          bqdb.OrderTablesByRows,
+	"last_modified": bqdb.OrderTablesByLastModified,
+	"id":            bqdb.OrderTablesByID,
+}
+
+// An API error that is returned to the client with an HTTP status code.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func newAPIError(status int, format string, args ...interface{}) *apiError {
+	return &apiError{status, fmt.Sprintf(format, args...)}
+}
+
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type apiLoadStatus struct {
+	Loading bool   `json:"loading"`
+	Percent int    `json:"percent"`
+	Message string `json:"message,omitempty"`
+	// The error from the last load, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+type apiProject struct {
+	ID           string `json:"id"`
+	FriendlyName string `json:"friendly_name"`
+	// The most recent complete snapshot, or 0 if the project has never finished loading.
+	SnapshotID int64          `json:"snapshot_id"`
+	Status     *apiLoadStatus `json:"status"`
+}
+
+type apiProjects struct {
+	Projects []*apiProject `json:"projects"`
+}
+
+type apiDataset struct {
+	ID                       string   `json:"id"`
+	Location                 string   `json:"location"`
+	Bytes                    int64    `json:"bytes"`
+	LongTermBytes            int64    `json:"long_term_bytes"`
+	DollarsPerMonth          float64  `json:"dollars_per_month"`
+	DefaultTableExpirationMs int64    `json:"default_table_expiration_ms,omitempty"`
+	Labels                   []string `json:"labels,omitempty"`
+}
+
+type apiDatasets struct {
+	SnapshotID int64         `json:"snapshot_id"`
+	Datasets   []*apiDataset `json:"datasets"`
+}
+
+type apiTable struct {
+	DatasetID          string  `json:"dataset_id"`
+	TableID            string  `json:"table_id"`
+	Type               string  `json:"type"`
+	Location           string  `json:"location"`
+	Bytes              int64   `json:"bytes"`
+	LongTermBytes      int64   `json:"long_term_bytes"`
+	Rows               int64   `json:"rows"`
+	CreationTimeMs     int64   `json:"creation_time_ms"`
+	LastModifiedTimeMs int64   `json:"last_modified_time_ms"`
+	DollarsPerMonth    float64 `json:"dollars_per_month"`
+}
+
+type apiTables struct {
+	SnapshotID int64       `json:"snapshot_id"`
+	Total      int64       `json:"total"`
+	Tables     []*apiTable `json:"tables"`
+	// The offset of the next page, or omitted if this is the last page.
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+type apiSnapshot struct {
+	ID              int64   `json:"id"`
+	TimeMs          int64   `json:"time_ms"`
+	Bytes           int64   `json:"bytes"`
+	LongTermBytes   int64   `json:"long_term_bytes"`
+	DollarsPerMonth float64 `json:"dollars_per_month"`
+}
+
+type apiSnapshots struct {
+	Snapshots []*apiSnapshot `json:"snapshots"`
+}
+
+func newAPIProject(project *bqdb.Project) *apiProject {
+	return &apiProject{ID: project.ProjectID, FriendlyName: project.FriendlyName,
+		SnapshotID: project.SnapshotID,
+		Status: &apiLoadStatus{Loading: project.IsLoading, Percent: project.LoadingPercent,
+			Message: project.LoadingMessage, Error: project.LoadingError}}
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Printf("bqcost: error writing API response: %s", err.Error())
+	}
+}
+
+// Serves the API for the user authenticated by the googlelogin cookie. Unlike the HTML pages,
+// requests without a valid token get a 401 Unauthorized error and are not redirected.
+func (s *server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	identity, err := s.auth.GetIdentity(r)
+	if err != nil {
+		writeAPIResponse(w, http.StatusUnauthorized, &apiErrorResponse{"not authenticated"})
+		return
+	}
+	s.apiHandler(w, r, identity)
+}
+
+// Routes API requests:
+//
+//	/api/v1/projects
+//	/api/v1/projects/<id>
+//	/api/v1/projects/<id>/status
+//	/api/v1/projects/<id>/datasets?snapshot=<id>
+//	/api/v1/projects/<id>/tables?snapshot=<id>&sort=<order>&offset=<n>&limit=<n>
+//	/api/v1/projects/<id>/snapshots
+func (s *server) apiHandler(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity) {
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+	var response interface{}
+	var err error
+	if r.Method != http.MethodGet {
+		err = newAPIError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	} else if len(parts) == 1 && parts[0] == "projects" {
+		response, err = s.apiProjects(identity)
+	} else if len(parts) == 2 && parts[0] == "projects" && parts[1] != "" {
+		response, err = s.apiProject(identity, parts[1])
+	} else if len(parts) == 3 && parts[0] == "projects" && parts[1] != "" {
+		switch parts[2] {
+		case "status":
+			response, err = s.apiStatus(identity, parts[1])
+		case "datasets":
+			response, err = s.apiDatasets(r, identity, parts[1])
+		case "tables":
+			response, err = s.apiTables(r, identity, parts[1])
+		case "snapshots":
+			response, err = s.apiSnapshots(identity, parts[1])
+		default:
+			err = newAPIError(http.StatusNotFound, "not found: %s", r.URL.Path)
+		}
+	} else {
+		err = newAPIError(http.StatusNotFound, "not found: %s", r.URL.Path)
+	}
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(*apiError); ok {
+			status = apiErr.status
+		} else {
+			log.Printf("%s error %s", r.URL.Path, err.Error())
+		}
+		writeAPIResponse(w, status, &apiErrorResponse{err.Error()})
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, response)
+}
+
+// Returns the user and project, or a 404 error if the project has not been loaded.
+func (s *server) getAPIProject(identity *googlelogin.Identity, projectID string) (*bqdb.User,
+	*bqdb.Project, error) {
+
+	user, err := bqdb.GetUserBySubject(s.dbmap, identity.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	var project *bqdb.Project
+	if user != nil {
+		project, err = bqdb.GetProjectByID(s.dbmap, user.ID, projectID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if project == nil {
+		return nil, nil, newAPIError(http.StatusNotFound, "project %s does not exist", projectID)
+	}
+	return user, project, nil
+}
+
+// Returns the snapshot in the snapshot parameter, or the project's latest snapshot.
+func (s *server) getAPISnapshot(r *http.Request, identity *googlelogin.Identity, projectID string) (
+	*bqdb.Snapshot, error) {
+
+	user, project, err := s.getAPIProject(identity, projectID)
+	if err != nil {
+		return nil, err
+	}
+	snapshotID := project.SnapshotID
+	snapshotParam := r.FormValue("snapshot")
+	if snapshotParam != "" {
+		snapshotID, err = strconv.ParseInt(snapshotParam, 10, 64)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, "invalid snapshot %#v", snapshotParam)
+		}
+	} else if snapshotID == 0 {
+		return nil, newAPIError(http.StatusConflict, "project %s has not finished loading",
+			projectID)
+	}
+	snapshot, err := bqdb.GetSnapshot(s.dbmap, user.ID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil || !snapshot.Complete {
+		return nil, newAPIError(http.StatusNotFound, "snapshot %d for project %s does not exist",
+			snapshotID, projectID)
+	}
+	return snapshot, nil
+}
+
+// Returns an integer parameter between min and max, or defaultValue if it is not set.
+func intParam(r *http.Request, name string, defaultValue int, min int, max int) (int, error) {
+	param := r.FormValue(name)
+	if param == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(param)
+	if err != nil || value < min || value > max {
+		return 0, newAPIError(http.StatusBadRequest, "invalid %s %#v", name, param)
+	}
+	return value, nil
+}
+
+func (s *server) apiProjects(identity *googlelogin.Identity) (*apiProjects, error) {
+	response := &apiProjects{Projects: []*apiProject{}}
+	// users are created when they load their first project
+	user, err := bqdb.GetUserBySubject(s.dbmap, identity.Subject)
+	if err != nil || user == nil {
+		return response, err
+	}
+	projects, err := bqdb.ListProjects(s.dbmap, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		response.Projects = append(response.Projects, newAPIProject(project))
+	}
+	return response, nil
+}
+
+func (s *server) apiProject(identity *googlelogin.Identity, projectID string) (*apiProject, error) {
+	_, project, err := s.getAPIProject(identity, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return newAPIProject(project), nil
+}
+
+func (s *server) apiStatus(identity *googlelogin.Identity, projectID string) (
+	*apiLoadStatus, error) {
+
+	project, err := s.apiProject(identity, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return project.Status, nil
+}
+
+func (s *server) apiDatasets(r *http.Request, identity *googlelogin.Identity, projectID string) (
+	*apiDatasets, error) {
+
+	snapshot, err := s.getAPISnapshot(r, identity, projectID)
+	if err != nil {
+		return nil, err
+	}
+	datasets, err := queryDatasetStorage(s.dbmap, s.prices, snapshot, 0)
+	if err != nil {
+		return nil, err
+	}
+	response := &apiDatasets{SnapshotID: snapshot.ID, Datasets: []*apiDataset{}}
+	for _, dataset := range datasets {
+		response.Datasets = append(response.Datasets, &apiDataset{ID: dataset.ID,
+			Location: dataset.Location, Bytes: dataset.Bytes, LongTermBytes: dataset.LongTermBytes,
+			DollarsPerMonth:          dataset.DollarsPerMonth,
+			DefaultTableExpirationMs: dataset.DefaultTableExpirationMs, Labels: dataset.Labels})
+	}
+	return response, nil
+}
+
+// Returns a page of tables. The sort parameter is one of the keys of apiTableOrders and
+// defaults to bytes.
+func (s *server) apiTables(r *http.Request, identity *googlelogin.Identity, projectID string) (
+	*apiTables, error) {
+
+	sort := r.FormValue("sort")
+	if sort == "" {
+		sort = "bytes"
+	}
+	order, ok := apiTableOrders[sort]
+	if !ok {
+		return nil, newAPIError(http.StatusBadRequest, "invalid sort %#v", sort)
+	}
+	offset, err := intParam(r, "offset", 0, 0, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	// a limit of 0 would return the same next_offset forever
+	limit, err := intParam(r, "limit", defaultAPITableLimit, 1, maxAPITableLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.getAPISnapshot(r, identity, projectID)
+	if err != nil {
+		return nil, err
+	}
+	total, err := bqdb.CountTables(s.dbmap, snapshot.UserID, projectID, snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := bqdb.ListTablesPage(s.dbmap, snapshot.UserID, projectID, snapshot.ID, order,
+		offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	response := &apiTables{SnapshotID: snapshot.ID, Total: total, Tables: []*apiTable{}}
+	for _, table := range tables {
+		response.Tables = append(response.Tables, &apiTable{DatasetID: table.DatasetID,
+			TableID: table.TableID, Type: table.Type, Location: table.Location,
+			Bytes: table.NumBytes, LongTermBytes: table.NumLongTermBytes, Rows: table.NumRows,
+			CreationTimeMs: table.CreationTimeMs, LastModifiedTimeMs: table.LastModifiedTimeMs,
+			DollarsPerMonth: tableStorageCost(s.prices, table, snapshot.TimeMs).Total()})
+	}
+	if int64(offset+len(tables)) < total {
+		response.NextOffset = offset + len(tables)
+	}
+	return response, nil
+}
+
+func (s *server) apiSnapshots(identity *googlelogin.Identity, projectID string) (
+	*apiSnapshots, error) {
+
+	user, _, err := s.getAPIProject(identity, projectID)
+	if err != nil {
+		return nil, err
+	}
+	// without DatasetStorage this only queries the project's history
+	data := &templates.ProjectData{}
+	err = queryHistory(s.dbmap, s.prices, user.ID, projectID, data)
+	if err != nil {
+		return nil, err
+	}
+	response := &apiSnapshots{Snapshots: []*apiSnapshot{}}
+	for _, snapshot := range data.History {
+		response.Snapshots = append(response.Snapshots, &apiSnapshot{ID: snapshot.ID,
+			TimeMs: snapshot.TimeMs, Bytes: snapshot.Bytes, LongTermBytes: snapshot.LongTermBytes,
+			DollarsPerMonth: snapshot.DollarsPerMonth})
+	}
+	return response, nil
+}
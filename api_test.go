@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+)
+
+// Makes an API request and decodes the JSON response into output.
+func apiGet(s *server, identity *googlelogin.Identity, url string, output interface{}) int {
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	s.apiHandler(w, r, identity)
+	if w.Header().Get("Content-Type") != "application/json" {
+		panic("bad content type: " + w.Header().Get("Content-Type"))
+	}
+	err := json.Unmarshal(w.Body.Bytes(), output)
+	if err != nil {
+		panic(err)
+	}
+	return w.Code
+}
+
+func TestAPI(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	// users that have not loaded a project have no projects
+	identity := &googlelogin.Identity{Subject: "subject", Token: &oauth2.Token{AccessToken: "token"}}
+	projects := &apiProjects{}
+	status := apiGet(s, identity, "/api/v1/projects", projects)
+	if !(status == http.StatusOK && projects.Projects != nil && len(projects.Projects) == 0) {
+		t.Error(status, projects)
+	}
+	apiErr := &apiErrorResponse{}
+	status = apiGet(s, identity, "/api/v1/projects/p/tables", apiErr)
+	if !(status == http.StatusNotFound && strings.Contains(apiErr.Error, "does not exist")) {
+		t.Error(status, apiErr)
+	}
+
+	u := &bqdb.User{Subject: identity.Subject, AccessToken: identity.Token.AccessToken}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 2000, Complete: true}
+	err = dbmap.Insert(old, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const gib = 1024 * 1024 * 1024
+	err = dbmap.Insert(
+		&bqdb.Project{UserID: u.ID, ProjectID: "p", FriendlyName: "P", SnapshotID: snapshot.ID,
+			IsLoading: true, LoadingPercent: 50, LoadingMessage: "loading d"},
+		&bqdb.Project{UserID: u.ID, ProjectID: "new", IsLoading: true},
+		&bqdb.Dataset{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			Location: "US"},
+		&bqdb.Label{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			LabelKey: "team", LabelValue: "x"},
+		&bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: old.ID, DatasetID: "d",
+			TableID: "a", Location: "US", NumBytes: gib},
+		&bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			TableID: "a", Location: "US", NumBytes: gib, NumRows: 5},
+		&bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			TableID: "b", Location: "US", NumBytes: 3 * gib, NumLongTermBytes: gib},
+		&bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "e",
+			TableID: "c", Location: "US", NumBytes: 2 * gib},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status = apiGet(s, identity, "/api/v1/projects", projects)
+	if !(status == http.StatusOK && len(projects.Projects) == 2 &&
+		projects.Projects[0].ID == "new" && projects.Projects[0].SnapshotID == 0 &&
+		projects.Projects[1].ID == "p" && projects.Projects[1].FriendlyName == "P") {
+		t.Error(status, projects)
+	}
+
+	loadStatus := &apiLoadStatus{}
+	status = apiGet(s, identity, "/api/v1/projects/p/status", loadStatus)
+	if !(status == http.StatusOK && *loadStatus == apiLoadStatus{Loading: true, Percent: 50,
+		Message: "loading d"}) {
+		t.Error(status, loadStatus)
+	}
+	status = apiGet(s, identity, "/api/v1/projects/new/datasets", apiErr)
+	if !(status == http.StatusConflict && strings.Contains(apiErr.Error, "not finished loading")) {
+		t.Error(status, apiErr)
+	}
+
+	datasets := &apiDatasets{}
+	status = apiGet(s, identity, "/api/v1/projects/p/datasets", datasets)
+	if !(status == http.StatusOK && datasets.SnapshotID == snapshot.ID &&
+		len(datasets.Datasets) == 2 && datasets.Datasets[0].ID == "d" &&
+		datasets.Datasets[0].Bytes == 4*gib && datasets.Datasets[0].LongTermBytes == gib &&
+		datasets.Datasets[0].DollarsPerMonth > 0 && len(datasets.Datasets[0].Labels) == 1 &&
+		datasets.Datasets[1].ID == "e") {
+		t.Error(status, datasets)
+	}
+	status = apiGet(s, identity, "/api/v1/projects/p/datasets?snapshot=999", apiErr)
+	if status != http.StatusNotFound {
+		t.Error(status, apiErr)
+	}
+
+	// page through the tables
+	tables := &apiTables{}
+	status = apiGet(s, identity, "/api/v1/projects/p/tables?limit=2", tables)
+	if !(status == http.StatusOK && tables.Total == 3 && tables.NextOffset == 2 &&
+		len(tables.Tables) == 2 && tables.Tables[0].TableID == "b" &&
+		tables.Tables[1].TableID == "c" && tables.Tables[0].DollarsPerMonth > 0) {
+		t.Error(status, tables)
+	}
+	tables = &apiTables{}
+	status = apiGet(s, identity, "/api/v1/projects/p/tables?limit=2&offset=2", tables)
+	if !(status == http.StatusOK && tables.NextOffset == 0 && len(tables.Tables) == 1 &&
+		tables.Tables[0].TableID == "a" && tables.Tables[0].Rows == 5) {
+		t.Error(status, tables)
+	}
+	tables = &apiTables{}
+	status = apiGet(s, identity, "/api/v1/projects/p/tables?sort=id&snapshot="+
+		strconv.FormatInt(old.ID, 10), tables)
+	if !(status == http.StatusOK && tables.SnapshotID == old.ID && len(tables.Tables) == 1) {
+		t.Error(status, tables)
+	}
+	for _, badParams := range []string{"sort=x", "limit=100000", "limit=0", "offset=-1",
+		"snapshot=x"} {
+		status = apiGet(s, identity, "/api/v1/projects/p/tables?"+badParams, apiErr)
+		if status != http.StatusBadRequest {
+			t.Error(badParams, status, apiErr)
+		}
+	}
+
+	snapshots := &apiSnapshots{}
+	status = apiGet(s, identity, "/api/v1/projects/p/snapshots", snapshots)
+	if !(status == http.StatusOK && len(snapshots.Snapshots) == 2 &&
+		snapshots.Snapshots[0].ID == old.ID && snapshots.Snapshots[0].Bytes == gib &&
+		snapshots.Snapshots[1].ID == snapshot.ID && snapshots.Snapshots[1].Bytes == 6*gib) {
+		t.Error(status, snapshots)
+	}
+
+	for _, path := range []string{"/api/v1/", "/api/v1/projects/", "/api/v1/projects/p/x",
+		"/api/v1/projects/p/tables/x"} {
+		status = apiGet(s, identity, path, apiErr)
+		if status != http.StatusNotFound {
+			t.Error(path, status, apiErr)
+		}
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/projects", nil)
+	w := httptest.NewRecorder()
+	s.apiHandler(w, r, identity)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestAPINotAuthenticated(t *testing.T) {
+	s := &server{auth: newTestAuthenticator()}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil)
+	w := httptest.NewRecorder()
+	s.handleAPI(w, r)
+	if !(w.Code == http.StatusUnauthorized && strings.Contains(w.Body.String(), "not authenticated")) {
+		t.Error(w.Code, w.Body.String())
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/mysql"
 	"github.com/go-gorp/gorp"
@@ -20,6 +23,7 @@ import (
 	"github.com/evanj/bqtools/bqdb"
 	"github.com/evanj/bqtools/bqscrape"
 	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
 	"github.com/evanj/bqtools/templates"
 )
 
@@ -27,6 +31,9 @@ const redirectPath = "/oauth2callback"
 const productionHost = "https://bigquery-tools.appspot-preview.com"
 const maxTopResults = 20
 
+// How often expired login sessions are deleted from the database.
+const sessionExpiryInterval = time.Hour
+
 // For secure cookies. See http://www.gorillatoolkit.org/pkg/securecookie
 func mustDecodeHex(hexString string) []byte {
 	out, err := hex.DecodeString(hexString)
@@ -36,6 +43,29 @@ func mustDecodeHex(hexString string) []byte {
 	return out
 }
 
+// Parses comma-separated hex hashKey:encryptionKey pairs, and returns the keys in the order
+// expected by securecookie.CodecsFromPairs.
+func parseCookieKeyPairs(pairs string) ([][]byte, error) {
+	if pairs == "" {
+		return nil, nil
+	}
+	var keys [][]byte
+	for _, pair := range strings.Split(pairs, ",") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bqcost: cookie keys must be hashKey:encryptionKey: %#v", pair)
+		}
+		for _, part := range parts {
+			key, err := hex.DecodeString(part)
+			if err != nil {
+				return nil, fmt.Errorf("bqcost: invalid cookie key: %s", err.Error())
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
 func handleRoot(w http.ResponseWriter, r *http.Request) {
 	fmt.Println(r.URL.Path)
 	if r.URL.Path != "/" {
@@ -66,14 +96,30 @@ func (s *server) handleStart(w http.ResponseWriter, r *http.Request) {
 }
 
 type server struct {
-	auth         *googlelogin.Authenticator
-	dbmap        *gorp.DbMap
-	startLoading func(userID int64, projectID string, accessToken string) error
+	auth   *googlelogin.Authenticator
+	dbmap  *gorp.DbMap
+	prices *pricing.Catalog
+	// called in the transaction that creates a project to start loading it
+	startLoading func(txn gorp.SqlExecutor, userID int64, projectID string, accessToken string) error
+	// called by job workers to load the data for a project, resuming from the job's checkpoint;
+	// ctx is cancelled if the worker loses the job's lease
+	loadProject func(ctx context.Context, job *bqdb.Job, tokens oauth2.TokenSource) error
+
+	// identifies this process's job workers in job leases
+	jobOwner string
+	// wakes up a job worker when a new job is created
+	jobCreated chan struct{}
 }
 
-func (s *server) projectsHandler(w http.ResponseWriter, r *http.Request, token *oauth2.Token) {
+func (s *server) projectsHandler(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity) {
+
 	parts := strings.Split(r.URL.Path, "/")
 	log.Printf("%s %s %d", r.URL.Path, parts, len(parts))
+	if len(parts) == 4 && parts[2] != "" {
+		s.projectPage(w, r, identity, parts[2], parts[3])
+		return
+	}
 	if len(parts) != 3 {
 		http.NotFound(w, r)
 		return
@@ -81,11 +127,20 @@ func (s *server) projectsHandler(w http.ResponseWriter, r *http.Request, token *
 	projectID := parts[2]
 	if projectID == "" {
 		log.Printf("%s = listProjects", r.URL.Path)
-		client := s.auth.Client(context.TODO(), token)
+		client := s.auth.Client(context.TODO(), identity)
 		listProjects(w, r, client)
+	} else if r.Method == http.MethodPost {
+		log.Printf("%s = refreshProject(%s)", r.URL.Path, projectID)
+		err := s.refreshProject(identity, projectID)
+		if err != nil {
+			log.Printf("refreshProject error %s", err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
 	} else {
 		log.Printf("%s = projectIndex(%s)", r.URL.Path, projectID)
-		err := s.projectIndex(w, r, token, projectID)
+		err := s.projectIndex(w, r, identity, projectID)
 		if err != nil {
 			log.Printf("projectIndex error %s", err.Error())
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -93,6 +148,40 @@ func (s *server) projectsHandler(w http.ResponseWriter, r *http.Request, token *
 	}
 }
 
+// Handles the reports under /projects/(projectID)/(page)
+func (s *server) projectPage(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string, page string) {
+
+	var handler func(http.ResponseWriter, *http.Request, *googlelogin.Identity, string) error
+	switch page {
+	case "diff":
+		handler = s.projectDiff
+	case "labels":
+		handler = s.projectLabels
+	case "table":
+		handler = s.projectTable
+	case "inventory":
+		handler = s.projectInventory
+	case "queries":
+		handler = s.projectQueries
+	case "cold":
+		handler = s.projectCold
+	case "dismiss":
+		handler = s.projectDismiss
+	case "export":
+		handler = s.projectExport
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	log.Printf("%s = %s(%s)", r.URL.Path, page, projectID)
+	err := handler(w, r, identity, projectID)
+	if err != nil {
+		log.Printf("%s error %s", page, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func listProjects(w http.ResponseWriter, r *http.Request, client *http.Client) {
 	bq, err := bigquery.New(client)
 	if err != nil {
@@ -112,8 +201,38 @@ func listProjects(w http.ResponseWriter, r *http.Request, client *http.Client) {
 	}
 }
 
-func queryProject(dbmap *gorp.DbMap, userID int64, projectID string) (*templates.ProjectData, error) {
-	total, err := bqdb.QueryTotalTableBytes(dbmap, userID, projectID)
+// Returns the monthly cost of storage in location at the prices in effect at timeMs. bytes and
+// longTermBytes are billed bytes in the dataset's storageBillingModel; see bqdb.Table.BilledBytes.
+func storageCost(prices *pricing.Catalog, location string, storageBillingModel string,
+	timeMs int64, bytes int64, longTermBytes int64) pricing.StorageCost {
+
+	at := time.Unix(0, timeMs*int64(time.Millisecond))
+	billing := pricing.BillingForModel(storageBillingModel)
+	return prices.StorageCost(location, billing, at, bytes, longTermBytes)
+}
+
+// Returns the monthly cost of table's storage at the prices in effect at timeMs.
+func tableStorageCost(prices *pricing.Catalog, table *bqdb.Table,
+	timeMs int64) pricing.StorageCost {
+
+	bytes, longTermBytes := table.BilledBytes()
+	return storageCost(prices, table.Location, table.StorageBillingModel, timeMs, bytes,
+		longTermBytes)
+}
+
+func queryProject(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64, projectID string,
+	snapshotID int64) (*templates.ProjectData, error) {
+
+	// costs use the prices when the snapshot was scraped
+	snapshot, err := bqdb.GetSnapshot(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("bqcost: snapshot %d for project %s does not exist",
+			snapshotID, projectID)
+	}
+	locations, err := bqdb.QueryStorageByLocation(dbmap, userID, projectID, snapshotID)
 	if err != nil {
 		return nil, err
 	}
@@ -123,19 +242,27 @@ func queryProject(dbmap *gorp.DbMap, userID int64, projectID string) (*templates
 	}
 
 	// TODO: Set FriendlyName correctly
-	data := &templates.ProjectData{ID: projectID, FriendlyName: projectID, TotalBytes: total}
-	_, err = dbmap.Select(&data.DatasetStorage,
-		"SELECT DatasetID AS ID, SUM(NumBytes) AS Bytes FROM "+quotedTable+
-			" WHERE UserID=? AND ProjectID=? GROUP BY ID ORDER BY Bytes DESC LIMIT ?",
-		userID, projectID, maxTopResults)
+	data := &templates.ProjectData{ID: projectID, FriendlyName: projectID, SnapshotID: snapshotID}
+	var total pricing.StorageCost
+	for _, location := range locations {
+		data.TotalBytes += location.Bytes
+		data.TotalLongTermBytes += location.LongTermBytes
+		total = total.Add(storageCost(prices, location.Location, location.StorageBillingModel,
+			snapshot.TimeMs, location.BilledBytes, location.BilledLongTermBytes))
+	}
+	data.ActiveCost = total.Active
+	data.LongTermCost = total.LongTerm
+
+	data.DatasetStorage, err = queryDatasetStorage(dbmap, prices, snapshot, maxTopResults)
 	if err != nil {
 		return nil, err
 	}
 
 	ifaces, err := dbmap.Select((*bqdb.Table)(nil),
-		"SELECT DatasetID, TableID, NumBytes FROM "+quotedTable+
-			" WHERE UserID=? AND ProjectID=? ORDER BY NumBytes DESC LIMIT ?",
-		userID, projectID, maxTopResults)
+		"SELECT DatasetID, TableID, Type, Location, NumBytes, NumLongTermBytes,"+
+			" NumActivePhysicalBytes, NumLongTermPhysicalBytes, StorageBillingModel FROM "+quotedTable+
+			" WHERE UserID=? AND ProjectID=? AND SnapshotID=? ORDER BY NumBytes DESC LIMIT ?",
+		userID, projectID, snapshotID, maxTopResults)
 	if err != nil {
 		return nil, err
 	}
@@ -143,17 +270,190 @@ func queryProject(dbmap *gorp.DbMap, userID int64, projectID string) (*templates
 	for i, iface := range ifaces {
 		table := iface.(*bqdb.Table)
 		id := table.DatasetID + "." + table.TableID
-		data.TableStorage[i] = &templates.StorageUsage{ID: id, Bytes: table.NumBytes}
+		data.TableStorage[i] = &templates.StorageUsage{ID: id, Type: table.Type,
+			Bytes: table.NumBytes, LongTermBytes: table.NumLongTermBytes, Location: table.Location,
+			DollarsPerMonth: tableStorageCost(prices, table, snapshot.TimeMs).Total()}
+	}
+
+	err = queryHistory(dbmap, prices, userID, projectID, data)
+	if err != nil {
+		return nil, err
 	}
 
+	project, err := bqdb.GetProjectByID(dbmap, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		data.QueryCosts, err = queryQueryCosts(dbmap, userID, project, snapshot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data.Recommendations, err = queryRecommendations(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
 	return data, nil
 }
 
-func (s *server) projectIndex(w http.ResponseWriter, r *http.Request, token *oauth2.Token,
-	projectID string) error {
+// Returns the storage used by the largest datasets in snapshot, or all datasets if limit is 0.
+func queryDatasetStorage(dbmap *gorp.DbMap, prices *pricing.Catalog, snapshot *bqdb.Snapshot,
+	limit int) ([]*templates.StorageUsage, error) {
+
+	quotedTable, err := bqdb.QuotedTableForQuery(dbmap, bqdb.Table{})
+	if err != nil {
+		return nil, err
+	}
+	// every table in a dataset has the dataset's billing model
+	query := "SELECT DatasetID AS ID, MAX(Location) AS Location," +
+		" MAX(StorageBillingModel) AS StorageBillingModel, SUM(NumBytes) AS Bytes," +
+		" SUM(NumLongTermBytes) AS LongTermBytes, " + bqdb.SumBilledBytesSQL("") +
+		" FROM " + quotedTable +
+		" WHERE UserID=? AND ProjectID=? AND SnapshotID=? GROUP BY ID ORDER BY Bytes DESC, ID"
+	args := []interface{}{snapshot.UserID, snapshot.ProjectID, snapshot.ID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var rows []*struct {
+		ID                  string
+		Location            string
+		StorageBillingModel string
+		Bytes               int64
+		LongTermBytes       int64
+		BilledBytes         int64
+		BilledLongTermBytes int64
+	}
+	_, err = dbmap.Select(&rows, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	datasets := make([]*templates.StorageUsage, len(rows))
+	for i, row := range rows {
+		datasets[i] = &templates.StorageUsage{ID: row.ID, Location: row.Location, Bytes: row.Bytes,
+			LongTermBytes: row.LongTermBytes,
+			DollarsPerMonth: storageCost(prices, row.Location, row.StorageBillingModel,
+				snapshot.TimeMs, row.BilledBytes, row.BilledLongTermBytes).Total()}
+	}
+	err = queryDatasetMetadata(dbmap, snapshot.UserID, snapshot.ProjectID, snapshot.ID, datasets)
+	if err != nil {
+		return nil, err
+	}
+	return datasets, nil
+}
+
+// Sets the location, labels and expiration of datasets from their scraped metadata.
+func queryDatasetMetadata(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64,
+	datasets []*templates.StorageUsage) error {
+
+	dbDatasets, err := bqdb.ListDatasets(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	labels, err := bqdb.ListDatasetLabels(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	byID := map[string]*templates.StorageUsage{}
+	for _, dataset := range datasets {
+		byID[dataset.ID] = dataset
+	}
+	for _, dbDataset := range dbDatasets {
+		dataset := byID[dbDataset.DatasetID]
+		if dataset == nil {
+			continue
+		}
+		if dbDataset.Location != "" {
+			dataset.Location = dbDataset.Location
+		}
+		dataset.DefaultTableExpirationMs = dbDataset.DefaultTableExpirationMs
+	}
+	for _, label := range labels {
+		dataset := byID[label.DatasetID]
+		if dataset != nil {
+			dataset.Labels = append(dataset.Labels, label.LabelKey+":"+label.LabelValue)
+		}
+	}
+	return nil
+}
+
+// Sets the storage history for the project and the datasets in data.DatasetStorage.
+func queryHistory(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64, projectID string,
+	data *templates.ProjectData) error {
+
+	snapshots, err := bqdb.ListCompleteSnapshots(dbmap, userID, projectID)
+	if err != nil {
+		return err
+	}
+	quotedTable, err := bqdb.QuotedTableForQuery(dbmap, bqdb.Table{})
+	if err != nil {
+		return err
+	}
+
+	var datasetBytes []struct {
+		SnapshotID          int64
+		DatasetID           string
+		Location            string
+		StorageBillingModel string
+		Bytes               int64
+		LongTermBytes       int64
+		BilledBytes         int64
+		BilledLongTermBytes int64
+	}
+	_, err = dbmap.Select(&datasetBytes,
+		"SELECT SnapshotID, DatasetID, MAX(Location) AS Location,"+
+			" MAX(StorageBillingModel) AS StorageBillingModel, SUM(NumBytes) AS Bytes,"+
+			" SUM(NumLongTermBytes) AS LongTermBytes, "+bqdb.SumBilledBytesSQL("")+
+			" FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? GROUP BY SnapshotID, DatasetID",
+		userID, projectID)
+	if err != nil {
+		return err
+	}
+	type snapshotDataset struct {
+		snapshotID int64
+		datasetID  string
+	}
+	snapshotTimes := map[int64]int64{}
+	for _, snapshot := range snapshots {
+		snapshotTimes[snapshot.ID] = snapshot.TimeMs
+	}
+	bytes := map[snapshotDataset]int64{}
+	snapshotTotals := map[int64]int64{}
+	snapshotLongTermTotals := map[int64]int64{}
+	snapshotCosts := map[int64]float64{}
+	for _, row := range datasetBytes {
+		bytes[snapshotDataset{row.SnapshotID, row.DatasetID}] = row.Bytes
+		snapshotTotals[row.SnapshotID] += row.Bytes
+		snapshotLongTermTotals[row.SnapshotID] += row.LongTermBytes
+		snapshotCosts[row.SnapshotID] += storageCost(prices, row.Location,
+			row.StorageBillingModel, snapshotTimes[row.SnapshotID], row.BilledBytes,
+			row.BilledLongTermBytes).Total()
+	}
+
+	data.History = make([]*templates.SnapshotUsage, len(snapshots))
+	for i, snapshot := range snapshots {
+		data.History[i] = &templates.SnapshotUsage{
+			ID: snapshot.ID, TimeMs: snapshot.TimeMs, Bytes: snapshotTotals[snapshot.ID],
+			LongTermBytes:   snapshotLongTermTotals[snapshot.ID],
+			DollarsPerMonth: snapshotCosts[snapshot.ID]}
+	}
+	data.DatasetHistory = make([]*templates.DatasetHistory, len(data.DatasetStorage))
+	for i, dataset := range data.DatasetStorage {
+		history := &templates.DatasetHistory{ID: dataset.ID, Bytes: make([]int64, len(snapshots))}
+		for j, snapshot := range snapshots {
+			history.Bytes[j] = bytes[snapshotDataset{snapshot.ID, dataset.ID}]
+		}
+		data.DatasetHistory[i] = history
+	}
+	return nil
+}
+
+func (s *server) projectIndex(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
 
 	log.Printf("projectIndex %s", projectID)
-	userID, project, err := s.getProjectOrStartLoading(token, projectID)
+	userID, project, err := s.getProjectOrStartLoading(identity, projectID)
 	if err != nil {
 		if err == errIsLoading {
 			return templates.Loading(w, project.LoadingPercent, project.LoadingMessage)
@@ -162,22 +462,39 @@ func (s *server) projectIndex(w http.ResponseWriter, r *http.Request, token *oau
 		}
 	}
 
-	pageVariables, err := queryProject(s.dbmap, userID, projectID)
+	snapshotID := project.SnapshotID
+	snapshotParam := r.FormValue("snapshot")
+	if snapshotParam != "" {
+		snapshotID, err = strconv.ParseInt(snapshotParam, 10, 64)
+		if err != nil {
+			return err
+		}
+		snapshot, err := bqdb.GetSnapshot(s.dbmap, userID, projectID, snapshotID)
+		if err != nil {
+			return err
+		}
+		if snapshot == nil || !snapshot.Complete {
+			return fmt.Errorf("bqcost: snapshot %d for project %s does not exist",
+				snapshotID, projectID)
+		}
+	}
+
+	pageVariables, err := queryProject(s.dbmap, s.prices, userID, projectID, snapshotID)
 	if err != nil {
 		return err
 	}
+	pageVariables.LoadingError = project.LoadingError
 	return templates.Project(w, pageVariables)
 }
 
 // TODO: Remove: see comment below
 var errIsLoading = errors.New("loading data from bigquery")
 
-// Returns a userID, Project or calls loader() to transactionally start loading. loader cannot
-// block, but if it returns as error the user will not be inserted. If loader starts a goroutine,
-// it should copy data from user to avoid data races.
+// Returns a userID, Project or calls startLoading() to transactionally start loading.
+// startLoading cannot block, but if it returns as error the user will not be inserted.
 // TODO: This should not return errIsLoading; it should be the caller's responsibility to check
 // if the user is loading
-func (s *server) getProjectOrStartLoading(token *oauth2.Token, projectID string) (
+func (s *server) getProjectOrStartLoading(identity *googlelogin.Identity, projectID string) (
 	int64, *bqdb.Project, error) {
 
 	txn, err := s.dbmap.Begin()
@@ -187,31 +504,20 @@ func (s *server) getProjectOrStartLoading(token *oauth2.Token, projectID string)
 	// don't forget to rollback
 	defer txn.Rollback()
 
-	user, err := bqdb.GetUserByAccessToken(txn, token.AccessToken)
+	// TODO: This probably should use one transaction to create the user and another to toggle
+	// "IsLoading": The commit could fail causing user id to be re-used
+	user, err := getOrCreateUser(txn, identity)
 	if err != nil {
 		return 0, nil, err
 	}
-	if user == nil {
-		// TODO: This probably should use one transaction to create the user and another to toggle
-		// "IsLoading": The commit could fail causing user id to be re-used, or the token to be
-		// assigned to a different user id
-		log.Printf("bqcost: token %s creating new user", token.AccessToken)
-		user = &bqdb.User{}
-		user.AccessToken = token.AccessToken
-		err = txn.Insert(user)
-		if err != nil {
-			return 0, nil, err
-		}
-	}
-	log.Printf("bqcost: token %s found user %d", token.AccessToken, user.ID)
 
 	project, err := bqdb.GetProjectByID(txn, user.ID, projectID)
 	if err != nil {
 		return 0, nil, err
 	}
 	if project == nil {
-		log.Printf("bqcost: token %s user id %d creating new project %s",
-			token.AccessToken, user.ID, projectID)
+		log.Printf("bqcost: subject %s user id %d creating new project %s",
+			identity.Subject, user.ID, projectID)
 		project = &bqdb.Project{
 			UserID:    user.ID,
 			ProjectID: projectID,
@@ -222,7 +528,7 @@ func (s *server) getProjectOrStartLoading(token *oauth2.Token, projectID string)
 			return 0, nil, err
 		}
 
-		err = s.startLoading(user.ID, projectID, user.AccessToken)
+		err = s.startLoading(txn, user.ID, projectID, user.AccessToken)
 		if err != nil {
 			return 0, nil, err
 		}
@@ -232,21 +538,141 @@ func (s *server) getProjectOrStartLoading(token *oauth2.Token, projectID string)
 		if err != nil {
 			return 0, nil, err
 		}
-		log.Printf("bqcost: token %s project %s loading started", token.AccessToken, projectID)
+		s.wakeJobWorker()
+		log.Printf("bqcost: subject %s project %s loading started", identity.Subject, projectID)
 		return user.ID, project, errIsLoading
 	}
 
-	log.Printf("bqcost: found user %v", user)
+	// save any changes to the user
+	err = txn.Commit()
+	if err != nil {
+		return 0, nil, err
+	}
 	if project.IsLoading {
 		return user.ID, project, errIsLoading
 	}
-	if project.LoadingError != "" {
+	if project.LoadingError != "" && project.SnapshotID == 0 {
+		// never loaded successfully: nothing to show
 		return 0, nil, errors.New(project.LoadingError)
 	}
 	return user.ID, project, nil
 }
 
-func (s *server) finishLoading(userID int64, projectID string, loadingErr error) error {
+// Returns the user for identity, creating it if this is their first login, unless they can claim
+// a retired user with their email. The access token and email are updated on each login, since
+// background jobs load projects with the latest token.
+func getOrCreateUser(txn gorp.SqlExecutor, identity *googlelogin.Identity) (*bqdb.User, error) {
+	user, err := bqdb.GetUserBySubject(txn, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// the first login of an account can claim a user retired by the migration
+		retired, err := bqdb.GetRetiredUserByEmail(txn, identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		if retired != nil {
+			log.Printf("bqcost: subject %s claiming retired user %d", identity.Subject, retired.ID)
+			retired.Subject = identity.Subject
+			retired.AccessToken = identity.Token.AccessToken
+			_, err = txn.Update(retired)
+			if err != nil {
+				return nil, err
+			}
+			return retired, nil
+		}
+
+		log.Printf("bqcost: subject %s creating new user", identity.Subject)
+		user = &bqdb.User{Subject: identity.Subject, Email: identity.Email,
+			AccessToken: identity.Token.AccessToken}
+		err = txn.Insert(user)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	log.Printf("bqcost: subject %s found user %d", identity.Subject, user.ID)
+
+	if user.AccessToken != identity.Token.AccessToken || user.Email != identity.Email {
+		user.AccessToken = identity.Token.AccessToken
+		user.Email = identity.Email
+		_, err = txn.Update(user)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// Returns the user for identity and their project, or an error if either does not exist.
+func getExistingProject(getter gorp.SqlExecutor, identity *googlelogin.Identity,
+	projectID string) (*bqdb.User, *bqdb.Project, error) {
+
+	user, err := bqdb.GetUserBySubject(getter, identity.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, fmt.Errorf("bqcost: subject %s has no user", identity.Subject)
+	}
+	project, err := bqdb.GetProjectByID(getter, user.ID, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if project == nil {
+		return nil, nil, fmt.Errorf("bqcost: project %d %s does not exist", user.ID, projectID)
+	}
+	return user, project, nil
+}
+
+// Returns the user for identity and the latest complete snapshot of their project, or an error
+// if the project has not finished loading.
+func getLatestSnapshot(getter gorp.SqlExecutor, identity *googlelogin.Identity,
+	projectID string) (*bqdb.User, *bqdb.Snapshot, error) {
+
+	user, project, err := getExistingProject(getter, identity, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := bqdb.GetSnapshot(getter, user.ID, projectID, project.SnapshotID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if snapshot == nil {
+		return nil, nil, fmt.Errorf("bqcost: project %s has not finished loading", projectID)
+	}
+	return user, snapshot, nil
+}
+
+// Deletes expired login sessions forever. Expired sessions are already rejected: this only
+// removes them from the database.
+func expireSessions(sessions googlelogin.SessionStore) {
+	for {
+		deleted, err := sessions.DeleteExpiredSessions(time.Now())
+		if err != nil {
+			log.Printf("bqcost: error deleting expired sessions: %s", err.Error())
+		} else if deleted > 0 {
+			log.Printf("bqcost: deleted %d expired sessions", deleted)
+		}
+		time.Sleep(sessionExpiryInterval)
+	}
+}
+
+// Forgets the user's revoked access token, so background jobs do not use it. Their projects are
+// kept for their next login.
+func (s *server) logoutUser(identity *googlelogin.Identity) error {
+	user, err := bqdb.GetUserBySubject(s.dbmap, identity.Subject)
+	if err != nil || user == nil {
+		return err
+	}
+	user.AccessToken = ""
+	_, err = s.dbmap.Update(user)
+	return err
+}
+
+// Starts loading a new snapshot of projectID, unless it is already loading.
+func (s *server) refreshProject(identity *googlelogin.Identity, projectID string) error {
 	txn, err := s.dbmap.Begin()
 	if err != nil {
 		return err
@@ -254,45 +680,62 @@ func (s *server) finishLoading(userID int64, projectID string, loadingErr error)
 	// don't forget to rollback
 	defer txn.Rollback()
 
-	project, err := bqdb.GetProjectByID(txn, userID, projectID)
+	// the job loads the project with the latest token
+	_, err = getOrCreateUser(txn, identity)
 	if err != nil {
 		return err
 	}
-	if project == nil {
-		return fmt.Errorf("bqcost: finishLoading: project %d %s does not exist", userID, projectID)
-	}
-	if !project.IsLoading || project.LoadingError != "" {
-		return fmt.Errorf("bqcost: finishLoading: project has already finished loading: %v", project)
+	user, project, err := getExistingProject(txn, identity, projectID)
+	if err != nil {
+		return err
 	}
-	project.IsLoading = false
-	if loadingErr != nil {
-		project.LoadingError = loadingErr.Error()
+	if project.IsLoading {
+		log.Printf("bqcost: refreshProject: project %d %s is already loading", user.ID, projectID)
+		return nil
 	}
+
+	project.IsLoading = true
+	project.LoadingPercent = 0
+	project.LoadingMessage = ""
+	project.LoadingError = ""
 	_, err = txn.Update(project)
 	if err != nil {
 		return err
 	}
-	return txn.Commit()
-}
-
-func (s *server) startLocalhostLoader(userID int64, projectID string, accessToken string) error {
-	// start a goroutine to start sync-ing data: copy args to avoid data races
-	go s.localhostLoaderGoroutine(userID, projectID, accessToken)
+	err = s.startLoading(txn, user.ID, projectID, user.AccessToken)
+	if err != nil {
+		return err
+	}
+	err = txn.Commit()
+	if err != nil {
+		return err
+	}
+	s.wakeJobWorker()
 	return nil
 }
 
-func (s *server) localhostLoaderGoroutine(userID int64, projectID string, accessToken string) {
-	log.Printf("bqcost: localhostLoaderGoroutine start user %d project %s", userID, projectID)
-	err := s.loadBigqueryData(userID, projectID, accessToken)
+// Marks the project as loaded. If loadingErr is nil, the project will show snapshotID.
+func (s *server) finishLoading(executor gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64, loadingErr error) error {
+
+	project, err := bqdb.GetProjectByID(executor, userID, projectID)
 	if err != nil {
-		log.Printf("bqcost: token %s loading error %s", accessToken, err.Error())
+		return err
 	}
-	err = s.finishLoading(userID, projectID, err)
-	if err != nil {
-		log.Printf("bqcost: token %s error finishing loading: %s", accessToken, err.Error())
+	if project == nil {
+		return fmt.Errorf("bqcost: finishLoading: project %d %s does not exist", userID, projectID)
+	}
+	if !project.IsLoading || project.LoadingError != "" {
+		return fmt.Errorf("bqcost: finishLoading: project has already finished loading: %v", project)
+	}
+	project.IsLoading = false
+	if loadingErr != nil {
+		project.LoadingError = loadingErr.Error()
+	} else {
+		project.SnapshotID = snapshotID
 	}
-	log.Printf("bqcost: localhostLoaderGoroutine end user %d %s project %s",
-		userID, accessToken, projectID)
+	_, err = executor.Update(project)
+	return err
 }
 
 func progressReport(dbmap *gorp.DbMap, userID int64, projectID string, percent int,
@@ -344,40 +787,177 @@ func (u *userProgressReporter) Progress(percent int, message string) {
 	}
 }
 
-func (s *server) loadBigqueryData(userID int64, projectID string, accessToken string) error {
-	client := s.auth.Client(context.TODO(), &oauth2.Token{AccessToken: accessToken})
+// Loads the job's project from BigQuery, saving each chunk of tables with the job's checkpoint.
+func (s *server) loadBigqueryData(ctx context.Context, job *bqdb.Job,
+	tokens oauth2.TokenSource) error {
+
+	client := oauth2.NewClient(ctx, tokens)
 	bq, err := bigquery.New(client)
 	if err != nil {
 		return err
 	}
 
-	progress := &userProgressReporter{s.dbmap, userID, projectID}
-	tables, err := bqscrape.GetAllTables(bq, projectID, progress)
+	progress := &userProgressReporter{s.dbmap, job.UserID, job.ProjectID}
+	checkpoint := bqscrape.Checkpoint{
+		DatasetID: job.CheckpointDatasetID,
+		PageToken: job.CheckpointPageToken,
+		Tables:    job.CheckpointTables,
+		Done:      job.CheckpointDone,
+	}
+	save := func(dataset *bigquery.Dataset, tables []*bigquery.Table,
+		partitions []*bqscrape.Partition, next bqscrape.Checkpoint) error {
+		// another worker may be loading the job: stop before writing
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return s.saveChunk(job, dataset, tables, partitions, next)
+	}
+	err = bqscrape.GetTablesInChunks(bq, job.ProjectID, checkpoint, progress, save)
+	if err != nil {
+		return err
+	}
+
+	// query history and recommendations are not saved with a checkpoint
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// query history is optional: it needs permission to list all users' jobs
+	progress.Progress(99, "Reading query history...")
+	list := func(min time.Time, max time.Time, save bqscrape.QueryJobSaver) (bool, error) {
+		return bqscrape.GetQueryJobs(bq, job.ProjectID, min, max, save)
+	}
+	err = s.loadQueryJobs(job.UserID, job.ProjectID, time.Now(), list)
+	if err != nil {
+		log.Printf("bqcost: job %d warning: not loading query jobs: %s", job.ID, err.Error())
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	progress.Progress(99, "Finding recommendations...")
+	err = saveRecommendations(s.dbmap, s.prices, job.UserID, job.ProjectID, job.SnapshotID)
+	if err != nil {
+		log.Printf("bqcost: job %d warning: not saving recommendations: %s", job.ID, err.Error())
+	}
+	return nil
+}
+
+// Saves the dataset if it is not nil, tables, partitions, and the checkpoint to resume after
+// them in one transaction.
+func (s *server) saveChunk(job *bqdb.Job, dataset *bigquery.Dataset, tables []*bigquery.Table,
+	partitions []*bqscrape.Partition, next bqscrape.Checkpoint) error {
+
+	txn, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	// don't forget to rollback
+	defer txn.Rollback()
+
+	// tables are billed in their dataset's billing model; only the first chunk of a dataset
+	// includes it
+	storageBillingModel := ""
+	if dataset != nil {
+		err = saveBigqueryDataset(txn, job.UserID, job.SnapshotID, dataset)
+		if err != nil {
+			return err
+		}
+		storageBillingModel = dataset.StorageBillingModel
+	} else if len(tables) > 0 {
+		dbDataset, err := bqdb.GetDataset(txn, job.UserID, job.ProjectID, job.SnapshotID,
+			tables[0].TableReference.DatasetId)
+		if err != nil {
+			return err
+		}
+		if dbDataset != nil {
+			storageBillingModel = dbDataset.StorageBillingModel
+		}
+	}
+	err = s.saveBigqueryTables(txn, job.UserID, job.SnapshotID, storageBillingModel, tables)
 	if err != nil {
 		return err
 	}
+	err = saveBigqueryPartitions(txn, job.UserID, job.ProjectID, job.SnapshotID, partitions)
+	if err != nil {
+		return err
+	}
+	updated := *job
+	updated.CheckpointDatasetID = next.DatasetID
+	updated.CheckpointPageToken = next.PageToken
+	updated.CheckpointTables = next.Tables
+	updated.CheckpointDone = next.Done
+	err = bqdb.SaveJobCheckpoint(s.dbmap, txn, &updated)
+	if err != nil {
+		return err
+	}
+	err = txn.Commit()
+	if err != nil {
+		return err
+	}
+	*job = updated
+	return nil
+}
 
-	return s.saveBigqueryTables(userID, tables)
+func saveBigqueryDataset(executor gorp.SqlExecutor, userID int64, snapshotID int64,
+	dataset *bigquery.Dataset) error {
+
+	dbDataset := &bqdb.Dataset{}
+	dbDataset.UserID = userID
+	dbDataset.ProjectID = dataset.DatasetReference.ProjectId
+	dbDataset.SnapshotID = snapshotID
+	dbDataset.DatasetID = dataset.DatasetReference.DatasetId
+	dbDataset.FriendlyName = dataset.FriendlyName
+	dbDataset.Description = dataset.Description
+	dbDataset.Location = dataset.Location
+	dbDataset.DefaultTableExpirationMs = dataset.DefaultTableExpirationMs
+	dbDataset.StorageBillingModel = dataset.StorageBillingModel
+	dbDataset.CreationTimeMs = dataset.CreationTime
+	dbDataset.LastModifiedTimeMs = dataset.LastModifiedTime
+
+	rows := []interface{}{dbDataset}
+	for key, value := range dataset.Labels {
+		rows = append(rows, &bqdb.Label{UserID: userID, ProjectID: dbDataset.ProjectID,
+			SnapshotID: snapshotID, DatasetID: dbDataset.DatasetID, LabelKey: key, LabelValue: value})
+	}
+	return executor.Insert(rows...)
 }
 
-func (s *server) saveBigqueryTables(userID int64, tables []*bigquery.Table) error {
+// Returns s truncated to at most maxBytes, without splitting a UTF-8 character.
+func truncate(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
+
+// Saves tables in one dataset with the dataset's storageBillingModel.
+func (s *server) saveBigqueryTables(executor gorp.SqlExecutor, userID int64, snapshotID int64,
+	storageBillingModel string, tables []*bigquery.Table) error {
+
 	dbTables := make([]interface{}, len(tables))
+	var labels []interface{}
 	for i, table := range tables {
-		if table.Type != bqscrape.TypeTable {
-			log.Printf("bqcost: uid %d table %v ignoring table type %s",
-				userID, table.TableReference, table.Type)
-			continue
-		}
 		dbTable := &bqdb.Table{}
 		dbTable.UserID = userID
 		dbTable.ProjectID = table.TableReference.ProjectId
+		dbTable.SnapshotID = snapshotID
 		dbTable.DatasetID = table.TableReference.DatasetId
 		dbTable.TableID = table.TableReference.TableId
+		dbTable.Type = table.Type
 		dbTable.FriendlyName = table.FriendlyName
 		dbTable.Description = table.Description
+		dbTable.Location = table.Location
+		// materialized views are billed for their storage like tables; views and external tables
+		// have no storage
 		dbTable.NumBytes = table.NumBytes
 		dbTable.NumLongTermBytes = table.NumLongTermBytes
 		dbTable.NumRows = int64(table.NumRows)
+		dbTable.NumActivePhysicalBytes = table.NumActivePhysicalBytes
+		dbTable.NumLongTermPhysicalBytes = table.NumLongTermPhysicalBytes
+		dbTable.StorageBillingModel = storageBillingModel
 
 		dbTable.CreationTimeMs = table.CreationTime
 		dbTable.LastModifiedTimeMs = int64(table.LastModifiedTime)
@@ -386,18 +966,99 @@ func (s *server) saveBigqueryTables(userID int64, tables []*bigquery.Table) erro
 			dbTable.StreamingEstimatedBytes = int64(table.StreamingBuffer.EstimatedBytes)
 			dbTable.StreamingEstimatedRows = int64(table.StreamingBuffer.EstimatedRows)
 		}
+		if table.TimePartitioning != nil {
+			dbTable.PartitionType = table.TimePartitioning.Type
+			dbTable.PartitionField = table.TimePartitioning.Field
+			dbTable.PartitionExpirationMs = table.TimePartitioning.ExpirationMs
+		}
+		if table.RangePartitioning != nil {
+			dbTable.PartitionType = bqdb.PartitionTypeRange
+			dbTable.PartitionField = table.RangePartitioning.Field
+		}
+		if table.Clustering != nil {
+			dbTable.ClusteringFields = strings.Join(table.Clustering.Fields, ",")
+		}
+
+		if table.View != nil {
+			dbTable.ViewQuery = truncate(table.View.Query, bqdb.MaxViewQueryLength)
+		} else if table.MaterializedView != nil {
+			dbTable.ViewQuery = truncate(table.MaterializedView.Query, bqdb.MaxViewQueryLength)
+		}
+		if table.ExternalDataConfiguration != nil {
+			dbTable.SourceFormat = table.ExternalDataConfiguration.SourceFormat
+			dbTable.SourceURIs = truncate(strings.Join(table.ExternalDataConfiguration.SourceUris, "\n"),
+				bqdb.MaxSourceURIsLength)
+		}
+		var base *bigquery.TableReference
+		if table.SnapshotDefinition != nil {
+			base = table.SnapshotDefinition.BaseTableReference
+		} else if table.CloneDefinition != nil {
+			base = table.CloneDefinition.BaseTableReference
+		}
+		if base != nil {
+			dbTable.BaseTable = base.ProjectId + ":" + base.DatasetId + "." + base.TableId
+		}
 		dbTables[i] = dbTable
+
+		for key, value := range table.Labels {
+			labels = append(labels, &bqdb.Label{UserID: userID, ProjectID: dbTable.ProjectID,
+				SnapshotID: snapshotID, DatasetID: dbTable.DatasetID, TableID: dbTable.TableID,
+				LabelKey: key, LabelValue: value})
+		}
 	}
 
 	// let's do a massive insert: TODO: Does gorp actually execute this as batch?
-	return s.dbmap.Insert(dbTables...)
+	err := executor.Insert(dbTables...)
+	if err != nil {
+		return err
+	}
+	return executor.Insert(labels...)
+}
+
+func saveBigqueryPartitions(executor gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64, partitions []*bqscrape.Partition) error {
+
+	rows := make([]interface{}, len(partitions))
+	for i, partition := range partitions {
+		rows[i] = &bqdb.TablePartition{
+			UserID:             userID,
+			ProjectID:          projectID,
+			SnapshotID:         snapshotID,
+			DatasetID:          partition.DatasetID,
+			TableID:            partition.TableID,
+			PartitionID:        partition.PartitionID,
+			NumBytes:           partition.NumBytes,
+			NumRows:            partition.NumRows,
+			LastModifiedTimeMs: partition.LastModifiedTimeMs,
+			LongTerm:           partition.LongTerm,
+		}
+	}
+	return executor.Insert(rows...)
 }
 
 func main() {
 	sqlitePath := flag.String("sqlitePath", "", "If set, runs the server in localhost test mode")
 	cloudSQLProxy := flag.Bool("cloudSQLProxy", false, "If set, runs in localhost mode conecting to cloud SQL")
+	pricesPath := flag.String("prices", "", "JSON storage price catalog; uses built-in prices if empty")
+	tokenEncryptionKey := flag.String("tokenEncryptionKey", "",
+		"If set, hex AES-256 key to store refresh tokens in the database for offline access")
+	previousCookieKeys := flag.String("previousCookieKeys", "",
+		"Comma-separated hex hashKey:encryptionKey pairs that still decode cookies, newest first")
+	importPath := flag.String("importSnapshot", "",
+		"If set, imports this snapshot file saved by bqcostreport --dump for --importUser, then exits")
+	importUser := flag.String("importUser", "", "Email of the user to import --importSnapshot for")
 	flag.Parse()
 
+	prices := pricing.Default()
+	if *pricesPath != "" {
+		var err error
+		prices, err = pricing.LoadFile(*pricesPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+	log.Printf("using price catalog version %s", prices.Version)
+
 	listenHostPost := ":8080"
 	redirectURL := productionHost + redirectPath
 	dbDriver := "mysql"
@@ -418,9 +1079,14 @@ func main() {
 		log.Printf("using production configuration")
 	}
 
-	securecookies := securecookie.New(cookieHashKey, cookieEncryptionKey)
+	previousKeys, err := parseCookieKeyPairs(*previousCookieKeys)
+	if err != nil {
+		panic(err)
+	}
+	codecs := securecookie.CodecsFromPairs(
+		append([][]byte{cookieHashKey, cookieEncryptionKey}, previousKeys...)...)
 	auth, err := googlelogin.New(googleOAuthClientID, googleOAuthClientSecret, redirectURL,
-		[]string{bigquery.BigqueryScope + ".readonly"}, securecookies, "/noauth", http.DefaultServeMux)
+		[]string{bqscrape.Scope}, codecs, "/noauth", http.DefaultServeMux)
 	if err != nil {
 		panic(err)
 	}
@@ -429,16 +1095,42 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	// the cookie only contains a session ID, so logins survive changing the cookie keys
+	sessions := bqdb.NewSessionStore(dbmap)
+	auth.EnableSessionStore(sessions)
+	go expireSessions(sessions)
+	if *tokenEncryptionKey != "" {
+		log.Printf("enabling offline access")
+		err = auth.EnableOfflineAccess(bqdb.NewTokenStore(dbmap), mustDecodeHex(*tokenEncryptionKey))
+		if err != nil {
+			panic(err)
+		}
+	}
 
-	s := &server{auth, dbmap, nil}
-	// TODO: figure out a better way to customize this
-	s.startLoading = s.startLocalhostLoader
+	jobOwner, err := makeJobOwner()
+	if err != nil {
+		panic(err)
+	}
+	s := &server{auth: auth, dbmap: dbmap, prices: prices, jobOwner: jobOwner,
+		jobCreated: make(chan struct{}, 1)}
+	s.startLoading = s.startLoadingJob
+	s.loadProject = s.loadBigqueryData
+	if *importPath != "" {
+		err = s.importSnapshotFile(*importPath, *importUser)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+	go s.jobWorker()
 
 	http.HandleFunc("/", handleRoot)
 	http.HandleFunc("/start", s.handleStart)
 	http.HandleFunc("/noauth", handleNoAuth)
+	http.Handle("/logout", auth.LogoutHandler("/", s.logoutUser))
 
 	http.Handle("/projects/", auth.Handler(s.projectsHandler))
+	http.HandleFunc(apiPrefix, s.handleAPI)
 
 	fmt.Printf("listening on http://%s/\n", listenHostPost)
 	err = http.ListenAndServe(listenHostPost, nil)
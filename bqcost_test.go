@@ -3,14 +3,20 @@ package main
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/evanj/bqtools/bqdb"
 	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
 	"github.com/go-gorp/gorp"
+	"github.com/gorilla/securecookie"
 	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/bigquery/v2"
@@ -24,8 +30,25 @@ func newTestDB() *gorp.DbMap {
 	return dbmap
 }
 
-func countUsers(dbmap *gorp.DbMap, token *oauth2.Token) int64 {
-	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM User WHERE AccessToken=?", token.AccessToken)
+func newTestAuthenticator() *googlelogin.Authenticator {
+	codecs := securecookie.CodecsFromPairs(securecookie.GenerateRandomKey(64),
+		securecookie.GenerateRandomKey(32))
+	auth, err := googlelogin.New("id", "secret", "http://localhost/callback", nil, codecs,
+		"/noauth", http.NewServeMux())
+	if err != nil {
+		panic(err)
+	}
+	return auth
+}
+
+// Returns an identity for user's most recent login.
+func testIdentity(user *bqdb.User) *googlelogin.Identity {
+	return &googlelogin.Identity{Subject: user.Subject, Email: user.Email,
+		Token: &oauth2.Token{AccessToken: user.AccessToken}}
+}
+
+func countUsers(dbmap *gorp.DbMap, identity *googlelogin.Identity) int64 {
+	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM User WHERE Subject=?", identity.Subject)
 	if err != nil {
 		panic(err)
 	}
@@ -38,7 +61,7 @@ func TestGetUserOrStartLoading(t *testing.T) {
 
 	var loaderUserID int64
 	loaderProjectID := ""
-	loader := func(userID int64, projectID string, accessToken string) error {
+	loader := func(txn gorp.SqlExecutor, userID int64, projectID string, accessToken string) error {
 		// loader should be called with an initialized user so we can use the id
 		if userID <= 0 {
 			return fmt.Errorf("userid must be set: %d", userID)
@@ -49,32 +72,39 @@ func TestGetUserOrStartLoading(t *testing.T) {
 	}
 
 	// creates a new user: returns errIsLoading but also the user
-	server := &server{nil, dbmap, loader}
-	token := &oauth2.Token{AccessToken: "fake_access_token"}
-	userID, project, err := server.getProjectOrStartLoading(token, "project")
+	server := &server{dbmap: dbmap, startLoading: loader}
+	identity := testIdentity(&bqdb.User{Subject: "subject", AccessToken: "fake_access_token"})
+	userID, project, err := server.getProjectOrStartLoading(identity, "project")
 	if userID <= 0 || project == nil || err != errIsLoading {
 		t.Fatal(userID, project, err)
 	}
 	if loaderUserID != userID || project.IsLoading != true {
 		t.Error(userID, project)
 	}
-	if countUsers(dbmap, token) != 1 {
-		t.Error(token)
+	if countUsers(dbmap, identity) != 1 {
+		t.Error(identity)
 	}
 	loadedID := loaderUserID
 	loaderUserID = 0
 
-	// calling it again with the same token should not call loader, but should return the project
-	userID, project, err = server.getProjectOrStartLoading(token, "project")
+	// logging in again returns the same user and project, and updates the token and email
+	identity = testIdentity(&bqdb.User{Subject: "subject", Email: "a@example.com",
+		AccessToken: "new_access_token"})
+	userID, project, err = server.getProjectOrStartLoading(identity, "project")
 	if userID <= 0 || project == nil || err != errIsLoading {
 		t.Fatal(userID, project, err)
 	}
-	if loaderUserID != 0 {
-		t.Error("calling twice should not have started loading:", loaderUserID)
+	if loaderUserID != 0 || userID != loadedID {
+		t.Error("calling twice should not have started loading:", loaderUserID, userID)
+	}
+	user, err := bqdb.GetUserByID(dbmap, userID)
+	if !(err == nil && user.AccessToken == "new_access_token" && user.Email == "a@example.com" &&
+		countUsers(dbmap, identity) == 1) {
+		t.Error(user, err)
 	}
 
 	// finish loading with an error
-	err = server.finishLoading(loadedID, loaderProjectID, errors.New("some err"))
+	err = server.finishLoading(dbmap, loadedID, loaderProjectID, 0, errors.New("some err"))
 	if err != nil {
 		panic(err)
 	}
@@ -87,19 +117,19 @@ func TestGetUserOrStartLoading(t *testing.T) {
 	}
 
 	// finishing loading again fails
-	err = server.finishLoading(loadedID, loaderProjectID, nil)
+	err = server.finishLoading(dbmap, loadedID, loaderProjectID, 0, nil)
 	if err == nil || !strings.Contains(err.Error(), "finished loading") {
 		t.Error(err)
 	}
 
 	// calling getUser again gets the error
-	userID, project, err = server.getProjectOrStartLoading(token, "project")
+	userID, project, err = server.getProjectOrStartLoading(identity, "project")
 	if !(userID == 0 && project == nil && err != nil && err.Error() == "some err") {
 		t.Error("expected some err:", userID, project, err)
 	}
 
 	// calling getProjectOrStartLoading with a different project causes that project to start
-	userID, project, err = server.getProjectOrStartLoading(token, "project2")
+	userID, project, err = server.getProjectOrStartLoading(identity, "project2")
 	if !project.IsLoading || err != errIsLoading {
 		t.Error(userID, project, err)
 	}
@@ -111,27 +141,27 @@ func TestGetProjectOrStartLoadingError(t *testing.T) {
 
 	var loaderUserID int64
 	errLoading := errors.New("loading error")
-	loader := func(userID int64, projectID string, accessToken string) error {
+	loader := func(txn gorp.SqlExecutor, userID int64, projectID string, accessToken string) error {
 		loaderUserID = userID
 		return errLoading
 	}
-	server := &server{nil, dbmap, loader}
+	server := &server{dbmap: dbmap, startLoading: loader}
 
 	// when the loader returns an error, nothisg should be inserted
-	otherToken := &oauth2.Token{AccessToken: "other token"}
-	userID, project, err := server.getProjectOrStartLoading(otherToken, "project")
+	other := testIdentity(&bqdb.User{Subject: "other", AccessToken: "other token"})
+	userID, project, err := server.getProjectOrStartLoading(other, "project")
 	if !(userID == 0 && project == nil && err == errLoading) {
 		t.Error(userID, project, err)
 	}
 	if loaderUserID <= 0 {
 		t.Error("expected loading to be called")
 	}
-	if countUsers(dbmap, otherToken) != 0 {
-		t.Error(otherToken)
+	if countUsers(dbmap, other) != 0 {
+		t.Error(other)
 	}
 
-	// finishing otherToken cannot work: was not inserted
-	err = server.finishLoading(loaderUserID, "project", nil)
+	// finishing other cannot work: was not inserted
+	err = server.finishLoading(dbmap, loaderUserID, "project", 0, nil)
 	if err == nil || !strings.Contains(err.Error(), "does not exist") {
 		t.Error("expected does not exist error:", err)
 	}
@@ -141,7 +171,7 @@ func TestSaveTables(t *testing.T) {
 	dbmap := newTestDB()
 	defer dbmap.Db.Close()
 
-	s := &server{nil, dbmap, nil}
+	s := &server{dbmap: dbmap}
 
 	tables := []*bigquery.Table{}
 	for i := 0; i < 3; i++ {
@@ -153,11 +183,11 @@ func TestSaveTables(t *testing.T) {
 		tables = append(tables, table)
 	}
 
-	err := s.saveBigqueryTables(42, tables)
+	err := s.saveBigqueryTables(dbmap, 42, 7, "", tables)
 	if err != nil {
 		t.Fatal(err)
 	}
-	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM `Table`")
+	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM `Table` WHERE SnapshotID=7")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -173,6 +203,7 @@ func TestProjectReport(t *testing.T) {
 	table := &bqdb.Table{}
 	table.UserID = 1
 	table.ProjectID = "p"
+	table.SnapshotID = 1
 	table.DatasetID = "d1"
 	table.TableID = "a"
 	table.NumBytes = 1234
@@ -208,7 +239,11 @@ func TestProjectReport(t *testing.T) {
 	}
 	const totalBytes = bigTableBytes*numExtraEntities + 500000 + 1234 + 20*numExtraEntities
 
-	vars, err := queryProject(dbmap, 1, "p")
+	err = dbmap.Insert(&bqdb.Snapshot{UserID: 1, ProjectID: "p", TimeMs: 1000, Complete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars, err := queryProject(dbmap, pricing.Default(), 1, "p", 1)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -235,15 +270,16 @@ func TestProjectReport(t *testing.T) {
 	}
 
 	// TODO: Verify that rendering the template actually works
-	u := &bqdb.User{ID: 1, AccessToken: "token"}
-	p := &bqdb.Project{UserID: 1, ProjectID: table.ProjectID}
+	u := &bqdb.User{ID: 1, Subject: "subject", AccessToken: "token"}
+	p := &bqdb.Project{UserID: 1, ProjectID: table.ProjectID, SnapshotID: 1}
 	err = dbmap.Insert(u, p)
 	if err != nil {
 		t.Fatal(err)
 	}
-	s := server{nil, dbmap, nil}
+	s := server{dbmap: dbmap, prices: pricing.Default()}
 	w := httptest.NewRecorder()
-	err = s.projectIndex(w, nil, &oauth2.Token{AccessToken: u.AccessToken}, p.ProjectID)
+	r := httptest.NewRequest("GET", "/projects/"+p.ProjectID, nil)
+	err = s.projectIndex(w, r, testIdentity(u), p.ProjectID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -254,6 +290,185 @@ func TestProjectReport(t *testing.T) {
 	}
 }
 
+func TestProjectHistory(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadingErr := errors.New("loading error")
+	loader := func(txn gorp.SqlExecutor, userID int64, projectID string, accessToken string) error {
+		return loadingErr
+	}
+	s := &server{dbmap: dbmap, prices: pricing.Default(), startLoading: loader}
+
+	// refreshing a project that does not exist fails
+	identity := testIdentity(u)
+	err = s.refreshProject(identity, "p")
+	if err == nil {
+		t.Error("expected error")
+	}
+
+	// two snapshots: dataset d grows
+	older := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	newer := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 2000, Complete: true}
+	incomplete := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 3000}
+	p := &bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: 2}
+	err = dbmap.Insert(older, newer, incomplete, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []*bqdb.Table{
+		{UserID: u.ID, ProjectID: "p", SnapshotID: older.ID, DatasetID: "d", TableID: "a", NumBytes: 10},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: newer.ID, DatasetID: "d", TableID: "a", NumBytes: 10},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: newer.ID, DatasetID: "d", TableID: "b", NumBytes: 20},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: newer.ID, DatasetID: "e", TableID: "c", NumBytes: 5},
+	} {
+		err = dbmap.Insert(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vars, err := queryProject(dbmap, s.prices, u.ID, "p", newer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(vars.TotalBytes == 35 && len(vars.History) == 2 && vars.History[0].Bytes == 10 &&
+		vars.History[1].Bytes == 35) {
+		t.Error(vars.TotalBytes, vars.History)
+	}
+	if !(len(vars.DatasetHistory) == 2 && vars.DatasetHistory[0].ID == "d" &&
+		reflect.DeepEqual(vars.DatasetHistory[0].Bytes, []int64{10, 30}) &&
+		reflect.DeepEqual(vars.DatasetHistory[1].Bytes, []int64{0, 5})) {
+		t.Error(vars.DatasetHistory)
+	}
+
+	// the page can show an older snapshot
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/projects/p?snapshot="+strconv.FormatInt(older.ID, 10), nil)
+	err = s.projectIndex(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.Body.String(), ">d.a<") || strings.Contains(w.Body.String(), ">d.b<") {
+		t.Error(w.Body.String())
+	}
+
+	// incomplete or missing snapshots are errors
+	for _, id := range []int64{incomplete.ID, 999} {
+		r = httptest.NewRequest("GET", "/projects/p?snapshot="+strconv.FormatInt(id, 10), nil)
+		err = s.projectIndex(httptest.NewRecorder(), r, identity, "p")
+		if err == nil || !strings.Contains(err.Error(), "does not exist") {
+			t.Error(id, err)
+		}
+	}
+
+	// refreshing fails if loading cannot start
+	err = s.refreshProject(identity, "p")
+	if err != loadingErr {
+		t.Error(err)
+	}
+	project, err := bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.IsLoading {
+		t.Error(project)
+	}
+
+	// refreshing starts loading
+	loadingErr = nil
+	err = s.refreshProject(identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, project, err = s.getProjectOrStartLoading(identity, "p")
+	if err != errIsLoading || !project.IsLoading {
+		t.Error(project, err)
+	}
+
+	// a failed refresh still shows the previous snapshot with the error
+	err = s.finishLoading(dbmap, u.ID, "p", 0, errors.New("refresh failed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/projects/p", nil)
+	err = s.projectIndex(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.Body.String(), ">d.b<") || !strings.Contains(w.Body.String(), "refresh failed") {
+		t.Error(w.Body.String())
+	}
+}
+
+func TestProjectCosts(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+
+	const gib = 1024 * 1024 * 1024
+	snapshot := &bqdb.Snapshot{UserID: 1, ProjectID: "p", TimeMs: 1000, Complete: true}
+	err := dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dbmap.Insert(
+		&bqdb.Table{UserID: 1, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "us",
+			TableID: "t", Location: "US", NumBytes: 100 * gib, NumLongTermBytes: 100 * gib},
+		&bqdb.Table{UserID: 1, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "tokyo",
+			TableID: "t", Location: "asia-northeast1", NumBytes: 50 * gib},
+		&bqdb.Dataset{UserID: 1, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "us",
+			Location: "US", DefaultTableExpirationMs: 30 * 24 * 60 * 60 * 1000},
+		&bqdb.Label{UserID: 1, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "us",
+			LabelKey: "team", LabelValue: "data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices, err := pricing.Load(strings.NewReader(`{"version": "test", "rates": [
+		{"billing": "logical", "class": "active", "effective_date": "1970-01-01",
+			"dollars_per_gib_month": 0.02},
+		{"billing": "logical", "class": "long_term", "effective_date": "1970-01-01",
+			"dollars_per_gib_month": 0.01},
+		{"billing": "physical", "class": "active", "effective_date": "1970-01-01",
+			"dollars_per_gib_month": 0.04},
+		{"billing": "physical", "class": "long_term", "effective_date": "1970-01-01",
+			"dollars_per_gib_month": 0.02},
+		{"location": "asia-northeast1", "billing": "logical", "class": "active",
+			"effective_date": "1970-01-01", "dollars_per_gib_month": 0.03}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars, err := queryProject(dbmap, prices, 1, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// long-term US storage: $1; active Tokyo storage: $1.50
+	if !(vars.TotalBytes == 150*gib && vars.TotalLongTermBytes == 100*gib &&
+		math.Abs(vars.ActiveCost-1.5) < 1e-9 && math.Abs(vars.LongTermCost-1) < 1e-9) {
+		t.Error(vars.TotalBytes, vars.TotalLongTermBytes, vars.ActiveCost, vars.LongTermCost)
+	}
+	if !(len(vars.DatasetStorage) == 2 && vars.DatasetStorage[0].Location == "US" &&
+		math.Abs(vars.DatasetStorage[0].DollarsPerMonth-1) < 1e-9 &&
+		math.Abs(vars.DatasetStorage[1].DollarsPerMonth-1.5) < 1e-9) {
+		t.Error(vars.DatasetStorage)
+	}
+	// dataset metadata
+	if !(reflect.DeepEqual(vars.DatasetStorage[0].Labels, []string{"team:data"}) &&
+		vars.DatasetStorage[0].HumanExpiration() == "30 days" &&
+		vars.DatasetStorage[1].HumanExpiration() == "never") {
+		t.Error(vars.DatasetStorage[0], vars.DatasetStorage[1])
+	}
+	if !(len(vars.History) == 1 && math.Abs(vars.History[0].DollarsPerMonth-2.5) < 1e-9) {
+		t.Error(vars.History)
+	}
+}
+
 func TestLoading(t *testing.T) {
 	dbmap := newTestDB()
 	defer dbmap.Db.Close()
@@ -301,7 +516,7 @@ func TestLoading(t *testing.T) {
 	s := server{dbmap: dbmap}
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/projects/"+p.ProjectID, nil)
-	err = s.projectIndex(w, r, &oauth2.Token{AccessToken: "token"}, p.ProjectID)
+	err = s.projectIndex(w, r, testIdentity(&bqdb.User{Subject: "subject"}), p.ProjectID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -310,6 +525,98 @@ func TestLoading(t *testing.T) {
 	}
 }
 
+func TestLogoutUser(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap}
+
+	// users that never loaded a project have nothing to delete
+	err := s.logoutUser(testIdentity(&bqdb.User{Subject: "other"}))
+	if err != nil {
+		t.Error(err)
+	}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	p := &bqdb.Project{UserID: 1, ProjectID: "p"}
+	err = dbmap.Insert(u, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.logoutUser(testIdentity(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := bqdb.GetUserBySubject(dbmap, u.Subject)
+	if !(err == nil && user.ID == u.ID && user.AccessToken == "") {
+		t.Error(user, err)
+	}
+	project, err := bqdb.GetProjectByID(dbmap, u.ID, p.ProjectID)
+	if !(err == nil && project != nil) {
+		t.Error("projects must be kept", project, err)
+	}
+}
+
+func TestClaimRetiredUser(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+
+	// the migration retires users without an email; an operator sets it on the newest one
+	retired := []*bqdb.User{{Subject: "legacy:1"}, {Subject: "legacy:2"}, {Subject: "legacy:3"}}
+	for _, u := range retired {
+		err := dbmap.Insert(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	retired[0].Email = "u@example.com"
+	retired[1].Email = "u@example.com"
+	_, err := dbmap.Update(retired[0], retired[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unverified emails claim nothing
+	unverified := &googlelogin.Identity{Subject: "unverified", Token: &oauth2.Token{}}
+	user, err := getOrCreateUser(dbmap, unverified)
+	if !(err == nil && user.ID == 4) {
+		t.Error(user, err)
+	}
+
+	identity := &googlelogin.Identity{Subject: "subject", Email: "u@example.com",
+		Token: &oauth2.Token{AccessToken: "token"}}
+	user, err = getOrCreateUser(dbmap, identity)
+	if !(err == nil && user.ID == retired[1].ID && user.Subject == "subject" &&
+		user.AccessToken == "token") {
+		t.Error(user, err)
+	}
+	// later logins find the claimed user; the older retired user is not claimed
+	user, err = getOrCreateUser(dbmap, identity)
+	if !(err == nil && user.ID == retired[1].ID) {
+		t.Error(user, err)
+	}
+	user, err = bqdb.GetUserByID(dbmap, retired[0].ID)
+	if !(err == nil && user.Subject == "legacy:1") {
+		t.Error(user, err)
+	}
+}
+
+func TestParseCookieKeyPairs(t *testing.T) {
+	keys, err := parseCookieKeyPairs("")
+	if !(keys == nil && err == nil) {
+		t.Error(keys, err)
+	}
+	keys, err = parseCookieKeyPairs("0102:03,04:05")
+	if !(err == nil && reflect.DeepEqual(keys, [][]byte{{1, 2}, {3}, {4}, {5}})) {
+		t.Error(keys, err)
+	}
+	for _, invalid := range []string{"01", "01:02:03", "01:zz", "01:02,"} {
+		keys, err = parseCookieKeyPairs(invalid)
+		if !(keys == nil && err != nil) {
+			t.Error(invalid, keys, err)
+		}
+	}
+}
+
 // func TestEmptyProject(t *testing.T) {
 // 	t.Error("TODO: empty projects should work")
 // }
@@ -2,15 +2,26 @@ package bqdb
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"reflect"
-	"strings"
+	"time"
 
 	gorp "github.com/go-gorp/gorp"
+	"golang.org/x/oauth2"
+
+	"github.com/evanj/bqtools/googlelogin"
 )
 
+// User is a Google account, so projects and history are kept across logins.
 type User struct {
-	ID          int64  `db:",primarykey,autoincrement"`
+	ID int64 `db:",primarykey,autoincrement"`
+	// Google's ID for the account: see googlelogin.Identity
+	Subject string `db:",notnull"`
+	Email   string `db:",notnull"`
+	// The token from the most recent login, used by background jobs
 	AccessToken string `db:",notnull"`
 }
 
@@ -23,27 +34,271 @@ type Project struct {
 	LoadingPercent int    `db:",notnull"`
 	LoadingMessage string `db:",notnull"`
 	LoadingError   string `db:",notnull"`
+
+	// The most recent complete snapshot, or 0 if the project has never finished loading.
+	SnapshotID int64 `db:",notnull"`
+
+	// Query jobs created before this time have been loaded; 0 if they have never been loaded.
+	QueryJobsLoadedMs int64 `db:",notnull"`
+	// A load stopped at bqscrape's limit on jobs, so older query jobs in the history are missing.
+	QueryJobsTruncated bool `db:",notnull"`
+}
+
+// Snapshot is one scrape of a project. Each scrape saves a new copy of all tables, so storage
+// can be compared over time.
+type Snapshot struct {
+	ID        int64  `db:",primarykey,autoincrement"`
+	UserID    int64  `db:",notnull"`
+	ProjectID string `db:",notnull"`
+	// When the scrape started.
+	TimeMs int64 `db:",notnull"`
+	// The scrape finished successfully.
+	Complete bool `db:",notnull"`
+}
+
+// https://cloud.google.com/bigquery/docs/reference/rest/v2/datasets#resource
+type Dataset struct {
+	UserID     int64
+	ProjectID  string
+	SnapshotID int64
+	DatasetID  string
+
+	FriendlyName string `db:",notnull"`
+	Description  string `db:",notnull"`
+	Location     string `db:",notnull"`
+
+	// 0 if tables in the dataset do not expire by default.
+	DefaultTableExpirationMs int64 `db:",notnull"`
+	// StorageBillingPhysical, LOGICAL, or empty for the default logical billing.
+	StorageBillingModel string `db:",notnull"`
+
+	CreationTimeMs     int64 `db:",notnull"`
+	LastModifiedTimeMs int64 `db:",notnull"`
 }
 
+// StorageBillingModel of datasets billed for compressed bytes.
+// https://cloud.google.com/bigquery/docs/datasets-intro#dataset_storage_billing_models
+const StorageBillingPhysical = "PHYSICAL"
+
+// A label on a dataset or table. Dataset labels have an empty TableID.
+// https://cloud.google.com/bigquery/docs/labels
+type Label struct {
+	UserID     int64
+	ProjectID  string
+	SnapshotID int64
+	DatasetID  string
+	TableID    string
+	// Key is reserved in MySQL
+	LabelKey   string
+	LabelValue string `db:",notnull"`
+}
+
+// PartitionType of tables partitioned by integer range instead of by time.
+const PartitionTypeRange = "RANGE"
+
+// Longest view query and external source URIs that are saved. MySQL rows are limited to 64 KiB.
+const (
+	MaxViewQueryLength  = 8192
+	MaxSourceURIsLength = 4096
+)
+
 // https://cloud.google.com/bigquery/docs/reference/rest/v2/tables#resource
 type Table struct {
-	UserID    int64
-	ProjectID string
-	DatasetID string
-	TableID   string
+	UserID     int64
+	ProjectID  string
+	SnapshotID int64
+	DatasetID  string
+	TableID    string
+
+	// TABLE, VIEW, MATERIALIZED_VIEW, EXTERNAL or SNAPSHOT: see bqscrape.TypeTable
+	Type string `db:",notnull"`
+	// SQL of a view or materialized view, truncated to MaxViewQueryLength.
+	ViewQuery string `db:",notnull"`
+	// External tables only: newline separated source URIs, truncated to MaxSourceURIsLength.
+	SourceFormat string `db:",notnull"`
+	SourceURIs   string `db:",notnull"`
+	// Snapshots and clones: the table they were copied from as project:dataset.table.
+	BaseTable string `db:",notnull"`
 
 	FriendlyName string `db:",notnull"`
 	Description  string `db:",notnull"`
+	// Inherited from the dataset; determines the price of storage.
+	Location string `db:",notnull"`
 
 	NumBytes         int64 `db:",notnull"`
 	NumLongTermBytes int64 `db:",notnull"`
 	NumRows          int64 `db:",notnull"`
+	// Compressed bytes. Unlike NumBytes, active bytes do not include long-term bytes.
+	NumActivePhysicalBytes   int64 `db:",notnull"`
+	NumLongTermPhysicalBytes int64 `db:",notnull"`
+	// Copied from the dataset, so storage can be summed by billing model; see
+	// Dataset.StorageBillingModel.
+	StorageBillingModel string `db:",notnull"`
 
 	CreationTimeMs     int64 `db:",notnull"`
 	LastModifiedTimeMs int64 `db:",notnull"`
 
 	StreamingEstimatedBytes int64 `db:",notnull"`
 	StreamingEstimatedRows  int64 `db:",notnull"`
+
+	// Time partitioning type (e.g. DAY), PartitionTypeRange, or empty if the table is not
+	// partitioned.
+	PartitionType string `db:",notnull"`
+	// Column the table is partitioned by; empty for ingestion time partitioning.
+	PartitionField string `db:",notnull"`
+	// 0 if partitions do not expire.
+	PartitionExpirationMs int64 `db:",notnull"`
+	// Comma separated clustering columns, in order; empty if the table is not clustered.
+	ClusteringFields string `db:",notnull"`
+}
+
+// BilledBytes returns the total and long-term bytes the table is billed for in its dataset's
+// billing model. As with NumBytes, the total includes the long-term bytes.
+func (t *Table) BilledBytes() (int64, int64) {
+	if t.StorageBillingModel == StorageBillingPhysical {
+		return t.NumActivePhysicalBytes + t.NumLongTermPhysicalBytes, t.NumLongTermPhysicalBytes
+	}
+	return t.NumBytes, t.NumLongTermBytes
+}
+
+// Returns SQL that sums the bytes tables are billed for as BilledBytes and BilledLongTermBytes;
+// see Table.BilledBytes. prefix is the alias of the Table table followed by a dot, or empty.
+// Queries must group by StorageBillingModel, which decides the price.
+func SumBilledBytesSQL(prefix string) string {
+	physical := prefix + "StorageBillingModel='" + StorageBillingPhysical + "'"
+	return "SUM(CASE WHEN " + physical + " THEN " + prefix + "NumActivePhysicalBytes+" + prefix +
+		"NumLongTermPhysicalBytes ELSE " + prefix + "NumBytes END) AS BilledBytes," +
+		" SUM(CASE WHEN " + physical + " THEN " + prefix + "NumLongTermPhysicalBytes ELSE " +
+		prefix + "NumLongTermBytes END) AS BilledLongTermBytes"
+}
+
+// One partition of a partitioned table. Partition is reserved in MySQL.
+// https://cloud.google.com/bigquery/docs/partitioned-tables
+type TablePartition struct {
+	UserID      int64
+	ProjectID   string
+	SnapshotID  int64
+	DatasetID   string
+	TableID     string
+	PartitionID string
+
+	NumBytes           int64 `db:",notnull"`
+	NumRows            int64 `db:",notnull"`
+	LastModifiedTimeMs int64 `db:",notnull"`
+	// Billed at the long-term storage rate.
+	LongTerm bool `db:",notnull"`
+}
+
+// Longest query text that is saved.
+const MaxQueryLength = 8192
+
+// A query run in a project, from the BigQuery jobs API. Query jobs are not part of a snapshot:
+// each project keeps one history of its queries.
+type QueryJob struct {
+	UserID    int64
+	ProjectID string
+	JobID     string
+
+	Location  string `db:",notnull"`
+	UserEmail string `db:",notnull"`
+	// Truncated to MaxQueryLength.
+	Query string `db:",notnull"`
+	// Identifies repeated runs of the same query.
+	QueryFingerprint string `db:",notnull"`
+	StatementType    string `db:",notnull"`
+	CreationTimeMs   int64  `db:",notnull"`
+
+	TotalBytesBilled    int64 `db:",notnull"`
+	TotalBytesProcessed int64 `db:",notnull"`
+	TotalSlotMs         int64 `db:",notnull"`
+	CacheHit            bool  `db:",notnull"`
+	// On-demand cost of TotalBytesBilled at the prices when the query ran.
+	DollarsBilled float64 `db:",notnull"`
+}
+
+// A table read by a query job.
+type QueryJobTable struct {
+	UserID    int64
+	ProjectID string
+	JobID     string
+	// project:dataset.table
+	ReferencedTable string
+	// Copied from the job, to find when tables were last read.
+	CreationTimeMs int64 `db:",notnull"`
+}
+
+// Longest recommendation message that is saved.
+const MaxRecommendationMessageLength = 1024
+
+// A way to reduce the cost of a snapshot: see the recommend package.
+type Recommendation struct {
+	UserID     int64
+	ProjectID  string
+	SnapshotID int64
+	// See recommend.Finding
+	Rule            string
+	Resource        string
+	Message         string  `db:",notnull"`
+	DollarsPerMonth float64 `db:",notnull"`
+}
+
+// A recommendation the user does not want to see, in all snapshots of the project.
+type DismissedRecommendation struct {
+	UserID      int64
+	ProjectID   string
+	Rule        string
+	Resource    string
+	DismissedMs int64 `db:",notnull"`
+}
+
+// Job states.
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// A background job that loads a project from BigQuery. A worker claims a job by taking a lease
+// on it. If the worker dies, the lease expires and another worker claims and re-runs the job.
+type Job struct {
+	ID         int64  `db:",primarykey,autoincrement"`
+	UserID     int64  `db:",notnull"`
+	ProjectID  string `db:",notnull"`
+	SnapshotID int64  `db:",notnull"`
+
+	State    string `db:",notnull"`
+	Attempts int    `db:",notnull"`
+
+	LeaseOwner    string `db:",notnull"`
+	LeaseExpiryMs int64  `db:",notnull"`
+
+	// Where to resume scraping: see bqscrape.Checkpoint
+	CheckpointDatasetID string `db:",notnull"`
+	CheckpointPageToken string `db:",notnull"`
+	CheckpointTables    int    `db:",notnull"`
+	CheckpointDone      bool   `db:",notnull"`
+}
+
+// An encrypted OAuth token saved for offline access; see googlelogin.TokenStore.
+type StoredToken struct {
+	// Key is reserved in MySQL
+	TokenKey   string
+	Ciphertext []byte `db:",notnull"`
+}
+
+// Maximum length of LoginSession.TokenJSON: Google's access tokens are a few hundred bytes.
+const MaxSessionTokenLength = 4096
+
+// A googlelogin session; see googlelogin.SessionStore. Session is reserved in MySQL.
+type LoginSession struct {
+	SessionID string
+	Subject   string `db:",notnull"`
+	Email     string `db:",notnull"`
+	// The JSON-encoded oauth2.Token
+	TokenJSON string `db:",notnull"`
+	CreatedMs int64  `db:",notnull"`
+	ExpiresMs int64  `db:",notnull"`
 }
 
 func OpenAndCreateTablesIfNeeded(driver string, path string, dialect gorp.Dialect) (*gorp.DbMap, error) {
@@ -60,31 +315,53 @@ func OpenAndCreateTablesIfNeeded(driver string, path string, dialect gorp.Dialec
 	return dbmap, nil
 }
 
+// Registers the tables with dbmap, creates the tables that do not exist, upgrades tables created
+// by earlier versions, then creates missing indexes.
 func RegisterAndCreateTablesIfNeeded(dbmap *gorp.DbMap) error {
-	dbmap.AddTable(User{}).
-		AddIndex("AccessTokenIndex", "", []string{"AccessToken"}).SetUnique(true)
-	dbmap.AddTable(Project{}).SetKeys(false, "UserID", "ProjectID")
-	dbmap.AddTable(Table{}).SetKeys(false, "UserID", "ProjectID", "DatasetID", "TableID")
+	registerTables(dbmap)
 	err := dbmap.CreateTablesIfNotExists()
 	if err != nil {
 		return err
 	}
-	err = dbmap.CreateIndex()
-	// sqlite: index {{.Name}} already exists
-	// mysql: Duplicate key name '{{.Name}}'
-	if err != nil && (strings.Contains(err.Error(), "already exists") ||
-		strings.Contains(err.Error(), "Duplicate key name")) {
-		// assume this is probably index already exists
-		log.Printf("bqdb: warning: ignoring error indexes already exist: %s", err.Error())
-		return nil
+	err = migrateTables(dbmap)
+	if err != nil {
+		return err
 	}
-	return err
+	return createIndexes(dbmap)
+}
+
+func registerTables(dbmap *gorp.DbMap) {
+	dbmap.AddTable(User{})
+	dbmap.AddTable(Project{}).SetKeys(false, "UserID", "ProjectID")
+	dbmap.AddTable(Snapshot{})
+	dbmap.AddTable(Dataset{}).SetKeys(false, "UserID", "ProjectID", "SnapshotID", "DatasetID")
+	dbmap.AddTable(Label{}).
+		SetKeys(false, "UserID", "ProjectID", "SnapshotID", "DatasetID", "TableID", "LabelKey")
+	tableMap := dbmap.AddTable(Table{}).
+		SetKeys(false, "UserID", "ProjectID", "SnapshotID", "DatasetID", "TableID")
+	tableMap.ColMap("ViewQuery").SetMaxSize(MaxViewQueryLength)
+	tableMap.ColMap("SourceURIs").SetMaxSize(MaxSourceURIsLength)
+	dbmap.AddTable(TablePartition{}).
+		SetKeys(false, "UserID", "ProjectID", "SnapshotID", "DatasetID", "TableID", "PartitionID")
+	dbmap.AddTable(QueryJob{}).SetKeys(false, "UserID", "ProjectID", "JobID").
+		ColMap("Query").SetMaxSize(MaxQueryLength)
+	dbmap.AddTable(QueryJobTable{}).
+		SetKeys(false, "UserID", "ProjectID", "JobID", "ReferencedTable")
+	dbmap.AddTable(Recommendation{}).
+		SetKeys(false, "UserID", "ProjectID", "SnapshotID", "Rule", "Resource").
+		ColMap("Message").SetMaxSize(MaxRecommendationMessageLength)
+	dbmap.AddTable(DismissedRecommendation{}).
+		SetKeys(false, "UserID", "ProjectID", "Rule", "Resource")
+	dbmap.AddTable(Job{})
+	dbmap.AddTable(StoredToken{}).SetKeys(false, "TokenKey")
+	dbmap.AddTable(LoginSession{}).SetKeys(false, "SessionID").
+		ColMap("TokenJSON").SetMaxSize(MaxSessionTokenLength)
 }
 
 // Returns nil, nil if there is no such user (same as dbMap.Get()). TODO: Return err?
-func GetUserByAccessToken(getter gorp.SqlExecutor, accessToken string) (*User, error) {
+func GetUserBySubject(getter gorp.SqlExecutor, subject string) (*User, error) {
 	user := &User{}
-	err := getter.SelectOne(user, "SELECT * FROM User WHERE AccessToken=?", accessToken)
+	err := getter.SelectOne(user, "SELECT * FROM User WHERE Subject=?", subject)
 	if err != nil {
 		user = nil
 	}
@@ -94,6 +371,38 @@ func GetUserByAccessToken(getter gorp.SqlExecutor, accessToken string) (*User, e
 	return user, err
 }
 
+// Returns the user who logs in with email, or nil, nil if there is no such user. Retired users
+// are ignored.
+func GetUserByEmail(getter gorp.SqlExecutor, email string) (*User, error) {
+	user := &User{}
+	err := getter.SelectOne(user, "SELECT * FROM User WHERE Email=? AND Subject NOT LIKE ?",
+		email, legacySubjectPrefix+"%")
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Returns the newest retired user whose Email is email, or nil, nil if there is none. Retired
+// users have no email until an operator sets one, so a login with email can claim the user and
+// its projects. Each login used to create a new user, so an account may have several.
+func GetRetiredUserByEmail(getter gorp.SqlExecutor, email string) (*User, error) {
+	if email == "" {
+		return nil, nil
+	}
+	var users []*User
+	_, err := getter.Select(&users,
+		"SELECT * FROM User WHERE Email=? AND Subject LIKE ? ORDER BY ID DESC LIMIT 1",
+		email, legacySubjectPrefix+"%")
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	return users[0], nil
+}
+
 // Returns nil, nil if there is no such user (same as dbMap.Get()). TODO: Return err?
 func GetUserByID(getter gorp.SqlExecutor, userID int64) (*User, error) {
 	iface, err := getter.Get((*User)(nil), userID)
@@ -120,6 +429,17 @@ func GetProjectByID(getter gorp.SqlExecutor, userID int64, projectID string) (*P
 	return p, nil
 }
 
+// Returns the projects that have been loaded by userID, sorted by ID.
+func ListProjects(getter gorp.SqlExecutor, userID int64) ([]*Project, error) {
+	var projects []*Project
+	_, err := getter.Select(&projects, "SELECT * FROM Project WHERE UserID=? ORDER BY ProjectID",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
 func QuotedTableForQuery(dbmap *gorp.DbMap, i interface{}) (string, error) {
 	// TODO: cache the query?
 	tableMap, err := dbmap.TableFor(reflect.TypeOf(i), false)
@@ -130,12 +450,868 @@ func QuotedTableForQuery(dbmap *gorp.DbMap, i interface{}) (string, error) {
 }
 
 // TODO: Remove this? projectQuery already calls QuotedTableForQuery
-func QueryTotalTableBytes(dbmap *gorp.DbMap, userID int64, projectID string) (int64, error) {
+func QueryTotalTableBytes(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64) (
+	int64, error) {
+
+	return sumTableColumn(dbmap, "NumBytes", userID, projectID, snapshotID)
+}
+
+// Returns the bytes billed at the long-term storage rate. These are included in the total bytes.
+func QueryTotalTableLongTermBytes(dbmap *gorp.DbMap, userID int64, projectID string,
+	snapshotID int64) (int64, error) {
+
+	return sumTableColumn(dbmap, "NumLongTermBytes", userID, projectID, snapshotID)
+}
+
+// Storage in one location with one billing model.
+type LocationStorage struct {
+	Location            string
+	StorageBillingModel string
+	Bytes               int64
+	LongTermBytes       int64
+	// The bytes billed in StorageBillingModel; see Table.BilledBytes.
+	BilledBytes         int64
+	BilledLongTermBytes int64
+}
+
+// Returns the storage in each location and billing model in a snapshot. Storage in different
+// locations and billing models has different prices.
+func QueryStorageByLocation(dbmap *gorp.DbMap, userID int64, projectID string,
+	snapshotID int64) ([]*LocationStorage, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var locations []*LocationStorage
+	_, err = dbmap.Select(&locations,
+		"SELECT Location, StorageBillingModel, SUM(NumBytes) AS Bytes,"+
+			" SUM(NumLongTermBytes) AS LongTermBytes, "+SumBilledBytesSQL("")+" FROM "+quotedTable+
+			" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" GROUP BY Location, StorageBillingModel ORDER BY Location, StorageBillingModel",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// Storage of tables of one type in one location with one billing model.
+type TypeStorage struct {
+	Type                string
+	Location            string
+	StorageBillingModel string
+	Tables              int64
+	Bytes               int64
+	LongTermBytes       int64
+	// The bytes billed in StorageBillingModel; see Table.BilledBytes.
+	BilledBytes         int64
+	BilledLongTermBytes int64
+}
+
+// Returns the number and storage of tables in a snapshot grouped by type, location and billing
+// model.
+func QueryStorageByType(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64) (
+	[]*TypeStorage, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var storage []*TypeStorage
+	_, err = dbmap.Select(&storage,
+		"SELECT Type, Location, StorageBillingModel, COUNT(*) AS Tables, SUM(NumBytes) AS Bytes,"+
+			" SUM(NumLongTermBytes) AS LongTermBytes, "+SumBilledBytesSQL("")+" FROM "+quotedTable+
+			" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" GROUP BY Type, Location, StorageBillingModel ORDER BY Type, Location, StorageBillingModel",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// Returns up to limit tables of tableType in a snapshot, largest first.
+func ListTablesByType(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64,
+	tableType string, limit int) ([]*Table, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var tables []*Table
+	_, err = dbmap.Select(&tables,
+		"SELECT * FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? AND SnapshotID=? AND Type=?"+
+			" ORDER BY NumBytes DESC, DatasetID, TableID LIMIT ?",
+		userID, projectID, snapshotID, tableType, limit)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Returns the keys of table labels in a snapshot, sorted.
+func ListTableLabelKeys(getter gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64) ([]string, error) {
+
+	var keys []string
+	_, err := getter.Select(&keys,
+		"SELECT DISTINCT LabelKey FROM Label WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" AND TableID<>'' ORDER BY LabelKey",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Storage of tables with one value of a label in one location with one billing model.
+type LabelStorage struct {
+	// The tables do not have the label; Value is empty.
+	Unlabeled           bool
+	Value               string
+	Location            string
+	StorageBillingModel string
+	Tables              int64
+	Bytes               int64
+	LongTermBytes       int64
+	// The bytes billed in StorageBillingModel; see Table.BilledBytes.
+	BilledBytes         int64
+	BilledLongTermBytes int64
+}
+
+// Returns the storage of tables in a snapshot grouped by the value of label key, location and
+// billing model.
+// Tables without the label are grouped in rows with Unlabeled set.
+func QueryStorageByLabel(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64,
+	key string) ([]*LabelStorage, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var storage []*LabelStorage
+	_, err = dbmap.Select(&storage,
+		"SELECT CASE WHEN l.LabelKey IS NULL THEN 1 ELSE 0 END AS Unlabeled,"+
+			" COALESCE(l.LabelValue, '') AS Value, t.Location AS Location,"+
+			" t.StorageBillingModel AS StorageBillingModel, COUNT(*) AS Tables,"+
+			" SUM(t.NumBytes) AS Bytes, SUM(t.NumLongTermBytes) AS LongTermBytes, "+
+			SumBilledBytesSQL("t.")+
+			" FROM "+quotedTable+" t LEFT JOIN Label l ON l.UserID=t.UserID AND"+
+			" l.ProjectID=t.ProjectID AND l.SnapshotID=t.SnapshotID AND l.DatasetID=t.DatasetID AND"+
+			" l.TableID=t.TableID AND l.LabelKey=?"+
+			" WHERE t.UserID=? AND t.ProjectID=? AND t.SnapshotID=?"+
+			" GROUP BY Unlabeled, Value, t.Location, t.StorageBillingModel"+
+			" ORDER BY Unlabeled, Value, t.Location, t.StorageBillingModel",
+		key, userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+func sumTableColumn(dbmap *gorp.DbMap, column string, userID int64, projectID string,
+	snapshotID int64) (int64, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return 0, err
+	}
+	query := "SELECT SUM(" + column + ") FROM " + quotedTable +
+		" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"
+
+	return dbmap.SelectInt(query, userID, projectID, snapshotID)
+}
+
+// Deletes all datasets, tables, partitions, labels and recommendations in a snapshot. Used
+// before re-loading a snapshot.
+func DeleteSnapshotTables(dbmap *gorp.DbMap, snapshotID int64) error {
+	for _, model := range []interface{}{Dataset{}, Label{}, Table{}, TablePartition{},
+		Recommendation{}} {
+		quotedTable, err := QuotedTableForQuery(dbmap, model)
+		if err != nil {
+			return err
+		}
+		_, err = dbmap.Exec("DELETE FROM "+quotedTable+" WHERE SnapshotID=?", snapshotID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns nil, nil if there is no such dataset in the snapshot (same as dbMap.Get()).
+func GetDataset(getter gorp.SqlExecutor, userID int64, projectID string, snapshotID int64,
+	datasetID string) (*Dataset, error) {
+
+	iface, err := getter.Get((*Dataset)(nil), userID, projectID, snapshotID, datasetID)
+	if err != nil {
+		return nil, err
+	}
+	var d *Dataset
+	if iface != nil {
+		d = iface.(*Dataset)
+	}
+	return d, nil
+}
+
+// Returns nil, nil if there is no such table in the snapshot (same as dbMap.Get()).
+func GetTable(getter gorp.SqlExecutor, userID int64, projectID string, snapshotID int64,
+	datasetID string, tableID string) (*Table, error) {
+
+	iface, err := getter.Get((*Table)(nil), userID, projectID, snapshotID, datasetID, tableID)
+	if err != nil {
+		return nil, err
+	}
+	var t *Table
+	if iface != nil {
+		t = iface.(*Table)
+	}
+	return t, nil
+}
+
+// Returns the partitions of a table in a snapshot sorted by ID.
+func ListTablePartitions(getter gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64, datasetID string, tableID string) ([]*TablePartition, error) {
+
+	var partitions []*TablePartition
+	_, err := getter.Select(&partitions,
+		"SELECT * FROM TablePartition WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" AND DatasetID=? AND TableID=? ORDER BY PartitionID",
+		userID, projectID, snapshotID, datasetID, tableID)
+	if err != nil {
+		return nil, err
+	}
+	return partitions, nil
+}
+
+// Returns the datasets in a snapshot sorted by ID.
+func ListDatasets(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64) (
+	[]*Dataset, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Dataset{})
+	if err != nil {
+		return nil, err
+	}
+	var datasets []*Dataset
+	_, err = dbmap.Select(&datasets,
+		"SELECT * FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" ORDER BY DatasetID",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return datasets, nil
+}
+
+// Returns the dataset labels in a snapshot, sorted by dataset and key.
+func ListDatasetLabels(dbmap *gorp.DbMap, userID int64, projectID string,
+	snapshotID int64) ([]*Label, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Label{})
+	if err != nil {
+		return nil, err
+	}
+	var labels []*Label
+	_, err = dbmap.Select(&labels,
+		"SELECT * FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? AND SnapshotID=? AND TableID=''"+
+			" ORDER BY DatasetID, LabelKey",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// Returns nil, nil if there is no such snapshot for the project (same as dbMap.Get()).
+func GetSnapshot(getter gorp.SqlExecutor, userID int64, projectID string, snapshotID int64) (
+	*Snapshot, error) {
+
+	iface, err := getter.Get((*Snapshot)(nil), snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if iface == nil {
+		return nil, nil
+	}
+	snapshot := iface.(*Snapshot)
+	if snapshot.UserID != userID || snapshot.ProjectID != projectID {
+		return nil, nil
+	}
+	return snapshot, nil
+}
+
+// Marks a snapshot as successfully loaded.
+func CompleteSnapshot(executor gorp.SqlExecutor, snapshotID int64) error {
+	_, err := executor.Exec("UPDATE Snapshot SET Complete=? WHERE ID=?", true, snapshotID)
+	return err
+}
+
+// Returns the complete snapshots for projectID, oldest first.
+func ListCompleteSnapshots(getter gorp.SqlExecutor, userID int64, projectID string) (
+	[]*Snapshot, error) {
+
+	var snapshots []*Snapshot
+	_, err := getter.Select(&snapshots,
+		"SELECT * FROM Snapshot WHERE UserID=? AND ProjectID=? AND Complete=? ORDER BY TimeMs, ID",
+		userID, projectID, true)
+	return snapshots, err
+}
+
+// Deletes the query jobs in a project created at or after minCreationTimeMs. Used before
+// re-loading query jobs that may have been partially loaded.
+func DeleteQueryJobsSince(executor gorp.SqlExecutor, userID int64, projectID string,
+	minCreationTimeMs int64) error {
+
+	for _, table := range []string{"QueryJob", "QueryJobTable"} {
+		_, err := executor.Exec("DELETE FROM "+table+
+			" WHERE UserID=? AND ProjectID=? AND CreationTimeMs>=?",
+			userID, projectID, minCreationTimeMs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records that query jobs created before loadedMs have been loaded. truncated records that some
+// older query jobs are missing.
+func SetQueryJobsLoaded(executor gorp.SqlExecutor, userID int64, projectID string,
+	loadedMs int64, truncated bool) error {
+
+	_, err := executor.Exec(
+		"UPDATE Project SET QueryJobsLoadedMs=?, QueryJobsTruncated=? WHERE UserID=? AND ProjectID=?",
+		loadedMs, truncated, userID, projectID)
+	return err
+}
+
+// Query cost grouped by one attribute of the queries.
+type QuerySpend struct {
+	// The value of the attribute, e.g. a user email. Key is reserved in MySQL.
+	Name        string
+	Jobs        int64
+	BytesBilled int64
+	SlotMs      int64
+	Dollars     float64
+	// One of the queries; only set by QuerySpendByFingerprint.
+	Example string
+}
+
+// SpendOrder sorts lists of QuerySpend, breaking ties by Name.
+type SpendOrder string
+
+const (
+	OrderByDollars     SpendOrder = "Dollars DESC, BytesBilled DESC"
+	OrderByBytesBilled SpendOrder = "BytesBilled DESC, Jobs DESC"
+	OrderByJobs        SpendOrder = "Jobs DESC, BytesBilled DESC"
+)
+
+func querySpend(getter gorp.SqlExecutor, selectSQL string, userID int64, projectID string,
+	minCreationTimeMs int64, order SpendOrder, limit int) ([]*QuerySpend, error) {
+
+	var spend []*QuerySpend
+	_, err := getter.Select(&spend,
+		selectSQL+" COUNT(*) AS Jobs, SUM(j.TotalBytesBilled) AS BytesBilled,"+
+			" SUM(j.TotalSlotMs) AS SlotMs, SUM(j.DollarsBilled) AS Dollars"+
+			" FROM QueryJob j WHERE j.UserID=? AND j.ProjectID=? AND j.CreationTimeMs>=?"+
+			" GROUP BY Name ORDER BY "+string(order)+", Name LIMIT ?",
+		userID, projectID, minCreationTimeMs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
+
+// Returns the total cost of queries created at or after minCreationTimeMs. Name is empty.
+func QueryTotalSpend(getter gorp.SqlExecutor, userID int64, projectID string,
+	minCreationTimeMs int64) (*QuerySpend, error) {
+
+	spend, err := querySpend(getter, "SELECT '' AS Name,", userID, projectID, minCreationTimeMs,
+		OrderByDollars, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(spend) == 0 || spend[0].Jobs == 0 {
+		return &QuerySpend{}, nil
+	}
+	return spend[0], nil
+}
+
+// Returns the cost of queries created at or after minCreationTimeMs by user, most expensive first.
+func QuerySpendByUser(getter gorp.SqlExecutor, userID int64, projectID string,
+	minCreationTimeMs int64, limit int) ([]*QuerySpend, error) {
+
+	return querySpend(getter, "SELECT j.UserEmail AS Name,", userID, projectID, minCreationTimeMs,
+		OrderByDollars, limit)
+}
+
+// Returns the cost of queries created at or after minCreationTimeMs by fingerprint, sorted by
+// order. Example is one of the queries with the fingerprint.
+func QuerySpendByFingerprint(getter gorp.SqlExecutor, userID int64, projectID string,
+	minCreationTimeMs int64, order SpendOrder, limit int) ([]*QuerySpend, error) {
+
+	return querySpend(getter, "SELECT j.QueryFingerprint AS Name, MAX(j.Query) AS Example,", userID,
+		projectID, minCreationTimeMs, order, limit)
+}
+
+// Returns the cost of queries created at or after minCreationTimeMs by the tables they read,
+// most expensive first. A query that reads several tables counts towards each of them.
+func QuerySpendByTable(getter gorp.SqlExecutor, userID int64, projectID string,
+	minCreationTimeMs int64, limit int) ([]*QuerySpend, error) {
+
+	var spend []*QuerySpend
+	_, err := getter.Select(&spend,
+		"SELECT t.ReferencedTable AS Name, COUNT(*) AS Jobs, SUM(j.TotalBytesBilled) AS BytesBilled,"+
+			" SUM(j.TotalSlotMs) AS SlotMs, SUM(j.DollarsBilled) AS Dollars"+
+			" FROM QueryJobTable t JOIN QueryJob j ON j.UserID=t.UserID AND"+
+			" j.ProjectID=t.ProjectID AND j.JobID=t.JobID"+
+			" WHERE t.UserID=? AND t.ProjectID=? AND t.CreationTimeMs>=?"+
+			" GROUP BY Name ORDER BY Dollars DESC, BytesBilled DESC, Name LIMIT ?",
+		userID, projectID, minCreationTimeMs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
+
+// Returns the tables in a snapshot that store data and were last modified before
+// maxLastModifiedTimeMs, largest first.
+func ListTablesModifiedBefore(dbmap *gorp.DbMap, userID int64, projectID string,
+	snapshotID int64, maxLastModifiedTimeMs int64) ([]*Table, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var tables []*Table
+	_, err = dbmap.Select(&tables,
+		"SELECT * FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" AND LastModifiedTimeMs<? AND NumBytes>0 ORDER BY NumBytes DESC, DatasetID, TableID",
+		userID, projectID, snapshotID, maxLastModifiedTimeMs)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// The last time a table was read by a query.
+type TableRead struct {
+	// project:dataset.table
+	ReferencedTable string
+	LastReadTimeMs  int64
+}
+
+// Returns the last time each table was read by the loaded queries in a project.
+func ListTableReads(dbmap *gorp.DbMap, userID int64, projectID string) ([]*TableRead, error) {
+	quotedTable, err := QuotedTableForQuery(dbmap, QueryJobTable{})
+	if err != nil {
+		return nil, err
+	}
+	var reads []*TableRead
+	_, err = dbmap.Select(&reads,
+		"SELECT ReferencedTable, MAX(CreationTimeMs) AS LastReadTimeMs FROM "+quotedTable+
+			" WHERE UserID=? AND ProjectID=? GROUP BY ReferencedTable",
+		userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return reads, nil
+}
+
+// Returns the creation time of the oldest loaded query job in a project, or 0 if there are none.
+func OldestQueryJobTimeMs(dbmap *gorp.DbMap, userID int64, projectID string) (int64, error) {
+	quotedTable, err := QuotedTableForQuery(dbmap, QueryJob{})
+	if err != nil {
+		return 0, err
+	}
+	oldest, err := dbmap.SelectNullInt(
+		"SELECT MIN(CreationTimeMs) FROM "+quotedTable+" WHERE UserID=? AND ProjectID=?",
+		userID, projectID)
+	if err != nil {
+		return 0, err
+	}
+	return oldest.Int64, nil
+}
+
+// Returns all tables in a snapshot, sorted by ID.
+func ListTables(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64) (
+	[]*Table, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var tables []*Table
+	_, err = dbmap.Select(&tables,
+		"SELECT * FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" ORDER BY DatasetID, TableID",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// TableOrder sorts lists of tables, breaking ties by ID.
+type TableOrder string
+
+const (
+	OrderTablesByBytes         TableOrder = "NumBytes DESC"
+	OrderTablesByLongTermBytes TableOrder = "NumLongTermBytes DESC"
+	OrderTablesByRows          TableOrder = "NumRows DESC"
+	OrderTablesByLastModified  TableOrder = "LastModifiedTimeMs DESC"
+	OrderTablesByID            TableOrder = "DatasetID, TableID"
+)
+
+// Returns up to limit tables in a snapshot sorted by order, skipping the first offset tables.
+func ListTablesPage(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64,
+	order TableOrder, offset int, limit int) ([]*Table, error) {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return nil, err
+	}
+	var tables []*Table
+	_, err = dbmap.Select(&tables,
+		"SELECT * FROM "+quotedTable+" WHERE UserID=? AND ProjectID=? AND SnapshotID=?"+
+			" ORDER BY "+string(order)+", DatasetID, TableID LIMIT ? OFFSET ?",
+		userID, projectID, snapshotID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Returns the number of tables in a snapshot.
+func CountTables(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64) (int64,
+	error) {
+
 	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
 	if err != nil {
 		return 0, err
 	}
-	query := "SELECT SUM(NumBytes) FROM " + quotedTable + " WHERE UserID=? AND ProjectID=?"
+	return dbmap.SelectInt("SELECT COUNT(*) FROM "+quotedTable+
+		" WHERE UserID=? AND ProjectID=? AND SnapshotID=?", userID, projectID, snapshotID)
+}
+
+// Calls f with each table in a snapshot, sorted by ID. Tables are read one at a time so large
+// projects are not loaded in memory. Only the ID, type, location, size and time columns are set.
+func EachTable(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64,
+	f func(*Table) error) error {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Table{})
+	if err != nil {
+		return err
+	}
+	rows, err := dbmap.Db.Query(
+		"SELECT DatasetID, TableID, Type, Location, NumBytes, NumLongTermBytes, NumRows,"+
+			" CreationTimeMs, LastModifiedTimeMs FROM "+quotedTable+
+			" WHERE UserID=? AND ProjectID=? AND SnapshotID=? ORDER BY DatasetID, TableID",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		table := &Table{UserID: userID, ProjectID: projectID, SnapshotID: snapshotID}
+		err = rows.Scan(&table.DatasetID, &table.TableID, &table.Type, &table.Location,
+			&table.NumBytes, &table.NumLongTermBytes, &table.NumRows, &table.CreationTimeMs,
+			&table.LastModifiedTimeMs)
+		if err != nil {
+			return err
+		}
+		err = f(table)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Replaces the recommendations for a snapshot.
+func SaveRecommendations(executor gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64, recommendations []*Recommendation) error {
+
+	_, err := executor.Exec(
+		"DELETE FROM Recommendation WHERE UserID=? AND ProjectID=? AND SnapshotID=?",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	rows := make([]interface{}, len(recommendations))
+	for i, recommendation := range recommendations {
+		rows[i] = recommendation
+	}
+	return executor.Insert(rows...)
+}
+
+// Returns the recommendations for a snapshot that the user has not dismissed, largest savings
+// first.
+func ListRecommendations(getter gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64) ([]*Recommendation, error) {
+
+	var recommendations []*Recommendation
+	_, err := getter.Select(&recommendations,
+		"SELECT r.* FROM Recommendation r LEFT JOIN DismissedRecommendation d"+
+			" ON d.UserID=r.UserID AND d.ProjectID=r.ProjectID AND d.Rule=r.Rule AND"+
+			" d.Resource=r.Resource"+
+			" WHERE r.UserID=? AND r.ProjectID=? AND r.SnapshotID=? AND d.UserID IS NULL"+
+			" ORDER BY r.DollarsPerMonth DESC, r.Rule, r.Resource",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return recommendations, nil
+}
+
+// Hides the recommendation for rule and resource from the user in all snapshots of the
+// project. Dismissing a recommendation twice does nothing.
+func DismissRecommendation(executor gorp.SqlExecutor, userID int64, projectID string,
+	rule string, resource string, nowMs int64) error {
+
+	iface, err := executor.Get((*DismissedRecommendation)(nil), userID, projectID, rule, resource)
+	if err != nil || iface != nil {
+		return err
+	}
+	return executor.Insert(&DismissedRecommendation{UserID: userID, ProjectID: projectID,
+		Rule: rule, Resource: resource, DismissedMs: nowMs})
+}
+
+// Returned when a worker tries to update a job whose lease was taken by another worker.
+var ErrLeaseLost = errors.New("bqdb: job lease is owned by another worker")
+
+// Claims the oldest pending job, or a running job with an expired lease, for owner. Returns
+// nil, nil if there are no jobs to run, or if another worker claimed the job first.
+func ClaimJob(dbmap *gorp.DbMap, owner string, nowMs int64, leaseMs int64) (*Job, error) {
+	quotedTable, err := QuotedTableForQuery(dbmap, Job{})
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	err = dbmap.SelectOne(job, "SELECT * FROM "+quotedTable+
+		" WHERE State=? OR (State=? AND LeaseExpiryMs<?) ORDER BY ID LIMIT 1",
+		JobPending, JobRunning, nowMs)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// only updates the row if no other worker claimed it since we read it
+	result, err := dbmap.Exec("UPDATE "+quotedTable+" SET State=?, Attempts=Attempts+1, "+
+		"LeaseOwner=?, LeaseExpiryMs=? WHERE ID=? AND State=? AND LeaseOwner=? AND LeaseExpiryMs=?",
+		JobRunning, owner, nowMs+leaseMs, job.ID, job.State, job.LeaseOwner, job.LeaseExpiryMs)
+	if err != nil {
+		return nil, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if count != 1 {
+		log.Printf("bqdb: job %d was claimed by another worker", job.ID)
+		return nil, nil
+	}
+
+	job.State = JobRunning
+	job.Attempts++
+	job.LeaseOwner = owner
+	job.LeaseExpiryMs = nowMs + leaseMs
+	return job, nil
+}
+
+// Extends the lease on a running job. Returns ErrLeaseLost if job is no longer owned by
+// job.LeaseOwner. The query runs on executor, which is dbmap or one of its transactions.
+func RenewJobLease(dbmap *gorp.DbMap, executor gorp.SqlExecutor, job *Job, nowMs int64,
+	leaseMs int64) error {
+
+	err := updateLeasedJob(dbmap, executor, job, "LeaseExpiryMs=?", nowMs+leaseMs)
+	if err != nil {
+		return err
+	}
+	job.LeaseExpiryMs = nowMs + leaseMs
+	return nil
+}
+
+// Releases the lease on a running job and sets its state. Use JobPending to have the job
+// retried. Returns ErrLeaseLost if job is no longer owned by job.LeaseOwner. The query runs on
+// executor, which is dbmap or one of its transactions.
+func EndJobLease(dbmap *gorp.DbMap, executor gorp.SqlExecutor, job *Job, state string) error {
+	err := updateLeasedJob(dbmap, executor, job, "State=?, LeaseOwner='', LeaseExpiryMs=0", state)
+	if err != nil {
+		return err
+	}
+	job.State = state
+	job.LeaseOwner = ""
+	job.LeaseExpiryMs = 0
+	return nil
+}
+
+// Saves the job's Checkpoint fields. Call in the same transaction that saves the scraped tables.
+// Returns ErrLeaseLost if job is no longer owned by job.LeaseOwner. The query runs on executor,
+// which is dbmap or one of its transactions.
+func SaveJobCheckpoint(dbmap *gorp.DbMap, executor gorp.SqlExecutor, job *Job) error {
+	return updateLeasedJob(dbmap, executor, job,
+		"CheckpointDatasetID=?, CheckpointPageToken=?, CheckpointTables=?, CheckpointDone=?",
+		job.CheckpointDatasetID, job.CheckpointPageToken, job.CheckpointTables, job.CheckpointDone)
+}
+
+// gorp's transactions do not expose their DbMap, which is needed to quote the table name.
+func updateLeasedJob(dbmap *gorp.DbMap, executor gorp.SqlExecutor, job *Job, set string,
+	args ...interface{}) error {
+
+	quotedTable, err := QuotedTableForQuery(dbmap, Job{})
+	if err != nil {
+		return err
+	}
+	args = append(args, job.ID, JobRunning, job.LeaseOwner)
+	result, err := executor.Exec("UPDATE "+quotedTable+" SET "+set+
+		" WHERE ID=? AND State=? AND LeaseOwner=?", args...)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// TokenStore stores tokens for googlelogin offline access in the database.
+type TokenStore struct {
+	dbmap *gorp.DbMap
+}
+
+func NewTokenStore(dbmap *gorp.DbMap) *TokenStore {
+	return &TokenStore{dbmap}
+}
+
+func (s *TokenStore) PutToken(key string, value []byte) error {
+	txn, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	// don't forget to rollback
+	defer txn.Rollback()
+	_, err = txn.Exec("DELETE FROM StoredToken WHERE TokenKey=?", key)
+	if err != nil {
+		return err
+	}
+	err = txn.Insert(&StoredToken{key, value})
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// Returns nil, nil if key does not exist.
+func (s *TokenStore) GetToken(key string) ([]byte, error) {
+	iface, err := s.dbmap.Get((*StoredToken)(nil), key)
+	if err != nil || iface == nil {
+		return nil, err
+	}
+	return iface.(*StoredToken).Ciphertext, nil
+}
+
+func (s *TokenStore) DeleteToken(key string) error {
+	_, err := s.dbmap.Exec("DELETE FROM StoredToken WHERE TokenKey=?", key)
+	return err
+}
+
+// SessionStore stores googlelogin sessions in the database.
+type SessionStore struct {
+	dbmap *gorp.DbMap
+}
+
+func NewSessionStore(dbmap *gorp.DbMap) *SessionStore {
+	return &SessionStore{dbmap}
+}
+
+func toMs(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMs(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func (s *SessionStore) PutSession(session *googlelogin.Session) error {
+	tokenJSON, err := json.Marshal(session.Token)
+	if err != nil {
+		return err
+	}
+	if len(tokenJSON) > MaxSessionTokenLength {
+		return fmt.Errorf("bqdb: session token is too long: %d bytes", len(tokenJSON))
+	}
+
+	txn, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	// don't forget to rollback
+	defer txn.Rollback()
+	_, err = txn.Exec("DELETE FROM LoginSession WHERE SessionID=?", session.ID)
+	if err != nil {
+		return err
+	}
+	err = txn.Insert(&LoginSession{session.ID, session.Subject, session.Email, string(tokenJSON),
+		toMs(session.CreatedAt), toMs(session.ExpiresAt)})
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+func (l *LoginSession) session() (*googlelogin.Session, error) {
+	var token *oauth2.Token
+	err := json.Unmarshal([]byte(l.TokenJSON), &token)
+	if err != nil {
+		return nil, err
+	}
+	return &googlelogin.Session{ID: l.SessionID, Subject: l.Subject, Email: l.Email,
+		Token: token, CreatedAt: fromMs(l.CreatedMs), ExpiresAt: fromMs(l.ExpiresMs)}, nil
+}
+
+// Returns nil, nil if id does not exist.
+func (s *SessionStore) GetSession(id string) (*googlelogin.Session, error) {
+	iface, err := s.dbmap.Get((*LoginSession)(nil), id)
+	if err != nil || iface == nil {
+		return nil, err
+	}
+	return iface.(*LoginSession).session()
+}
+
+func (s *SessionStore) ListSessions(subject string) ([]*googlelogin.Session, error) {
+	var rows []*LoginSession
+	_, err := s.dbmap.Select(&rows,
+		"SELECT * FROM LoginSession WHERE Subject=? ORDER BY CreatedMs, SessionID", subject)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*googlelogin.Session, len(rows))
+	for i, row := range rows {
+		sessions[i], err = row.session()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+func (s *SessionStore) DeleteSession(id string) error {
+	_, err := s.dbmap.Exec("DELETE FROM LoginSession WHERE SessionID=?", id)
+	return err
+}
 
-	return dbmap.SelectInt(query, userID, projectID)
+func (s *SessionStore) DeleteExpiredSessions(now time.Time) (int, error) {
+	result, err := s.dbmap.Exec("DELETE FROM LoginSession WHERE ExpiresMs<?", toMs(now))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
 }
@@ -1,11 +1,18 @@
 package bqdb
 
 import (
+	"database/sql"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-gorp/gorp"
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/oauth2"
+
+	"github.com/evanj/bqtools/googlelogin"
 )
 
 func TestRegister(t *testing.T) {
@@ -16,7 +23,9 @@ func TestRegister(t *testing.T) {
 	}
 	defer dbmap.Db.Close()
 	user := &User{}
-	user.AccessToken = "foo"
+	user.Subject = "foo"
+	user.Email = "foo@example.com"
+	user.AccessToken = "token"
 	err = dbmap.Insert(user)
 	if err != nil {
 		t.Error(err)
@@ -37,15 +46,15 @@ func TestRegister(t *testing.T) {
 		t.Error(u2, user)
 	}
 
-	// cannot insert duplicate access tokens
+	// cannot insert duplicate subjects
 	u2 = &User{}
-	u2.AccessToken = "foo"
+	u2.Subject = "foo"
 	err = dbmap.Insert(u2)
 	if err == nil {
 		t.Error(err)
 	}
 
-	u2, err = GetUserByAccessToken(dbmap, "foo")
+	u2, err = GetUserBySubject(dbmap, "foo")
 	if err != nil {
 		t.Error(err)
 	}
@@ -58,7 +67,21 @@ func TestRegister(t *testing.T) {
 	if !(u2 == nil && err == nil) {
 		t.Error(u2, err)
 	}
-	u2, err = GetUserByAccessToken(dbmap, "does not exist")
+	u2, err = GetUserBySubject(dbmap, "does not exist")
+	if !(u2 == nil && err == nil) {
+		t.Error(u2, err)
+	}
+
+	// retired users with the same email are ignored
+	err = dbmap.Insert(&User{Subject: legacySubjectPrefix + "1", Email: "foo@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err = GetUserByEmail(dbmap, "foo@example.com")
+	if !(err == nil && reflect.DeepEqual(u2, user)) {
+		t.Error(u2, err)
+	}
+	u2, err = GetUserByEmail(dbmap, "does-not-exist@example.com")
 	if !(u2 == nil && err == nil) {
 		t.Error(u2, err)
 	}
@@ -73,7 +96,7 @@ func TestQuerySum(t *testing.T) {
 	defer dbmap.Db.Close()
 
 	// does not exist: should return an error
-	count, err := QueryTotalTableBytes(dbmap, 42, "project")
+	count, err := QueryTotalTableBytes(dbmap, 42, "project", 1)
 	if err == nil {
 		t.Error(count, err)
 	}
@@ -81,6 +104,7 @@ func TestQuerySum(t *testing.T) {
 	table := &Table{}
 	table.UserID = 42
 	table.ProjectID = "project"
+	table.SnapshotID = 1
 	table.TableID = "a"
 	table.NumBytes = 5
 	err = dbmap.Insert(table)
@@ -89,18 +113,230 @@ func TestQuerySum(t *testing.T) {
 	}
 	table.TableID = "b"
 	table.NumBytes = 7
+	table.NumLongTermBytes = 3
+	table.Location = "EU"
 	err = dbmap.Insert(table)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	count, err = QueryTotalTableBytes(dbmap, 42, "project")
+	// a different snapshot is not included
+	table.SnapshotID = 2
+	table.NumBytes = 100
+	err = dbmap.Insert(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err = QueryTotalTableBytes(dbmap, 42, "project", 1)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if count != 12 {
 		t.Error(count)
 	}
+	count, err = QueryTotalTableLongTermBytes(dbmap, 42, "project", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Error(count)
+	}
+	locations, err := QueryStorageByLocation(dbmap, 42, "project", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(locations) == 2 && *locations[0] == LocationStorage{"", "", 5, 0, 5, 0} &&
+		*locations[1] == LocationStorage{"EU", "", 7, 3, 7, 3}) {
+		t.Error(locations)
+	}
+
+	// datasets with physical billing are billed for compressed bytes
+	physical := &Table{UserID: 42, ProjectID: "project", SnapshotID: 1, TableID: "c",
+		Location: "EU", NumBytes: 20, NumLongTermBytes: 10, NumActivePhysicalBytes: 4,
+		NumLongTermPhysicalBytes: 2, StorageBillingModel: StorageBillingPhysical}
+	err = dbmap.Insert(physical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, longTermBytes := physical.BilledBytes()
+	if !(bytes == 6 && longTermBytes == 2) {
+		t.Error(bytes, longTermBytes)
+	}
+	locations, err = QueryStorageByLocation(dbmap, 42, "project", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(locations) == 3 && *locations[1] == LocationStorage{"EU", "", 7, 3, 7, 3} &&
+		*locations[2] == LocationStorage{"EU", "PHYSICAL", 20, 10, 6, 2}) {
+		t.Error(locations)
+	}
+
+	err = dbmap.Insert(
+		&Dataset{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d"},
+		&Label{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d", LabelKey: "k"},
+		&TablePartition{UserID: 42, ProjectID: "project", SnapshotID: 1, TableID: "b",
+			PartitionID: "20170101"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DeleteSnapshotTables(dbmap, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datasets, err := ListDatasets(dbmap, 42, "project", 1)
+	if err != nil || len(datasets) != 0 {
+		t.Error(datasets, err)
+	}
+	labels, err := ListDatasetLabels(dbmap, 42, "project", 1)
+	if err != nil || len(labels) != 0 {
+		t.Error(labels, err)
+	}
+	partitions, err := ListTablePartitions(dbmap, 42, "project", 1, "", "b")
+	if err != nil || len(partitions) != 0 {
+		t.Error(partitions, err)
+	}
+	count, err = QueryTotalTableBytes(dbmap, 42, "project", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 100 {
+		t.Error(count)
+	}
+	count, err = dbmap.SelectInt("SELECT COUNT(*) FROM `Table`")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error(count)
+	}
+}
+
+func TestTablePartitions(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	table := &Table{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d",
+		TableID: "t", PartitionType: "DAY", ClusteringFields: "a,b"}
+	newer := &TablePartition{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d",
+		TableID: "t", PartitionID: "20170102", NumBytes: 7}
+	older := &TablePartition{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d",
+		TableID: "t", PartitionID: "20170101", NumBytes: 5, LongTerm: true}
+	otherTable := &TablePartition{UserID: 42, ProjectID: "project", SnapshotID: 1,
+		DatasetID: "d", TableID: "other", PartitionID: "20170101"}
+	err = dbmap.Insert(table, newer, older, otherTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t2, err := GetTable(dbmap, 42, "project", 1, "d", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(t2, table) {
+		t.Error(t2)
+	}
+	t2, err = GetTable(dbmap, 42, "project", 2, "d", "t")
+	if !(t2 == nil && err == nil) {
+		t.Error(t2, err)
+	}
+
+	partitions, err := ListTablePartitions(dbmap, 42, "project", 1, "d", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(partitions, []*TablePartition{older, newer}) {
+		t.Error(partitions)
+	}
+}
+
+func TestTableTypes(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	small := &Table{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d",
+		TableID: "small", Type: "TABLE", NumBytes: 5}
+	large := &Table{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d",
+		TableID: "large", Type: "TABLE", NumBytes: 10}
+	view := &Table{UserID: 42, ProjectID: "project", SnapshotID: 1, DatasetID: "d",
+		TableID: "view", Type: "VIEW", ViewQuery: "SELECT 1"}
+	err = dbmap.Insert(small, large, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := QueryStorageByType(dbmap, 42, "project", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(storage) == 2 && *storage[0] == TypeStorage{"TABLE", "", "", 2, 15, 0, 15, 0} &&
+		*storage[1] == TypeStorage{"VIEW", "", "", 1, 0, 0, 0, 0}) {
+		t.Error(storage)
+	}
+
+	tables, err := ListTablesByType(dbmap, 42, "project", 1, "TABLE", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tables, []*Table{large, small}) {
+		t.Error(tables)
+	}
+	tables, err = ListTablesByType(dbmap, 42, "project", 1, "TABLE", 1)
+	if err != nil || len(tables) != 1 {
+		t.Error(tables, err)
+	}
+	tables, err = ListTablesByType(dbmap, 42, "project", 1, "VIEW", 10)
+	if err != nil || !reflect.DeepEqual(tables, []*Table{view}) {
+		t.Error(tables, err)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	newer := &Snapshot{UserID: 42, ProjectID: "project", TimeMs: 2000, Complete: true}
+	older := &Snapshot{UserID: 42, ProjectID: "project", TimeMs: 1000, Complete: true}
+	incomplete := &Snapshot{UserID: 42, ProjectID: "project", TimeMs: 3000}
+	otherProject := &Snapshot{UserID: 42, ProjectID: "other", TimeMs: 1000, Complete: true}
+	err = dbmap.Insert(newer, older, incomplete, otherProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := ListCompleteSnapshots(dbmap, 42, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(snapshots, []*Snapshot{older, newer}) {
+		t.Error(snapshots)
+	}
+
+	snapshot, err := GetSnapshot(dbmap, 42, "project", incomplete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(snapshot, incomplete) {
+		t.Error(snapshot)
+	}
+	// snapshots for other projects or users are not found
+	snapshot, err = GetSnapshot(dbmap, 42, "project", otherProject.ID)
+	if !(snapshot == nil && err == nil) {
+		t.Error(snapshot, err)
+	}
+	snapshot, err = GetSnapshot(dbmap, 43, "project", older.ID)
+	if !(snapshot == nil && err == nil) {
+		t.Error(snapshot, err)
+	}
 }
 
 func TestQuotedTable(t *testing.T) {
@@ -128,3 +364,659 @@ func TestQuotedTable(t *testing.T) {
 		t.Error(table)
 	}
 }
+
+// User, Project and Table before snapshots and accounts were added.
+type baselineUser struct {
+	ID          int64
+	AccessToken string
+}
+
+type baselineProject struct {
+	UserID         int64
+	ProjectID      string
+	FriendlyName   string
+	IsLoading      bool
+	LoadingPercent int
+	LoadingMessage string
+	LoadingError   string
+}
+
+type baselineTable struct {
+	UserID    int64
+	ProjectID string
+	DatasetID string
+	TableID   string
+	NumBytes  int64
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// each connection is a separate in-memory database
+	db.SetMaxOpenConns(1)
+	old := &gorp.DbMap{Db: db, Dialect: gorp.SqliteDialect{}}
+	old.AddTableWithName(baselineUser{}, "User").SetKeys(true, "ID").
+		AddIndex("AccessTokenIndex", "", []string{"AccessToken"}).SetUnique(true)
+	old.AddTableWithName(baselineProject{}, "Project").SetKeys(false, "UserID", "ProjectID")
+	old.AddTableWithName(baselineTable{}, "Table").
+		SetKeys(false, "UserID", "ProjectID", "DatasetID", "TableID")
+	err = old.CreateTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = old.CreateIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = old.Insert(&baselineUser{AccessToken: "a"}, &baselineUser{AccessToken: "b"},
+		&baselineProject{UserID: 1, ProjectID: "p", FriendlyName: "friendly"},
+		&baselineTable{UserID: 1, ProjectID: "p", DatasetID: "d", TableID: "t", NumBytes: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// migrating again does nothing
+	var dbmap *gorp.DbMap
+	for i := 0; i < 2; i++ {
+		dbmap = &gorp.DbMap{Db: db, Dialect: gorp.SqliteDialect{}}
+		err = RegisterAndCreateTablesIfNeeded(dbmap)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+	}
+	project, err := GetProjectByID(dbmap, 1, "p")
+	if !(err == nil && project.FriendlyName == "friendly" && project.SnapshotID == 0 &&
+		project.QueryJobsLoadedMs == 0) {
+		t.Error(project, err)
+	}
+	// users identified by access token are retired
+	user, err := GetUserByID(dbmap, 2)
+	if !(err == nil && user.Subject == "legacy:2" && user.AccessToken == "") {
+		t.Error(user, err)
+	}
+	// retired users have no email until an operator sets one
+	retired, err := GetRetiredUserByEmail(dbmap, "")
+	if !(retired == nil && err == nil) {
+		t.Error(retired, err)
+	}
+	user.Email = "user@example.com"
+	_, err = dbmap.Update(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err = GetRetiredUserByEmail(dbmap, "user@example.com")
+	if !(err == nil && retired != nil && retired.ID == 2) {
+		t.Error(retired, err)
+	}
+	// access tokens are no longer unique; subjects are
+	err = dbmap.Insert(&User{Subject: "subject"}, &User{Subject: "other"})
+	if err != nil {
+		t.Error(err)
+	}
+	err = dbmap.Insert(&User{Subject: "subject"})
+	if err == nil {
+		t.Error("subjects must be unique")
+	}
+
+	// tables from before snapshots are deleted
+	count, err := dbmap.SelectInt(`SELECT COUNT(*) FROM "Table"`)
+	if !(err == nil && count == 0) {
+		t.Error(count, err)
+	}
+	err = dbmap.Insert(&Table{UserID: 1, ProjectID: "p", SnapshotID: 1, DatasetID: "d",
+		TableID: "t", Type: "TABLE", ViewQuery: "SELECT 1"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// columns without a migration fail at startup
+	_, err = db.Exec(`ALTER TABLE "Table" DROP COLUMN "Description"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterAndCreateTablesIfNeeded(&gorp.DbMap{Db: db, Dialect: gorp.SqliteDialect{}})
+	if !(err != nil && strings.Contains(err.Error(), "missing column Description")) {
+		t.Error(err)
+	}
+}
+
+func TestAddColumnStatements(t *testing.T) {
+	dbmap := &gorp.DbMap{Dialect: gorp.MySQLDialect{Engine: "InnoDB", Encoding: "UTF8"}}
+	registerTables(dbmap)
+
+	tests := []struct {
+		table    interface{}
+		field    string
+		expected []string
+	}{
+		{Project{}, "QueryJobsLoadedMs", []string{
+			"ALTER TABLE `Project` ADD COLUMN `QueryJobsLoadedMs` bigint NOT NULL DEFAULT 0"}},
+		{User{}, "Email", []string{
+			"ALTER TABLE `User` ADD COLUMN `Email` varchar(255) NOT NULL DEFAULT ''"}},
+		// MySQL does not allow defaults on text columns
+		{LoginSession{}, "TokenJSON", []string{
+			"ALTER TABLE `LoginSession` ADD COLUMN `TokenJSON` text",
+			"UPDATE `LoginSession` SET `TokenJSON`=''"}},
+		{Table{}, "ViewQuery", []string{
+			"ALTER TABLE `Table` ADD COLUMN `ViewQuery` text",
+			"UPDATE `Table` SET `ViewQuery`=''"}},
+		{Table{}, "SourceURIs", []string{
+			"ALTER TABLE `Table` ADD COLUMN `SourceURIs` text",
+			"UPDATE `Table` SET `SourceURIs`=''"}},
+	}
+	for i, test := range tests {
+		statements, err := addColumnStatements(dbmap, test.table, test.field)
+		if !(err == nil && reflect.DeepEqual(statements, test.expected)) {
+			t.Errorf("%d: %#v %v", i, statements, err)
+		}
+	}
+}
+
+func TestJobLease(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	// no jobs
+	job, err := ClaimJob(dbmap, "worker1", 1000, 100)
+	if !(job == nil && err == nil) {
+		t.Error(job, err)
+	}
+
+	err = dbmap.Insert(&Job{UserID: 42, ProjectID: "project", State: JobPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = ClaimJob(dbmap, "worker1", 1000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(job.ProjectID == "project" && job.State == JobRunning && job.Attempts == 1 &&
+		job.LeaseOwner == "worker1" && job.LeaseExpiryMs == 1100) {
+		t.Error(job)
+	}
+
+	// lease has not expired: cannot be claimed
+	job2, err := ClaimJob(dbmap, "worker2", 1099, 100)
+	if !(job2 == nil && err == nil) {
+		t.Error(job2, err)
+	}
+
+	// renewing extends the lease
+	err = RenewJobLease(dbmap, dbmap, job, 1050, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job2, err = ClaimJob(dbmap, "worker2", 1149, 100)
+	if !(job2 == nil && err == nil) {
+		t.Error(job2, err)
+	}
+
+	// lease expired: claimed by worker2; worker1 can no longer update it
+	job2, err = ClaimJob(dbmap, "worker2", 1151, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(job2.ID == job.ID && job2.Attempts == 2 && job2.LeaseOwner == "worker2") {
+		t.Error(job2)
+	}
+	err = RenewJobLease(dbmap, dbmap, job, 1200, 100)
+	if err != ErrLeaseLost {
+		t.Error(err)
+	}
+	err = EndJobLease(dbmap, dbmap, job, JobDone)
+	if err != ErrLeaseLost {
+		t.Error(err)
+	}
+
+	// releasing as pending makes it immediately available
+	err = EndJobLease(dbmap, dbmap, job2, JobPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = ClaimJob(dbmap, "worker1", 1152, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(job.Attempts == 3 && job.LeaseOwner == "worker1") {
+		t.Error(job)
+	}
+
+	// checkpoints are saved
+	job.CheckpointDatasetID = "dataset"
+	job.CheckpointPageToken = "token"
+	job.CheckpointTables = 5
+	err = SaveJobCheckpoint(dbmap, dbmap, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface, err := dbmap.Get((*Job)(nil), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(iface.(*Job), job) {
+		t.Error(iface, job)
+	}
+
+	// done jobs are never claimed
+	err = EndJobLease(dbmap, dbmap, job, JobDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = ClaimJob(dbmap, "worker1", 100000, 100)
+	if !(job == nil && err == nil) {
+		t.Error(job, err)
+	}
+}
+
+func TestQueryJobs(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	// no jobs
+	total, err := QueryTotalSpend(dbmap, 42, "project", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *total != (QuerySpend{}) {
+		t.Error(total)
+	}
+
+	err = dbmap.Insert(
+		&QueryJob{UserID: 42, ProjectID: "project", JobID: "a", UserEmail: "a@example.com",
+			Query: "SELECT 1", QueryFingerprint: "one", CreationTimeMs: 1000, TotalBytesBilled: 10,
+			TotalSlotMs: 1, DollarsBilled: 1},
+		&QueryJob{UserID: 42, ProjectID: "project", JobID: "b", UserEmail: "b@example.com",
+			Query: "SELECT 2", QueryFingerprint: "two", CreationTimeMs: 2000, TotalBytesBilled: 30,
+			TotalSlotMs: 3, DollarsBilled: 3},
+		&QueryJob{UserID: 42, ProjectID: "project", JobID: "c", UserEmail: "a@example.com",
+			Query: "SELECT 1", QueryFingerprint: "one", CreationTimeMs: 3000, TotalBytesBilled: 5,
+			TotalSlotMs: 5, DollarsBilled: 0.5},
+		&QueryJobTable{UserID: 42, ProjectID: "project", JobID: "a", ReferencedTable: "p:d.t",
+			CreationTimeMs: 1000},
+		&QueryJobTable{UserID: 42, ProjectID: "project", JobID: "b", ReferencedTable: "p:d.t",
+			CreationTimeMs: 2000},
+		&QueryJobTable{UserID: 42, ProjectID: "project", JobID: "b", ReferencedTable: "p:d.u",
+			CreationTimeMs: 2000},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total, err = QueryTotalSpend(dbmap, 42, "project", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *total != (QuerySpend{"", 3, 45, 9, 4.5, ""}) {
+		t.Error(total)
+	}
+	users, err := QuerySpendByUser(dbmap, 42, "project", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(users) == 2 && *users[0] == QuerySpend{"b@example.com", 1, 30, 3, 3, ""} &&
+		*users[1] == QuerySpend{"a@example.com", 2, 15, 6, 1.5, ""}) {
+		t.Error(users)
+	}
+	// limited by time
+	users, err = QuerySpendByUser(dbmap, 42, "project", 2500, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(users) == 1 && users[0].BytesBilled == 5) {
+		t.Error(users)
+	}
+	queries, err := QuerySpendByFingerprint(dbmap, 42, "project", 0, OrderByDollars, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(queries) == 1 && queries[0].Name == "two" && queries[0].Example == "SELECT 2") {
+		t.Error(queries)
+	}
+	queries, err = QuerySpendByFingerprint(dbmap, 42, "project", 0, OrderByJobs, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(queries) == 2 && queries[0].Name == "one" && queries[0].Jobs == 2) {
+		t.Error(queries)
+	}
+	queries, err = QuerySpendByFingerprint(dbmap, 42, "project", 0, OrderByBytesBilled, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(queries) == 2 && queries[0].Name == "two" && queries[1].BytesBilled == 15) {
+		t.Error(queries)
+	}
+	tables, err := QuerySpendByTable(dbmap, 42, "project", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(tables) == 2 && *tables[0] == QuerySpend{"p:d.t", 2, 40, 4, 4, ""} &&
+		tables[1].Name == "p:d.u") {
+		t.Error(tables)
+	}
+	reads, err := ListTableReads(dbmap, 42, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(reads) == 2 && *reads[0] == TableRead{"p:d.t", 2000} &&
+		*reads[1] == TableRead{"p:d.u", 2000}) {
+		t.Error(reads)
+	}
+	oldest, err := OldestQueryJobTimeMs(dbmap, 42, "project")
+	if err != nil || oldest != 1000 {
+		t.Error(oldest, err)
+	}
+	oldest, err = OldestQueryJobTimeMs(dbmap, 42, "other")
+	if err != nil || oldest != 0 {
+		t.Error(oldest, err)
+	}
+
+	err = DeleteQueryJobsSince(dbmap, 42, "project", 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, err = QueryTotalSpend(dbmap, 42, "project", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Jobs != 1 {
+		t.Error(total)
+	}
+	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM QueryJobTable")
+	if err != nil || count != 1 {
+		t.Error(count, err)
+	}
+
+	err = dbmap.Insert(&Project{UserID: 42, ProjectID: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetQueryJobsLoaded(dbmap, 42, "project", 5000, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := GetProjectByID(dbmap, 42, "project")
+	if err != nil || !(project.QueryJobsLoadedMs == 5000 && project.QueryJobsTruncated) {
+		t.Error(project, err)
+	}
+}
+
+func TestListTablesModifiedBefore(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	err = dbmap.Insert(
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "old",
+			NumBytes: 10, LastModifiedTimeMs: 1000},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "big",
+			NumBytes: 20, LastModifiedTimeMs: 1000},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "new",
+			NumBytes: 10, LastModifiedTimeMs: 3000},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "view",
+			LastModifiedTimeMs: 1000},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 2, DatasetID: "d", TableID: "other",
+			NumBytes: 10, LastModifiedTimeMs: 1000},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := ListTablesModifiedBefore(dbmap, 42, "p", 1, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(tables) == 2 && tables[0].TableID == "big" && tables[1].TableID == "old") {
+		t.Error(tables)
+	}
+}
+
+func TestListTablesPage(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	err = dbmap.Insert(
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "a", NumBytes: 10},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "b", NumBytes: 30},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 1, DatasetID: "d", TableID: "c", NumBytes: 10},
+		&Table{UserID: 42, ProjectID: "p", SnapshotID: 2, DatasetID: "d", TableID: "d", NumBytes: 20},
+		&Project{UserID: 42, ProjectID: "z"},
+		&Project{UserID: 42, ProjectID: "p"},
+		&Project{UserID: 43, ProjectID: "p"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := ListTablesPage(dbmap, 42, "p", 1, OrderTablesByBytes, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(tables) == 2 && tables[0].TableID == "b" && tables[1].TableID == "a") {
+		t.Error(tables)
+	}
+	tables, err = ListTablesPage(dbmap, 42, "p", 1, OrderTablesByBytes, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(tables) == 1 && tables[0].TableID == "c") {
+		t.Error(tables)
+	}
+	var ids []string
+	err = EachTable(dbmap, 42, "p", 1, func(table *Table) error {
+		ids = append(ids, table.TableID)
+		return nil
+	})
+	if !(err == nil && reflect.DeepEqual(ids, []string{"a", "b", "c"})) {
+		t.Error(err, ids)
+	}
+	stop := errors.New("stop")
+	err = EachTable(dbmap, 42, "p", 1, func(table *Table) error {
+		return stop
+	})
+	if err != stop {
+		t.Error(err)
+	}
+
+	count, err := CountTables(dbmap, 42, "p", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Error(count)
+	}
+
+	projects, err := ListProjects(dbmap, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(projects) == 2 && projects[0].ProjectID == "p" && projects[1].ProjectID == "z") {
+		t.Error(projects)
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	err = SaveRecommendations(dbmap, 42, "p", 1, []*Recommendation{
+		{UserID: 42, ProjectID: "p", SnapshotID: 1, Rule: "r", Resource: "a", DollarsPerMonth: 1},
+		{UserID: 42, ProjectID: "p", SnapshotID: 1, Rule: "r", Resource: "b", DollarsPerMonth: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replaces the previous recommendations
+	err = SaveRecommendations(dbmap, 42, "p", 1, []*Recommendation{
+		{UserID: 42, ProjectID: "p", SnapshotID: 1, Rule: "r", Resource: "b", DollarsPerMonth: 2},
+		{UserID: 42, ProjectID: "p", SnapshotID: 1, Rule: "r", Resource: "c", DollarsPerMonth: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SaveRecommendations(dbmap, 42, "p", 2, []*Recommendation{
+		{UserID: 42, ProjectID: "p", SnapshotID: 2, Rule: "r", Resource: "c", DollarsPerMonth: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recommendations, err := ListRecommendations(dbmap, 42, "p", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(recommendations) == 2 && recommendations[0].Resource == "c" &&
+		recommendations[1].Resource == "b") {
+		t.Error(recommendations)
+	}
+
+	// dismissed in all snapshots, but only for this user
+	for i := 0; i < 2; i++ {
+		err = DismissRecommendation(dbmap, 42, "p", "r", "c", 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, snapshotID := range []int64{1, 2} {
+		recommendations, err = ListRecommendations(dbmap, 42, "p", snapshotID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, recommendation := range recommendations {
+			if recommendation.Resource == "c" {
+				t.Error(snapshotID, recommendation)
+			}
+		}
+	}
+	err = DismissRecommendation(dbmap, 43, "p", "r", "b", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recommendations, err = ListRecommendations(dbmap, 42, "p", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(recommendations) == 1 && recommendations[0].Resource == "b") {
+		t.Error(recommendations)
+	}
+
+	err = DeleteSnapshotTables(dbmap, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recommendations, err = ListRecommendations(dbmap, 42, "p", 1)
+	if err != nil || len(recommendations) != 0 {
+		t.Error(recommendations, err)
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	store := NewTokenStore(dbmap)
+	value, err := store.GetToken("k")
+	if !(value == nil && err == nil) {
+		t.Error(value, err)
+	}
+	for _, input := range []string{"first", "second"} {
+		err = store.PutToken("k", []byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err = store.GetToken("k")
+		if !(string(value) == input && err == nil) {
+			t.Error(string(value), err)
+		}
+	}
+	err = store.DeleteToken("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err = store.GetToken("k")
+	if !(value == nil && err == nil) {
+		t.Error(value, err)
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	dbmap, err := OpenAndCreateTablesIfNeeded("sqlite3", ":memory:", gorp.SqliteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbmap.Db.Close()
+
+	store := NewSessionStore(dbmap)
+	session, err := store.GetSession("a")
+	if !(session == nil && err == nil) {
+		t.Error(session, err)
+	}
+
+	now := time.Unix(1500000000, 0)
+	input := &googlelogin.Session{ID: "a", Subject: "subject", Email: "user@example.com",
+		Token:     &oauth2.Token{AccessToken: "access", TokenType: "Bearer", Expiry: now},
+		CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	for _, accessToken := range []string{"first", "access"} {
+		input.Token.AccessToken = accessToken
+		err = store.PutSession(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	session, err = store.GetSession("a")
+	if !(err == nil && session.Subject == "subject" && session.Email == "user@example.com" &&
+		session.Token.AccessToken == "access" && session.Token.Expiry.Equal(now) &&
+		session.CreatedAt.Equal(now) && session.ExpiresAt.Equal(now.Add(time.Hour))) {
+		t.Error(session, err)
+	}
+
+	for _, other := range []*googlelogin.Session{
+		{ID: "b", Subject: "subject", CreatedAt: now.Add(-time.Minute), ExpiresAt: now},
+		{ID: "c", Subject: "other", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		err = store.PutSession(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sessions, err := store.ListSessions("subject")
+	if !(err == nil && len(sessions) == 2 && sessions[0].ID == "b" && sessions[1].ID == "a") {
+		t.Error(sessions, err)
+	}
+
+	deleted, err := store.DeleteExpiredSessions(now.Add(time.Minute))
+	if !(err == nil && deleted == 1) {
+		t.Error(deleted, err)
+	}
+	err = store.DeleteSession("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err = store.ListSessions("subject")
+	if !(err == nil && len(sessions) == 0) {
+		t.Error(sessions, err)
+	}
+	session, err = store.GetSession("c")
+	if !(err == nil && session != nil && session.Token == nil) {
+		t.Error(session, err)
+	}
+}
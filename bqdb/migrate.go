@@ -0,0 +1,299 @@
+package bqdb
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	gorp "github.com/go-gorp/gorp"
+)
+
+// Every table registered by RegisterAndCreateTablesIfNeeded. Their columns are checked at startup.
+var allTables = []interface{}{User{}, Project{}, Snapshot{}, Dataset{}, Label{}, Table{},
+	TablePartition{}, QueryJob{}, QueryJobTable{}, Recommendation{}, DismissedRecommendation{},
+	Job{}, StoredToken{}, LoginSession{}}
+
+// Columns added to tables after they were first created. CreateTablesIfNotExists never changes
+// existing tables, so migrateTables adds these columns, with the zero value for existing rows.
+var addedColumns = []struct {
+	table interface{}
+	field string
+}{
+	{Job{}, "CheckpointDatasetID"},
+	{Job{}, "CheckpointPageToken"},
+	{Job{}, "CheckpointTables"},
+	{Job{}, "CheckpointDone"},
+	{Job{}, "SnapshotID"},
+	{Project{}, "SnapshotID"},
+	{Project{}, "QueryJobsLoadedMs"},
+	{Project{}, "QueryJobsTruncated"},
+	{Table{}, "Location"},
+	{Table{}, "PartitionType"},
+	{Table{}, "PartitionField"},
+	{Table{}, "PartitionExpirationMs"},
+	{Table{}, "ClusteringFields"},
+	{Table{}, "Type"},
+	{Table{}, "ViewQuery"},
+	{Table{}, "SourceFormat"},
+	{Table{}, "SourceURIs"},
+	{Table{}, "BaseTable"},
+	{Table{}, "NumActivePhysicalBytes"},
+	{Table{}, "NumLongTermPhysicalBytes"},
+	{Table{}, "StorageBillingModel"},
+	{Dataset{}, "StorageBillingModel"},
+	{User{}, "Subject"},
+	{User{}, "Email"},
+}
+
+// Prefix of the Subject given to users from before accounts were identified by their Google
+// subject. Google's subjects are numbers, so these never match a login.
+const legacySubjectPrefix = "legacy:"
+
+// An index on a table. gorp's CreateIndex stops at the first index that already exists, so
+// indexes added after a database was created would never be created. Each index is created
+// separately instead.
+type tableIndex struct {
+	table   interface{}
+	name    string
+	unique  bool
+	columns []string
+}
+
+var allIndexes = []tableIndex{
+	{User{}, "SubjectIndex", true, []string{"Subject"}},
+	{Snapshot{}, "SnapshotProjectIndex", false, []string{"UserID", "ProjectID", "TimeMs"}},
+	{QueryJob{}, "QueryJobTimeIndex", false, []string{"UserID", "ProjectID", "CreationTimeMs"}},
+	{QueryJobTable{}, "QueryJobTableIndex", false,
+		[]string{"UserID", "ProjectID", "ReferencedTable"}},
+	{Job{}, "JobStateIndex", false, []string{"State", "LeaseExpiryMs"}},
+	{LoginSession{}, "LoginSessionSubjectIndex", false, []string{"Subject", "CreatedMs"}},
+	{LoginSession{}, "LoginSessionExpiresIndex", false, []string{"ExpiresMs"}},
+}
+
+// Returns the names of the columns of table in the database.
+func tableColumns(dbmap *gorp.DbMap, table interface{}) (map[string]bool, error) {
+	quotedTable, err := QuotedTableForQuery(dbmap, table)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbmap.Db.Query("SELECT * FROM " + quotedTable + " LIMIT 0")
+	if err != nil {
+		return nil, err
+	}
+	names, err := rows.Columns()
+	err2 := rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err2 != nil {
+		return nil, err2
+	}
+	columns := map[string]bool{}
+	for _, name := range names {
+		columns[name] = true
+	}
+	return columns, nil
+}
+
+// Returns the statements that add the column for field to table. Existing rows get the zero
+// value. MySQL does not allow defaults on text and blob columns, so those columns are added
+// nullable and existing rows are then set to the empty string.
+func addColumnStatements(dbmap *gorp.DbMap, table interface{}, field string) ([]string, error) {
+	tableMap, err := dbmap.TableFor(reflect.TypeOf(table), false)
+	if err != nil {
+		return nil, err
+	}
+	structField, ok := reflect.TypeOf(table).FieldByName(field)
+	if !ok {
+		return nil, fmt.Errorf("bqdb: %s has no field %s", tableMap.TableName, field)
+	}
+	colMap := tableMap.ColMap(field)
+	quotedTable := dbmap.Dialect.QuotedTableForQuery(tableMap.SchemaName, tableMap.TableName)
+	quotedColumn := dbmap.Dialect.QuoteField(colMap.ColumnName)
+	sqlType := dbmap.Dialect.ToSqlType(structField.Type, colMap.MaxSize, false)
+	addColumn := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quotedTable, quotedColumn, sqlType)
+
+	lowerType := strings.ToLower(sqlType)
+	if strings.Contains(lowerType, "text") || strings.Contains(lowerType, "blob") {
+		return []string{addColumn,
+			fmt.Sprintf("UPDATE %s SET %s=''", quotedTable, quotedColumn)}, nil
+	}
+	zero := "0"
+	if structField.Type.Kind() == reflect.String {
+		zero = "''"
+	}
+	return []string{addColumn + " NOT NULL DEFAULT " + zero}, nil
+}
+
+// Adds the column for field to table. Existing rows get the zero value.
+func addColumn(dbmap *gorp.DbMap, table interface{}, field string) error {
+	statements, err := addColumnStatements(dbmap, table, field)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		_, err = dbmap.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Upgrades tables created by earlier versions, then checks that every table has all its columns.
+func migrateTables(dbmap *gorp.DbMap) error {
+	// Table rows from before snapshots are not part of any snapshot, and SnapshotID is part of
+	// the primary key. The rows are only a copy of BigQuery's metadata, so the table is
+	// recreated, and projects are loaded again when they are refreshed.
+	columns, err := tableColumns(dbmap, Table{})
+	if err != nil {
+		return err
+	}
+	if !columns["SnapshotID"] {
+		log.Printf("bqdb: migrating: recreating Table without snapshots")
+		err = dbmap.DropTable(Table{})
+		if err != nil {
+			return err
+		}
+		err = dbmap.CreateTablesIfNotExists()
+		if err != nil {
+			return err
+		}
+	}
+
+	columns, err = tableColumns(dbmap, User{})
+	if err != nil {
+		return err
+	}
+	retireUsers := !columns["Subject"]
+
+	for _, added := range addedColumns {
+		columns, err := tableColumns(dbmap, added.table)
+		if err != nil {
+			return err
+		}
+		if columns[added.field] {
+			continue
+		}
+		log.Printf("bqdb: migrating: adding column %s.%s",
+			reflect.TypeOf(added.table).Name(), added.field)
+		err = addColumn(dbmap, added.table, added.field)
+		if err != nil {
+			return err
+		}
+	}
+
+	if retireUsers {
+		err = retireAccessTokenUsers(dbmap)
+		if err != nil {
+			return err
+		}
+	}
+
+	// fail fast instead of failing queries: a column was added without a migration
+	for _, table := range allTables {
+		tableMap, err := dbmap.TableFor(reflect.TypeOf(table), false)
+		if err != nil {
+			return err
+		}
+		columns, err := tableColumns(dbmap, table)
+		if err != nil {
+			return err
+		}
+		for _, colMap := range tableMap.Columns {
+			if !colMap.Transient && !columns[colMap.ColumnName] {
+				return fmt.Errorf("bqdb: table %s is missing column %s: add it to addedColumns",
+					tableMap.TableName, colMap.ColumnName)
+			}
+		}
+	}
+	return nil
+}
+
+// Users were identified by the access token of their login before they were identified by their
+// Google account. An access token cannot be matched to an account after it expires, so these
+// users are retired: they get a unique legacySubjectPrefix subject that never matches a login,
+// and their access token is cleared so background jobs stop using it. Their projects are kept:
+// setting a retired user's Email lets the account with that email claim it on its next login
+// (see GetRetiredUserByEmail). The unique index on AccessToken is dropped, since tokens are no
+// longer unique.
+func retireAccessTokenUsers(dbmap *gorp.DbMap) error {
+	quotedTable, err := QuotedTableForQuery(dbmap, User{})
+	if err != nil {
+		return err
+	}
+	dropIndex := "DROP INDEX AccessTokenIndex"
+	if _, isMySQL := dbmap.Dialect.(gorp.MySQLDialect); isMySQL {
+		dropIndex += " ON " + quotedTable
+	}
+	_, err = dbmap.Exec(dropIndex)
+	if err != nil {
+		return err
+	}
+
+	var users []*User
+	_, err = dbmap.Select(&users, "SELECT * FROM "+quotedTable+" WHERE Subject=''")
+	if err != nil {
+		return err
+	}
+	quotedProject, err := QuotedTableForQuery(dbmap, Project{})
+	if err != nil {
+		return err
+	}
+	var projects []*Project
+	_, err = dbmap.Select(&projects, "SELECT * FROM "+quotedProject)
+	if err != nil {
+		return err
+	}
+	projectIDs := map[int64][]string{}
+	for _, project := range projects {
+		projectIDs[project.UserID] = append(projectIDs[project.UserID], project.ProjectID)
+	}
+
+	retiredProjects := 0
+	for _, user := range users {
+		user.Subject = fmt.Sprintf("%s%d", legacySubjectPrefix, user.ID)
+		user.AccessToken = ""
+		_, err = dbmap.Update(user)
+		if err != nil {
+			return err
+		}
+		// lets operators find who to give each retired user to
+		if len(projectIDs[user.ID]) > 0 {
+			log.Printf("bqdb: migrating: retired user %d with projects: %s",
+				user.ID, strings.Join(projectIDs[user.ID], ", "))
+		}
+		retiredProjects += len(projectIDs[user.ID])
+	}
+	log.Printf("bqdb: migrating: retired %d users identified by access token with %d projects; "+
+		"set a retired user's Email so that account can claim it", len(users), retiredProjects)
+	return nil
+}
+
+// Creates each index in allIndexes that does not exist.
+func createIndexes(dbmap *gorp.DbMap) error {
+	for _, index := range allIndexes {
+		quotedTable, err := QuotedTableForQuery(dbmap, index.table)
+		if err != nil {
+			return err
+		}
+		quotedColumns := make([]string, len(index.columns))
+		for i, column := range index.columns {
+			quotedColumns[i] = dbmap.Dialect.QuoteField(column)
+		}
+		create := "CREATE INDEX "
+		if index.unique {
+			create = "CREATE UNIQUE INDEX "
+		}
+		_, err = dbmap.Exec(create + index.name + " ON " + quotedTable +
+			" (" + strings.Join(quotedColumns, ", ") + ")")
+		// sqlite: index {{.Name}} already exists
+		// mysql: Duplicate key name '{{.Name}}'
+		if err != nil && !(strings.Contains(err.Error(), "already exists") ||
+			strings.Contains(err.Error(), "Duplicate key name")) {
+			return err
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sync"
 	"time"
 
 	"google.golang.org/api/gensupport"
@@ -29,14 +30,34 @@ const maxTables = 20000
 // https://cloud.google.com/bigquery/docs/data#paging-through-list-results
 const collectionMaxResults = 1000
 
-// https://cloud.google.com/bigquery/docs/reference/rest/v2/tables#resource
-const TypeTable = "TABLE"
+// Scope is the OAuth scope needed to scrape a project. The bigquery.readonly scope can't run the
+// queries that read partitions, but the read-only Cloud Platform scope can. It grants read access
+// to the account's other Google Cloud resources, but can't modify anything.
+// https://cloud.google.com/bigquery/docs/reference/rest/v2/jobs/query#authorization-scopes
+const Scope = bigquery.CloudPlatformReadOnlyScope
+
+// Table types: https://cloud.google.com/bigquery/docs/reference/rest/v2/tables#resource
+// Clones have TypeTable and a cloneDefinition.
+const (
+	TypeTable            = "TABLE"
+	TypeView             = "VIEW"
+	TypeMaterializedView = "MATERIALIZED_VIEW"
+	TypeExternal         = "EXTERNAL"
+	TypeSnapshot         = "SNAPSHOT"
+)
 
 // Makes it easier to test this code
 type api interface {
 	listDatasets(projectId string, pageToken string) (*bigquery.DatasetList, error)
+	getDataset(projectId string, datasetId string) (*bigquery.Dataset, error)
 	listTables(projectId string, datasetId string, pageToken string) (*bigquery.TableList, error)
-	getTable(projectId string, datasetId string, tableId string) (*bigquery.Table, error)
+	// Stops retrying when ctx is cancelled.
+	getTable(ctx context.Context, projectId string, datasetId string, tableId string) (
+		*bigquery.Table, error)
+	listPartitions(projectId string, datasetId string, location string, tableIds []string) (
+		[]*Partition, error)
+	listJobs(projectId string, minCreationTimeMs uint64, maxCreationTimeMs uint64,
+		pageToken string) (*bigquery.JobList, error)
 }
 
 type bigQueryAPI struct {
@@ -60,6 +81,20 @@ func (a *bigQueryAPI) listDatasets(projectId string, pageToken string) (
 	return result, err
 }
 
+func (a *bigQueryAPI) getDataset(projectId string, datasetId string) (*bigquery.Dataset, error) {
+	request := a.bq.Datasets.Get(projectId, datasetId).
+		Fields("creationTime,datasetReference,defaultTableExpirationMs,description,friendlyName,labels,lastModifiedTime,location")
+
+	var result *bigquery.Dataset
+	makeRequest := func() error {
+		var err error
+		result, err = request.Do()
+		return err
+	}
+	err := retry(context.TODO(), makeRequest)
+	return result, err
+}
+
 func (a *bigQueryAPI) listTables(projectId string, datasetId string, pageToken string) (
 	*bigquery.TableList, error) {
 	// TODO: filter attributes?
@@ -77,11 +112,11 @@ func (a *bigQueryAPI) listTables(projectId string, datasetId string, pageToken s
 	return result, err
 }
 
-func (a *bigQueryAPI) getTable(projectId string, datasetId string, tableId string) (
-	*bigquery.Table, error) {
-	request := a.bq.Tables.Get(projectId, datasetId, tableId).
+func (a *bigQueryAPI) getTable(ctx context.Context, projectId string, datasetId string,
+	tableId string) (*bigquery.Table, error) {
+	request := a.bq.Tables.Get(projectId, datasetId, tableId).Context(ctx).
 		// created with the API fields editor
-		Fields("creationTime,description,expirationTime,friendlyName,id,kind,lastModifiedTime,numBytes,numLongTermBytes,numRows,streamingBuffer,tableReference,type")
+		Fields("cloneDefinition,clustering,creationTime,description,expirationTime,externalDataConfiguration(sourceFormat,sourceUris),friendlyName,id,kind,labels,lastModifiedTime,location,materializedView(query),numBytes,numLongTermBytes,numRows,snapshotDefinition,streamingBuffer,tableReference,timePartitioning,type,view(query)")
 
 	var result *bigquery.Table
 	makeRequest := func() error {
@@ -89,7 +124,7 @@ func (a *bigQueryAPI) getTable(projectId string, datasetId string, tableId strin
 		result, err = request.Do()
 		return err
 	}
-	err := retry(context.TODO(), makeRequest)
+	err := retry(ctx, makeRequest)
 	return result, err
 }
 
@@ -247,31 +282,88 @@ func estimateListTablesProgress(tablesListed int, totalTables int) (int, string)
 	return percent, message
 }
 
-// Fetches all metadata from all bigquery tables from projectId. TODO: Parallelize
+// Calls getTable for every table using numWorkers goroutines. The results are in the same order
+// as tables. The first error stops all workers and is returned.
+func getTables(bqAPI api, tables []*bigquery.TableListTables, limiter *rate.Limiter,
+	progress ProgressReporter, numWorkers int) ([]*bigquery.Table, error) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tableData := make([]*bigquery.Table, len(tables))
+	// protects firstErr, completed and calls to progress
+	var mu sync.Mutex
+	var firstErr error
+	completed := 0
+
+	percent, message := estimateListTablesProgress(completed, len(tables))
+	progress.Progress(percent, message)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				var table *bigquery.Table
+				err := limiter.Wait(ctx)
+				if err == nil {
+					ref := tables[i].TableReference
+					table, err = bqAPI.getTable(ctx, ref.ProjectId, ref.DatasetId, ref.TableId)
+				}
+
+				mu.Lock()
+				if err != nil {
+					// errors after the first are probably caused by cancel: ignore them
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					tableData[i] = table
+					completed++
+					// report progress every 100 tables
+					if completed%progressTableCount == 0 {
+						percent, message := estimateListTablesProgress(completed, len(tables))
+						progress.Progress(percent, message)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+sendLoop:
+	for i := range tables {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break sendLoop
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return tableData, nil
+}
+
+// Fetches all metadata from all bigquery tables from projectId.
 func getAllTables(bqAPI api, projectId string, limiter *rate.Limiter, progress ProgressReporter) (
 	[]*bigquery.Table, error) {
 
-	const listTablesPercent = 10
-
 	progress.Progress(0, "Listing tables...")
 	tables, err := listAllTables(bqAPI, projectId, limiter)
 	if err != nil {
 		return nil, err
 	}
 
-	tableData := make([]*bigquery.Table, len(tables))
-	for i, table := range tables {
-		// report progress every 100 tables
-		if i%progressTableCount == 0 {
-			percent, message := estimateListTablesProgress(i, len(tables))
-			progress.Progress(percent, message)
-		}
-
-		tableData[i], err = bqAPI.getTable(table.TableReference.ProjectId, table.TableReference.DatasetId,
-			table.TableReference.TableId)
-		if err != nil {
-			return nil, err
-		}
+	tableData, err := getTables(bqAPI, tables, limiter, progress, maxConcurrentAPIRequests)
+	if err != nil {
+		return nil, err
 	}
 	// TODO: factor this into the progress indicator better
 	progress.Progress(99, "Saving results...")
@@ -299,7 +391,7 @@ type NilProgressReporter struct{}
 
 func (n *NilProgressReporter) Progress(percent int, message string) {}
 
-// Fetches all metadata from all bigquery tables from projectId. TODO: Parallelize
+// Fetches all metadata from all bigquery tables from projectId.
 func GetAllTables(bq *bigquery.Service, projectId string, progress ProgressReporter) (
 	[]*bigquery.Table, error) {
 
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,6 +24,29 @@ const itemsPerPage = 2
 type fakeBigQueryAPI struct {
 	err           error
 	datasetTables map[string][]string
+
+	// getTable returns tableErr for errTableID
+	errTableID string
+	// getTable retries retryTableID until ctx is cancelled; sets retryNotCancelled if it times out
+	retryTableID      string
+	retryNotCancelled bool
+	tableErr          error
+
+	// partitions of partitioned tables, by table ID; listPartitions returns partitionsErr
+	partitions    map[string][]*Partition
+	partitionsErr error
+
+	// listJobs returns these jobs, itemsPerPage at a time
+	jobs []*bigquery.JobListJobs
+	// the creation time range passed to the last call of listJobs
+	minCreationTimeMs uint64
+	maxCreationTimeMs uint64
+
+	// tracks concurrent getTable calls
+	mu            sync.Mutex
+	active        int
+	maxActive     int
+	getTableCalls int
 }
 
 func extractPageSlice(items []string, pageToken string) ([]string, string, error) {
@@ -76,6 +100,16 @@ func (a *fakeBigQueryAPI) listDatasets(projectId string, pageToken string) (
 	return result, nil
 }
 
+func (a *fakeBigQueryAPI) getDataset(projectId string, datasetId string) (*bigquery.Dataset, error) {
+	if _, ok := a.datasetTables[datasetId]; !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: " + datasetId}
+	}
+	return &bigquery.Dataset{
+		DatasetReference: &bigquery.DatasetReference{ProjectId: projectId, DatasetId: datasetId},
+		Location:         "US",
+	}, nil
+}
+
 func (a *fakeBigQueryAPI) listTables(projectId string, datasetID string, pageToken string) (
 	*bigquery.TableList, error) {
 
@@ -97,14 +131,80 @@ func (a *fakeBigQueryAPI) listTables(projectId string, datasetID string, pageTok
 	return result, nil
 }
 
-func (a *fakeBigQueryAPI) getTable(projectId string, datasetId string, tableId string) (
-	*bigquery.Table, error) {
+func (a *fakeBigQueryAPI) getTable(ctx context.Context, projectId string, datasetId string,
+	tableId string) (*bigquery.Table, error) {
+
+	a.mu.Lock()
+	a.getTableCalls++
+	a.active++
+	if a.active > a.maxActive {
+		a.maxActive = a.active
+	}
+	a.mu.Unlock()
+	// give other workers a chance to run concurrently
+	time.Sleep(time.Millisecond)
+	a.mu.Lock()
+	a.active--
+	a.mu.Unlock()
+
+	if tableId == a.errTableID {
+		return nil, a.tableErr
+	}
+	if tableId == a.retryTableID {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			a.mu.Lock()
+			a.retryNotCancelled = true
+			a.mu.Unlock()
+			return nil, errors.New("retrying was not cancelled")
+		}
+	}
 
 	// TODO: check that the table "exists?"
-	return &bigquery.Table{
+	table := &bigquery.Table{
 		TableReference: &bigquery.TableReference{
 			ProjectId: projectId, DatasetId: datasetId, TableId: tableId},
-	}, nil
+	}
+	if _, ok := a.partitions[tableId]; ok {
+		table.TimePartitioning = &bigquery.TimePartitioning{Type: "DAY"}
+	}
+	return table, nil
+}
+
+func (a *fakeBigQueryAPI) listPartitions(projectId string, datasetId string, location string,
+	tableIds []string) ([]*Partition, error) {
+
+	if a.partitionsErr != nil {
+		return nil, a.partitionsErr
+	}
+	var partitions []*Partition
+	for _, tableId := range tableIds {
+		partitions = append(partitions, a.partitions[tableId]...)
+	}
+	return partitions, nil
+}
+
+func (a *fakeBigQueryAPI) listJobs(projectId string, minCreationTimeMs uint64,
+	maxCreationTimeMs uint64, pageToken string) (*bigquery.JobList, error) {
+
+	a.minCreationTimeMs = minCreationTimeMs
+	a.maxCreationTimeMs = maxCreationTimeMs
+	ids := make([]string, len(a.jobs))
+	for i := range a.jobs {
+		ids[i] = strconv.Itoa(i)
+	}
+	slice, nextPageToken, err := extractPageSlice(ids, pageToken)
+	if err != nil {
+		return nil, err
+	}
+	result := &bigquery.JobList{NextPageToken: nextPageToken}
+	for _, id := range slice {
+		i, _ := strconv.Atoi(id)
+		result.Jobs = append(result.Jobs, a.jobs[i])
+	}
+	return result, nil
 }
 
 func TestListAllDatasets(t *testing.T) {
@@ -206,6 +306,64 @@ func TestGetAllTables(t *testing.T) {
 	}
 }
 
+func TestGetTablesParallel(t *testing.T) {
+	fakeBQ := &fakeBigQueryAPI{}
+	tables := []*bigquery.TableListTables{}
+	for i := 0; i < 250; i++ {
+		tables = append(tables, &bigquery.TableListTables{
+			TableReference: &bigquery.TableReference{
+				ProjectId: "project", DatasetId: "ds", TableId: "table" + strconv.Itoa(i)},
+		})
+	}
+	limiter := rate.NewLimiter(rate.Inf, 0)
+
+	const numWorkers = 5
+	progress := &FakeProgressReporter{}
+	tableData, err := getTables(fakeBQ, tables, limiter, progress, numWorkers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// results must be in the same order as the input
+	if len(tableData) != len(tables) {
+		t.Fatal(len(tableData))
+	}
+	for i, table := range tableData {
+		if table.TableReference.TableId != tables[i].TableReference.TableId {
+			t.Errorf("%d: %s != %s", i, table.TableReference.TableId, tables[i].TableReference.TableId)
+		}
+	}
+	if !(1 < fakeBQ.maxActive && fakeBQ.maxActive <= numWorkers) {
+		t.Error("expected concurrent requests up to numWorkers:", fakeBQ.maxActive)
+	}
+
+	expected := []progressReport{
+		{10, "Reading table metadata: 0/250 tables"},
+		{45, "Reading table metadata: 100/250 tables"},
+		{81, "Reading table metadata: 200/250 tables"},
+	}
+	if !reflect.DeepEqual(expected, progress.progress) {
+		t.Error(progress.progress)
+	}
+
+	// an error stops the workers and is returned
+	fakeBQ = &fakeBigQueryAPI{errTableID: "table3", tableErr: errors.New("permanent error")}
+	tableData, err = getTables(fakeBQ, tables, limiter, &FakeProgressReporter{}, numWorkers)
+	if tableData != nil || err != fakeBQ.tableErr {
+		t.Error(tableData, err)
+	}
+	if fakeBQ.getTableCalls >= len(tables) {
+		t.Error("workers should stop after an error; getTable calls:", fakeBQ.getTableCalls)
+	}
+
+	// requests that are being retried stop after another worker's error
+	fakeBQ = &fakeBigQueryAPI{errTableID: "table3", tableErr: errors.New("permanent error"),
+		retryTableID: "table0"}
+	tableData, err = getTables(fakeBQ, tables, limiter, &FakeProgressReporter{}, numWorkers)
+	if tableData != nil || err != fakeBQ.tableErr || fakeBQ.retryNotCancelled {
+		t.Error(tableData, err, fakeBQ.retryNotCancelled)
+	}
+}
+
 func TestEstimateProgress(t *testing.T) {
 	tests := []struct {
 		listed int
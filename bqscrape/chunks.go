@@ -0,0 +1,163 @@
+package bqscrape
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+)
+
+// Checkpoint is the position of the next chunk to scrape. The zero Checkpoint starts at the
+// beginning of the project. Datasets are scraped in sorted order so a checkpoint stays valid if
+// datasets are created or deleted between attempts.
+type Checkpoint struct {
+	// Next dataset to scrape.
+	DatasetID string
+	// Next page of tables in DatasetID; empty for the first page.
+	PageToken string
+	// Number of tables scraped before this checkpoint.
+	Tables int
+	// All chunks have been scraped.
+	Done bool
+}
+
+// ChunkSaver persists a chunk of tables, the partitions of the partitioned tables in the chunk,
+// and the checkpoint to resume after it. The first chunk of each dataset includes the dataset;
+// otherwise dataset is nil. It should save everything atomically, so a retried scrape never
+// saves the same chunk twice.
+type ChunkSaver func(dataset *bigquery.Dataset, tables []*bigquery.Table,
+	partitions []*Partition, next Checkpoint) error
+
+func estimateChunkProgress(datasetsScraped int, totalDatasets int, tables int) (int, string) {
+	fraction := float64(datasetsScraped) / float64(totalDatasets)
+	percent := listTablesPercent + int((100-listTablesPercent-savingPercent)*fraction)
+	message := fmt.Sprintf("Reading table metadata: %d/%d datasets; %d tables",
+		datasetsScraped, totalDatasets, tables)
+	return percent, message
+}
+
+// Scrapes projectId one page of tables at a time, starting from checkpoint. Calls save after
+// each page, and after each empty dataset.
+func getTablesInChunks(bqAPI api, projectId string, limiter *rate.Limiter,
+	checkpoint Checkpoint, progress ProgressReporter, save ChunkSaver) error {
+
+	if checkpoint.Done {
+		return nil
+	}
+
+	progress.Progress(0, "Listing datasets...")
+	datasets, err := listAllDatasets(bqAPI, projectId, limiter)
+	if err != nil {
+		return err
+	}
+	datasetIDs := make([]string, len(datasets))
+	for i, dataset := range datasets {
+		datasetIDs[i] = dataset.DatasetReference.DatasetId
+	}
+	sort.Strings(datasetIDs)
+
+	// skip datasets that were completed by a previous attempt; if the checkpoint dataset was
+	// deleted, this starts at the following dataset
+	start := sort.SearchStrings(datasetIDs, checkpoint.DatasetID)
+	if start > 0 {
+		log.Printf("bqscrape: project %s resuming at dataset %d/%d %s",
+			projectId, start, len(datasetIDs), checkpoint.DatasetID)
+	}
+
+	next := checkpoint
+	for i := start; i < len(datasetIDs); i++ {
+		datasetID := datasetIDs[i]
+		pageToken := ""
+		if datasetID == checkpoint.DatasetID {
+			pageToken = checkpoint.PageToken
+		}
+
+		// a previous attempt saved the dataset with its first page of tables
+		var dataset *bigquery.Dataset
+		if pageToken == "" {
+			err = limiter.Wait(context.TODO())
+			if err != nil {
+				return err
+			}
+			dataset, err = bqAPI.getDataset(projectId, datasetID)
+			if err != nil {
+				return err
+			}
+		}
+
+		for {
+			percent, message := estimateChunkProgress(i, len(datasetIDs), next.Tables)
+			progress.Progress(percent, message)
+
+			err = limiter.Wait(context.TODO())
+			if err != nil {
+				return err
+			}
+			resp, err := bqAPI.listTables(projectId, datasetID, pageToken)
+			if err != nil {
+				return err
+			}
+			if next.Tables+len(resp.Tables) > maxTables {
+				return fmt.Errorf("bqscrape: projectId:%s exceeded max tables:%d", projectId, maxTables)
+			}
+
+			tables, err := getTables(bqAPI, resp.Tables, limiter, &NilProgressReporter{},
+				maxConcurrentAPIRequests)
+			if err != nil {
+				return err
+			}
+			partitions, err := getPartitions(bqAPI, projectId, datasetID, tables, limiter)
+			if err != nil {
+				return err
+			}
+
+			next.Tables += len(tables)
+			next.DatasetID = datasetID
+			next.PageToken = resp.NextPageToken
+			if next.PageToken == "" {
+				// resume at the next dataset
+				if i+1 < len(datasetIDs) {
+					next.DatasetID = datasetIDs[i+1]
+				} else {
+					next.Done = true
+				}
+			}
+			err = save(dataset, tables, partitions, next)
+			if err != nil {
+				return err
+			}
+			dataset = nil
+
+			pageToken = resp.NextPageToken
+			if pageToken == "" {
+				break
+			}
+		}
+	}
+
+	if !next.Done {
+		// no datasets after the checkpoint
+		next.Done = true
+		err = save(nil, nil, nil, next)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fetches all metadata from all bigquery tables from projectId in chunks, starting from
+// checkpoint. Calls save with each chunk of tables. A failed scrape can be resumed by calling
+// this again with the last checkpoint passed to save.
+func GetTablesInChunks(bq *bigquery.Service, projectId string, checkpoint Checkpoint,
+	progress ProgressReporter, save ChunkSaver) error {
+
+	bqAPI, limiter := productionConfig(bq)
+	if progress == nil {
+		progress = &NilProgressReporter{}
+	}
+	return getTablesInChunks(bqAPI, projectId, limiter, checkpoint, progress, save)
+}
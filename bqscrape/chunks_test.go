@@ -0,0 +1,148 @@
+package bqscrape
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+)
+
+type savedChunk struct {
+	// empty if the chunk has no dataset
+	datasetID string
+	tableIDs  []string
+	next      Checkpoint
+}
+
+type fakeChunkSaver struct {
+	chunks     []savedChunk
+	partitions []*Partition
+	// if > 0, fails when saving chunk number failAt
+	failAt int
+}
+
+var errSaveFailed = errors.New("save failed")
+
+func (f *fakeChunkSaver) save(dataset *bigquery.Dataset, tables []*bigquery.Table,
+	partitions []*Partition, next Checkpoint) error {
+
+	if f.failAt > 0 && len(f.chunks)+1 == f.failAt {
+		return errSaveFailed
+	}
+	datasetID := ""
+	if dataset != nil {
+		datasetID = dataset.DatasetReference.DatasetId
+	}
+	ids := []string{}
+	for _, table := range tables {
+		ids = append(ids, table.TableReference.DatasetId+"."+table.TableReference.TableId)
+	}
+	f.chunks = append(f.chunks, savedChunk{datasetID, ids, next})
+	f.partitions = append(f.partitions, partitions...)
+	return nil
+}
+
+func (f *fakeChunkSaver) datasetIDs() []string {
+	ids := []string{}
+	for _, chunk := range f.chunks {
+		if chunk.datasetID != "" {
+			ids = append(ids, chunk.datasetID)
+		}
+	}
+	return ids
+}
+
+func (f *fakeChunkSaver) tableIDs() []string {
+	ids := []string{}
+	for _, chunk := range f.chunks {
+		ids = append(ids, chunk.tableIDs...)
+	}
+	return ids
+}
+
+func TestGetTablesInChunks(t *testing.T) {
+	fakeBQ := &fakeBigQueryAPI{}
+	fakeBQ.datasetTables = map[string][]string{
+		"ds1":   []string{"tableA", "tableB", "tableC"},
+		"ds0":   []string{"tableZ"},
+		"empty": []string{},
+	}
+	limiter := rate.NewLimiter(rate.Inf, 0)
+
+	saver := &fakeChunkSaver{}
+	progress := &FakeProgressReporter{}
+	err := getTablesInChunks(fakeBQ, "project", limiter, Checkpoint{}, progress, saver.save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// itemsPerPage = 2 so ds1 has 2 pages
+	expected := []savedChunk{
+		{"ds0", []string{"ds0.tableZ"}, Checkpoint{"ds1", "", 1, false}},
+		{"ds1", []string{"ds1.tableA", "ds1.tableB"}, Checkpoint{"ds1", "2", 3, false}},
+		{"", []string{"ds1.tableC"}, Checkpoint{"empty", "", 4, false}},
+		{"empty", []string{}, Checkpoint{"empty", "", 4, true}},
+	}
+	if !reflect.DeepEqual(expected, saver.chunks) {
+		t.Error(saver.chunks)
+	}
+	expectedProgress := []progressReport{
+		{0, "Listing datasets..."},
+		{10, "Reading table metadata: 0/3 datasets; 0 tables"},
+		{39, "Reading table metadata: 1/3 datasets; 1 tables"},
+		{39, "Reading table metadata: 1/3 datasets; 3 tables"},
+		{69, "Reading table metadata: 2/3 datasets; 4 tables"},
+	}
+	if !reflect.DeepEqual(expectedProgress, progress.progress) {
+		t.Error(progress.progress)
+	}
+
+	// a finished checkpoint does nothing
+	saver = &fakeChunkSaver{}
+	err = getTablesInChunks(fakeBQ, "project", limiter, expected[3].next, progress, saver.save)
+	if err != nil || len(saver.chunks) != 0 {
+		t.Error(err, saver.chunks)
+	}
+
+	// fail in the middle of each chunk then resume: each dataset and table is saved exactly once
+	allDatasets := []string{"ds0", "ds1", "empty"}
+	allTables := []string{"ds0.tableZ", "ds1.tableA", "ds1.tableB", "ds1.tableC"}
+	for failAt := 1; failAt <= len(expected); failAt++ {
+		saver = &fakeChunkSaver{failAt: failAt}
+		err = getTablesInChunks(fakeBQ, "project", limiter, Checkpoint{}, progress, saver.save)
+		if err != errSaveFailed {
+			t.Fatal(failAt, err)
+		}
+		checkpoint := Checkpoint{}
+		if len(saver.chunks) > 0 {
+			checkpoint = saver.chunks[len(saver.chunks)-1].next
+		}
+		saver.failAt = 0
+		err = getTablesInChunks(fakeBQ, "project", limiter, checkpoint, progress, saver.save)
+		if err != nil {
+			t.Fatal(failAt, err)
+		}
+		if !reflect.DeepEqual(allTables, saver.tableIDs()) {
+			t.Error(failAt, saver.tableIDs())
+		}
+		if !reflect.DeepEqual(allDatasets, saver.datasetIDs()) {
+			t.Error(failAt, saver.datasetIDs())
+		}
+		last := saver.chunks[len(saver.chunks)-1].next
+		if !last.Done || last.Tables != len(allTables) {
+			t.Error(failAt, last)
+		}
+	}
+
+	// resuming from a dataset that was deleted starts at the next dataset
+	saver = &fakeChunkSaver{}
+	checkpoint := Checkpoint{DatasetID: "ds0_deleted", PageToken: "2", Tables: 1}
+	err = getTablesInChunks(fakeBQ, "project", limiter, checkpoint, progress, saver.save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(allTables[1:], saver.tableIDs()) {
+		t.Error(saver.tableIDs())
+	}
+}
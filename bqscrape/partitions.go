@@ -0,0 +1,229 @@
+package bqscrape
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+)
+
+// One query reads the partitions of every partitioned table in a page of a dataset's tables, and
+// each table can have thousands of partitions, so the limit is for all of them: stop before the
+// rows of one chunk use too much memory.
+// https://cloud.google.com/bigquery/quotas#partitioned_tables
+const maxPartitions = 200000
+
+// Storage tier of long-term partitions in INFORMATION_SCHEMA.PARTITIONS.
+const storageTierLongTerm = "LONG_TERM"
+
+// https://cloud.google.com/bigquery/docs/information-schema-partitions
+const partitionsQuery = "SELECT table_name, partition_id, total_rows, total_logical_bytes," +
+	" last_modified_time, storage_tier FROM `%s.%s`.INFORMATION_SCHEMA.PARTITIONS" +
+	" WHERE table_name IN UNNEST(@tables) ORDER BY table_name, partition_id"
+
+// Partition is the storage used by one partition of a partitioned table.
+// https://cloud.google.com/bigquery/docs/partitioned-tables
+type Partition struct {
+	DatasetID string
+	TableID   string
+	// The partition's date or time (e.g. 20170102), the start of an integer range partition, or a
+	// special partition like __NULL__.
+	PartitionID        string
+	NumRows            int64
+	NumBytes           int64
+	LastModifiedTimeMs int64
+	// The partition has not been modified for 90 days and is billed at the long-term rate.
+	LongTerm bool
+}
+
+// Returns true if table is divided into partitions by time or by integer range.
+func IsPartitioned(table *bigquery.Table) bool {
+	return table.TimePartitioning != nil || table.RangePartitioning != nil
+}
+
+// Returns a random ID for a query. Retries of a query send the same ID, so BigQuery runs and bills
+// it at most once.
+// https://cloud.google.com/bigquery/docs/reference/rest/v2/jobs/query#QueryRequest
+func makeRequestID() (string, error) {
+	random := make([]byte, 16)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+func (a *bigQueryAPI) listPartitions(projectId string, datasetId string, location string,
+	tableIds []string) ([]*Partition, error) {
+
+	requestID, err := makeRequestID()
+	if err != nil {
+		return nil, err
+	}
+	useLegacySQL := false
+	tableValues := make([]*bigquery.QueryParameterValue, len(tableIds))
+	for i, tableId := range tableIds {
+		tableValues[i] = &bigquery.QueryParameterValue{Value: tableId}
+	}
+	request := &bigquery.QueryRequest{
+		Query:         fmt.Sprintf(partitionsQuery, projectId, datasetId),
+		RequestId:     requestID,
+		UseLegacySql:  &useLegacySQL,
+		Location:      location,
+		MaxResults:    collectionMaxResults,
+		ParameterMode: "NAMED",
+		QueryParameters: []*bigquery.QueryParameter{{
+			Name: "tables",
+			ParameterType: &bigquery.QueryParameterType{
+				Type: "ARRAY", ArrayType: &bigquery.QueryParameterType{Type: "STRING"}},
+			ParameterValue: &bigquery.QueryParameterValue{ArrayValues: tableValues},
+		}},
+	}
+
+	var resp *bigquery.QueryResponse
+	makeRequest := func() error {
+		var err error
+		resp, err = a.bq.Jobs.Query(projectId, request).Do()
+		return err
+	}
+	err = retry(context.TODO(), makeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for the query to finish, then read the remaining pages
+	rows := resp.Rows
+	jobComplete := resp.JobComplete
+	pageToken := resp.PageToken
+	for {
+		if len(rows) > maxPartitions {
+			return nil, fmt.Errorf("bqscrape: projectId:%s datasetId:%s exceeded max partitions:%d",
+				projectId, datasetId, maxPartitions)
+		}
+		if jobComplete && pageToken == "" {
+			break
+		}
+
+		var results *bigquery.GetQueryResultsResponse
+		makeRequest := func() error {
+			var err error
+			results, err = a.bq.Jobs.GetQueryResults(projectId, resp.JobReference.JobId).
+				Location(resp.JobReference.Location).
+				PageToken(pageToken).
+				MaxResults(collectionMaxResults).
+				Do()
+			return err
+		}
+		err = retry(context.TODO(), makeRequest)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, results.Rows...)
+		jobComplete = results.JobComplete
+		pageToken = results.PageToken
+	}
+
+	partitions := make([]*Partition, len(rows))
+	for i, row := range rows {
+		partitions[i], err = parsePartitionRow(datasetId, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return partitions, nil
+}
+
+// Returns the string value of a cell, or "" if it is NULL.
+func cellString(cell *bigquery.TableCell) string {
+	s, _ := cell.V.(string)
+	return s
+}
+
+// Parses an INT64 cell, which the API returns as a string. NULL is 0.
+func cellInt(cell *bigquery.TableCell) (int64, error) {
+	s := cellString(cell)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Parses a TIMESTAMP cell, which the API returns as a string of floating point seconds.
+func cellTimeMs(cell *bigquery.TableCell) (int64, error) {
+	s := cellString(cell)
+	if s == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(seconds * 1000), nil
+}
+
+// Parses a row of partitionsQuery.
+func parsePartitionRow(datasetId string, row *bigquery.TableRow) (*Partition, error) {
+	if len(row.F) != 6 {
+		return nil, fmt.Errorf("bqscrape: partitions query returned %d columns; expected 6",
+			len(row.F))
+	}
+	partition := &Partition{
+		DatasetID:   datasetId,
+		TableID:     cellString(row.F[0]),
+		PartitionID: cellString(row.F[1]),
+		LongTerm:    cellString(row.F[5]) == storageTierLongTerm,
+	}
+	var err error
+	partition.NumRows, err = cellInt(row.F[2])
+	if err != nil {
+		return nil, err
+	}
+	partition.NumBytes, err = cellInt(row.F[3])
+	if err != nil {
+		return nil, err
+	}
+	partition.LastModifiedTimeMs, err = cellTimeMs(row.F[4])
+	if err != nil {
+		return nil, err
+	}
+	return partition, nil
+}
+
+// Returns the partitions of the partitioned tables in one dataset. Reading partitions runs a
+// query, which needs permission to create jobs and the Scope OAuth scope. Partitions are
+// optional: if the query fails for any reason, this logs and returns no partitions so the rest of
+// the scrape still succeeds.
+func getPartitions(bqAPI api, projectId string, datasetId string, tables []*bigquery.Table,
+	limiter *rate.Limiter) ([]*Partition, error) {
+
+	var tableIds []string
+	location := ""
+	for _, table := range tables {
+		if IsPartitioned(table) {
+			tableIds = append(tableIds, table.TableReference.TableId)
+			location = table.Location
+		}
+	}
+	if len(tableIds) == 0 {
+		return nil, nil
+	}
+	sort.Strings(tableIds)
+
+	err := limiter.Wait(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := bqAPI.listPartitions(projectId, datasetId, location, tableIds)
+	if err != nil {
+		log.Printf("bqscrape: warning: project %s dataset %s: skipping partitions: %s",
+			projectId, datasetId, err.Error())
+		return nil, nil
+	}
+	return partitions, nil
+}
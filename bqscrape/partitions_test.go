@@ -0,0 +1,113 @@
+package bqscrape
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+func TestParsePartitionRow(t *testing.T) {
+	cells := func(values ...interface{}) *bigquery.TableRow {
+		row := &bigquery.TableRow{}
+		for _, v := range values {
+			row.F = append(row.F, &bigquery.TableCell{V: v})
+		}
+		return row
+	}
+
+	partition, err := parsePartitionRow("d", cells("t", "20170102", "10", "1024",
+		"1.483315200123E9", "LONG_TERM"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Partition{DatasetID: "d", TableID: "t", PartitionID: "20170102", NumRows: 10,
+		NumBytes: 1024, LastModifiedTimeMs: 1483315200123, LongTerm: true}
+	if !reflect.DeepEqual(partition, expected) {
+		t.Error(partition)
+	}
+
+	// NULL values are zero
+	partition, err = parsePartitionRow("d", cells("t", "__NULL__", nil, nil, nil, "ACTIVE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = &Partition{DatasetID: "d", TableID: "t", PartitionID: "__NULL__"}
+	if !reflect.DeepEqual(partition, expected) {
+		t.Error(partition)
+	}
+
+	for _, row := range []*bigquery.TableRow{
+		cells("t", "20170102"),
+		cells("t", "20170102", "not a number", "1", "1", "ACTIVE"),
+		cells("t", "20170102", "1", "1", "not a time", "ACTIVE"),
+	} {
+		_, err = parsePartitionRow("d", row)
+		if err == nil {
+			t.Error("expected error", row)
+		}
+	}
+}
+
+func TestGetTablesInChunksPartitions(t *testing.T) {
+	fakeBQ := &fakeBigQueryAPI{}
+	fakeBQ.datasetTables = map[string][]string{
+		"ds": []string{"partitioned", "unpartitioned"},
+	}
+	fakeBQ.partitions = map[string][]*Partition{
+		"partitioned": []*Partition{
+			{DatasetID: "ds", TableID: "partitioned", PartitionID: "20170101", NumBytes: 5},
+			{DatasetID: "ds", TableID: "partitioned", PartitionID: "20170102", NumBytes: 7},
+		},
+	}
+	limiter := rate.NewLimiter(rate.Inf, 0)
+
+	saver := &fakeChunkSaver{}
+	err := getTablesInChunks(fakeBQ, "project", limiter, Checkpoint{}, &NilProgressReporter{},
+		saver.save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saver.partitions, fakeBQ.partitions["partitioned"]) {
+		t.Error(saver.partitions)
+	}
+
+	// not permitted to run queries: tables are still saved without partitions
+	fakeBQ.partitionsErr = &googleapi.Error{Code: http.StatusForbidden,
+		Errors: []googleapi.ErrorItem{{Reason: "accessDenied"}}}
+	saver = &fakeChunkSaver{}
+	err = getTablesInChunks(fakeBQ, "project", limiter, Checkpoint{}, &NilProgressReporter{},
+		saver.save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(saver.tableIDs()) == 2 && len(saver.partitions) == 0) {
+		t.Error(saver.tableIDs(), saver.partitions)
+	}
+
+	// any other error, such as too many partitions, also skips the partitions
+	fakeBQ.partitionsErr = errors.New("bqscrape: exceeded max partitions")
+	saver = &fakeChunkSaver{}
+	err = getTablesInChunks(fakeBQ, "project", limiter, Checkpoint{}, &NilProgressReporter{},
+		saver.save)
+	if !(err == nil && len(saver.tableIDs()) == 2 && len(saver.partitions) == 0) {
+		t.Error(err, saver.tableIDs(), saver.partitions)
+	}
+}
+
+func TestIsPartitioned(t *testing.T) {
+	if IsPartitioned(&bigquery.Table{}) {
+		t.Error("table without partitioning must not be partitioned")
+	}
+	if !IsPartitioned(&bigquery.Table{TimePartitioning: &bigquery.TimePartitioning{Type: "DAY"}}) {
+		t.Error("time partitioned table must be partitioned")
+	}
+	if !IsPartitioned(&bigquery.Table{RangePartitioning: &bigquery.RangePartitioning{
+		Field: "id", Range: &bigquery.RangePartitioningRange{Start: 0, End: 100, Interval: 10}}}) {
+		t.Error("integer range partitioned table must be partitioned")
+	}
+}
@@ -0,0 +1,157 @@
+package bqscrape
+
+import (
+	"log"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+)
+
+// Projects can run millions of jobs a month: stop before a scrape takes hours. Only the newest
+// jobs are listed.
+const maxQueryJobs = 200000
+
+// QueryJob is one finished query from the BigQuery jobs API.
+// https://cloud.google.com/bigquery/docs/reference/rest/v2/jobs
+type QueryJob struct {
+	JobID    string
+	Location string
+	// The user or service account that ran the query.
+	UserEmail string
+	Query     string
+	// e.g. SELECT, INSERT or CREATE_TABLE_AS_SELECT
+	StatementType  string
+	CreationTimeMs int64
+
+	TotalBytesBilled    int64
+	TotalBytesProcessed int64
+	TotalSlotMs         int64
+	// The results were read from the query cache, which is free.
+	CacheHit bool
+	// Tables read by the query as project:dataset.table
+	ReferencedTables []string
+}
+
+// QueryJobSaver persists a page of query jobs.
+type QueryJobSaver func(jobs []*QueryJob) error
+
+func (a *bigQueryAPI) listJobs(projectId string, minCreationTimeMs uint64,
+	maxCreationTimeMs uint64, pageToken string) (*bigquery.JobList, error) {
+
+	request := a.bq.Jobs.List(projectId).
+		AllUsers(true).
+		Projection("full").
+		StateFilter("done").
+		MinCreationTime(minCreationTimeMs).
+		MaxCreationTime(maxCreationTimeMs).
+		PageToken(pageToken).
+		MaxResults(collectionMaxResults).
+		Fields("jobs(configuration(jobType,query(query)),jobReference,statistics(creationTime,query(cacheHit,referencedTables,statementType,totalBytesBilled,totalBytesProcessed,totalSlotMs)),user_email),nextPageToken")
+
+	var result *bigquery.JobList
+	makeRequest := func() error {
+		var err error
+		result, err = request.Do()
+		return err
+	}
+	err := retry(context.TODO(), makeRequest)
+	return result, err
+}
+
+// Returns the query job for job, or nil if it is not a query.
+func newQueryJob(job *bigquery.JobListJobs) *QueryJob {
+	if job.Configuration == nil || job.Configuration.Query == nil || job.Statistics == nil ||
+		job.Statistics.Query == nil {
+		return nil
+	}
+	stats := job.Statistics.Query
+	queryJob := &QueryJob{
+		JobID:               job.JobReference.JobId,
+		Location:            job.JobReference.Location,
+		UserEmail:           job.UserEmail,
+		Query:               job.Configuration.Query.Query,
+		StatementType:       stats.StatementType,
+		CreationTimeMs:      job.Statistics.CreationTime,
+		TotalBytesBilled:    stats.TotalBytesBilled,
+		TotalBytesProcessed: stats.TotalBytesProcessed,
+		TotalSlotMs:         stats.TotalSlotMs,
+		CacheHit:            stats.CacheHit,
+	}
+	for _, ref := range stats.ReferencedTables {
+		queryJob.ReferencedTables = append(queryJob.ReferencedTables,
+			ref.ProjectId+":"+ref.DatasetId+"."+ref.TableId)
+	}
+	return queryJob
+}
+
+func msSinceEpoch(t time.Time) uint64 {
+	return uint64(t.UnixNano() / int64(time.Millisecond))
+}
+
+// Lists the finished queries run by all users in projectId created in [minCreationTime,
+// maxCreationTime]. Calls save with each page of queries, newest first. Stops after maxJobs jobs
+// and returns true if there were more.
+func getQueryJobs(bqAPI api, projectId string, minCreationTime time.Time,
+	maxCreationTime time.Time, limiter *rate.Limiter, maxJobs int,
+	save QueryJobSaver) (bool, error) {
+
+	total := 0
+	pageToken := ""
+	for {
+		err := limiter.Wait(context.TODO())
+		if err != nil {
+			return false, err
+		}
+		resp, err := bqAPI.listJobs(projectId, msSinceEpoch(minCreationTime),
+			msSinceEpoch(maxCreationTime), pageToken)
+		if err != nil {
+			return false, err
+		}
+
+		truncated := false
+		listed := resp.Jobs
+		if total+len(listed) > maxJobs {
+			listed = listed[:maxJobs-total]
+			truncated = true
+		}
+		var jobs []*QueryJob
+		for _, job := range listed {
+			queryJob := newQueryJob(job)
+			if queryJob != nil {
+				jobs = append(jobs, queryJob)
+			}
+		}
+		total += len(listed)
+		log.Printf("bqscrape: project %s: %d query jobs in page of %d jobs",
+			projectId, len(jobs), len(listed))
+
+		err = save(jobs)
+		if err != nil {
+			return false, err
+		}
+		if truncated {
+			log.Printf("bqscrape: warning: project %s: stopped at max jobs:%d; older jobs are missing",
+				projectId, maxJobs)
+			return true, nil
+		}
+		pageToken = resp.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	return false, nil
+}
+
+// Fetches the finished queries run by all users in projectId created between minCreationTime
+// and maxCreationTime. Calls save with each page of queries, newest first. Returns true if the
+// history was truncated: there were too many jobs, so the oldest were not listed. Listing the
+// jobs of all users requires the bigquery.jobs.listAll permission.
+func GetQueryJobs(bq *bigquery.Service, projectId string, minCreationTime time.Time,
+	maxCreationTime time.Time, save QueryJobSaver) (bool, error) {
+
+	bqAPI, limiter := productionConfig(bq)
+	return getQueryJobs(bqAPI, projectId, minCreationTime, maxCreationTime, limiter,
+		maxQueryJobs, save)
+}
@@ -0,0 +1,68 @@
+package bqscrape
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestGetQueryJobs(t *testing.T) {
+	queryJob := func(id string, bytesBilled int64) *bigquery.JobListJobs {
+		return &bigquery.JobListJobs{
+			JobReference: &bigquery.JobReference{ProjectId: "p", JobId: id, Location: "US"},
+			UserEmail:    "user@example.com",
+			Configuration: &bigquery.JobConfiguration{JobType: "QUERY",
+				Query: &bigquery.JobConfigurationQuery{Query: "SELECT * FROM d.t"}},
+			Statistics: &bigquery.JobStatistics{CreationTime: 1000,
+				Query: &bigquery.JobStatistics2{TotalBytesBilled: bytesBilled, StatementType: "SELECT",
+					ReferencedTables: []*bigquery.TableReference{
+						{ProjectId: "p", DatasetId: "d", TableId: "t"}}}},
+		}
+	}
+	fakeBQ := &fakeBigQueryAPI{}
+	fakeBQ.jobs = []*bigquery.JobListJobs{
+		queryJob("a", 10),
+		// load jobs are skipped
+		&bigquery.JobListJobs{
+			JobReference:  &bigquery.JobReference{ProjectId: "p", JobId: "load"},
+			Configuration: &bigquery.JobConfiguration{JobType: "LOAD"},
+			Statistics:    &bigquery.JobStatistics{CreationTime: 1000},
+		},
+		queryJob("b", 20),
+	}
+	limiter := rate.NewLimiter(rate.Inf, 0)
+
+	var pages [][]*QueryJob
+	save := func(jobs []*QueryJob) error {
+		pages = append(pages, jobs)
+		return nil
+	}
+	min := time.Unix(1, 0)
+	max := time.Unix(2, 0)
+	truncated, err := getQueryJobs(fakeBQ, "p", min, max, limiter, 10, save)
+	if err != nil || truncated {
+		t.Fatal(truncated, err)
+	}
+	if !(fakeBQ.minCreationTimeMs == 1000 && fakeBQ.maxCreationTimeMs == 2000) {
+		t.Error(fakeBQ.minCreationTimeMs, fakeBQ.maxCreationTimeMs)
+	}
+	expected := &QueryJob{JobID: "a", Location: "US", UserEmail: "user@example.com",
+		Query: "SELECT * FROM d.t", StatementType: "SELECT", CreationTimeMs: 1000,
+		TotalBytesBilled: 10, ReferencedTables: []string{"p:d.t"}}
+	// itemsPerPage = 2
+	if !(len(pages) == 2 && len(pages[0]) == 1 && reflect.DeepEqual(pages[0][0], expected) &&
+		len(pages[1]) == 1 && pages[1][0].JobID == "b") {
+		t.Error(pages)
+	}
+
+	// stops at the limit, saving the newest jobs
+	pages = nil
+	truncated, err = getQueryJobs(fakeBQ, "p", min, max, limiter, 1, save)
+	if !(err == nil && truncated && len(pages) == 1 && len(pages[0]) == 1 &&
+		pages[0][0].JobID == "a") {
+		t.Error(truncated, err, pages)
+	}
+}
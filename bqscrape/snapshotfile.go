@@ -0,0 +1,195 @@
+package bqscrape
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// Version of the snapshot file format. Readers reject newer versions.
+const snapshotFileVersion = 1
+
+// Longest line in a snapshot file: tables with huge schemas or view queries can be large.
+const maxSnapshotLineBytes = 64 * 1024 * 1024
+
+// One line of a snapshot file. The first line is the header; each following line has one of
+// Dataset, Table or Partition.
+type snapshotLine struct {
+	// Header only.
+	Version   int    `json:"version,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	TimeMs    int64  `json:"time_ms,omitempty"`
+
+	Dataset   *bigquery.Dataset `json:"dataset,omitempty"`
+	Table     *bigquery.Table   `json:"table,omitempty"`
+	Partition *Partition        `json:"partition,omitempty"`
+}
+
+// Snapshot is a complete scrape of a project, read from a snapshot file.
+type Snapshot struct {
+	ProjectID string
+	// When the scrape started.
+	TimeMs     int64
+	Datasets   []*bigquery.Dataset
+	Tables     []*bigquery.Table
+	Partitions []*Partition
+}
+
+// SnapshotWriter writes a scrape to a gzip compressed file with one JSON object per line, so
+// it can be shared and reported on without access to BigQuery.
+type SnapshotWriter struct {
+	gz      *gzip.Writer
+	encoder *json.Encoder
+}
+
+// NewSnapshotWriter writes the header of a snapshot of projectID scraped at scrapeTime to w.
+// Call Close to finish the file.
+func NewSnapshotWriter(w io.Writer, projectID string, scrapeTime time.Time) (*SnapshotWriter,
+	error) {
+
+	gz := gzip.NewWriter(w)
+	s := &SnapshotWriter{gz, json.NewEncoder(gz)}
+	err := s.encoder.Encode(&snapshotLine{Version: snapshotFileVersion, ProjectID: projectID,
+		TimeMs: scrapeTime.UnixNano() / int64(time.Millisecond)})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes a chunk. It is a ChunkSaver; the checkpoint is ignored, since a snapshot file
+// can't resume a scrape.
+func (s *SnapshotWriter) Save(dataset *bigquery.Dataset, tables []*bigquery.Table,
+	partitions []*Partition, next Checkpoint) error {
+
+	if dataset != nil {
+		err := s.encoder.Encode(&snapshotLine{Dataset: dataset})
+		if err != nil {
+			return err
+		}
+	}
+	for _, table := range tables {
+		err := s.encoder.Encode(&snapshotLine{Table: table})
+		if err != nil {
+			return err
+		}
+	}
+	for _, partition := range partitions {
+		err := s.encoder.Encode(&snapshotLine{Partition: partition})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the compressed data. It does not close the underlying writer.
+func (s *SnapshotWriter) Close() error {
+	return s.gz.Close()
+}
+
+// ReadSnapshot reads a file written by SnapshotWriter.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(nil, maxSnapshotLineBytes)
+
+	snapshot := &Snapshot{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := &snapshotLine{}
+		err = json.Unmarshal(scanner.Bytes(), line)
+		if err != nil {
+			return nil, fmt.Errorf("bqscrape: snapshot line %d: %s", lineNumber, err.Error())
+		}
+		if lineNumber == 1 {
+			if line.Version == 0 || line.ProjectID == "" {
+				return nil, fmt.Errorf("bqscrape: snapshot is missing its header")
+			}
+			if line.Version > snapshotFileVersion {
+				return nil, fmt.Errorf("bqscrape: unsupported snapshot version %d; expected <= %d",
+					line.Version, snapshotFileVersion)
+			}
+			snapshot.ProjectID = line.ProjectID
+			snapshot.TimeMs = line.TimeMs
+			continue
+		}
+		switch {
+		case line.Dataset != nil:
+			snapshot.Datasets = append(snapshot.Datasets, line.Dataset)
+		case line.Table != nil:
+			snapshot.Tables = append(snapshot.Tables, line.Table)
+		case line.Partition != nil:
+			snapshot.Partitions = append(snapshot.Partitions, line.Partition)
+		default:
+			return nil, fmt.Errorf("bqscrape: snapshot line %d is empty", lineNumber)
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	if lineNumber == 0 {
+		return nil, fmt.Errorf("bqscrape: snapshot is missing its header")
+	}
+	return snapshot, nil
+}
+
+// ReadSnapshotFile reads the snapshot file at path.
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSnapshot(f)
+}
+
+// WriteSnapshot scrapes all datasets, tables and partitions in projectId and writes them to w
+// as a snapshot file.
+func WriteSnapshot(bq *bigquery.Service, projectId string, progress ProgressReporter,
+	w io.Writer) error {
+
+	writer, err := NewSnapshotWriter(w, projectId, time.Now())
+	if err != nil {
+		return err
+	}
+	err = GetTablesInChunks(bq, projectId, Checkpoint{}, progress, writer.Save)
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// WriteSnapshotFile scrapes projectId into a new snapshot file at path. The file is removed if
+// the scrape fails.
+func WriteSnapshotFile(bq *bigquery.Service, projectId string, progress ProgressReporter,
+	path string) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteSnapshot(bq, projectId, progress, f)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package bqscrape
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestSnapshotFile(t *testing.T) {
+	fakeBQ := &fakeBigQueryAPI{}
+	fakeBQ.datasetTables = map[string][]string{
+		"a":     []string{"partitioned", "t"},
+		"empty": []string{},
+	}
+	fakeBQ.partitions = map[string][]*Partition{
+		"partitioned": []*Partition{
+			{DatasetID: "a", TableID: "partitioned", PartitionID: "20170101", NumBytes: 5},
+		},
+	}
+	limiter := rate.NewLimiter(rate.Inf, 0)
+
+	buf := &bytes.Buffer{}
+	scrapeTime := time.Unix(1500000000, 0)
+	writer, err := NewSnapshotWriter(buf, "project", scrapeTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = getTablesInChunks(fakeBQ, "project", limiter, Checkpoint{}, &NilProgressReporter{},
+		writer.Save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := ReadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(snapshot.ProjectID == "project" && snapshot.TimeMs == 1500000000000 &&
+		len(snapshot.Datasets) == 2 && snapshot.Datasets[0].DatasetReference.DatasetId == "a" &&
+		len(snapshot.Tables) == 2 && snapshot.Tables[1].TableReference.TableId == "t" &&
+		reflect.DeepEqual(snapshot.Partitions, fakeBQ.partitions["partitioned"])) {
+		t.Error(snapshot)
+	}
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	gzipLines := func(lines ...string) []byte {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		gz.Write([]byte(strings.Join(lines, "\n")))
+		gz.Close()
+		return buf.Bytes()
+	}
+	header := `{"version":1,"project_id":"p","time_ms":1}`
+	tests := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("this is not a gzip file"), "gzip: invalid header"},
+		{gzipLines(), "missing its header"},
+		{gzipLines(`{"table":{}}`), "missing its header"},
+		{gzipLines(`{"version":2,"project_id":"p"}`), "unsupported snapshot version 2"},
+		{gzipLines(header, `{}`), "line 2 is empty"},
+		{gzipLines(header, `{"table":`), "snapshot line 2"},
+	}
+	for _, test := range tests {
+		_, err := ReadSnapshot(bytes.NewReader(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("ReadSnapshot(%#v) = %v; expected error containing %#v",
+				string(test.input), err, test.expected)
+		}
+	}
+
+	snapshot, err := ReadSnapshot(bytes.NewReader(gzipLines(header,
+		`{"table":{"tableReference":{"datasetId":"d","tableId":"t"},"numBytes":"5"}}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &bigquery.Table{TableReference: &bigquery.TableReference{DatasetId: "d",
+		TableId: "t"}, NumBytes: 5}
+	if !(len(snapshot.Tables) == 1 && reflect.DeepEqual(snapshot.Tables[0], expected)) {
+		t.Error(snapshot.Tables)
+	}
+}
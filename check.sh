@@ -11,7 +11,7 @@ go test -v -i ./...
 go test -race -v -i ./...
 go test -race -v ./... || (echo "FAILED" && exit 1)
 go vet ./...
-go build bqcost.go credentials.go
+go build
 
 # currently too noisy TODO: enable
 #golint ./...
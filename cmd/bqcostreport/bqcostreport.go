@@ -0,0 +1,243 @@
+// Command bqcostreport prints the storage cost of a BigQuery project's datasets and tables,
+// without the web server. It uses application default credentials, or a service account key.
+// It can save the scraped metadata to a snapshot file, and report on a snapshot file without
+// access to BigQuery.
+//
+//	bqcostreport --project=my-project --format=csv > storage.csv
+//	bqcostreport --project=my-project --dump=my-project.jsonl.gz
+//	bqcostreport --snapshot=my-project.jsonl.gz
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+
+	"github.com/evanj/bqtools/internal/cliauth"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Storage and cost of a dataset or table.
+type storage struct {
+	ID       string `json:"id"`
+	Type     string `json:"type,omitempty"`
+	Location string `json:"location"`
+	// Datasets only.
+	Tables          int     `json:"tables,omitempty"`
+	Rows            int64   `json:"rows"`
+	Bytes           int64   `json:"bytes"`
+	LongTermBytes   int64   `json:"long_term_bytes"`
+	DollarsPerMonth float64 `json:"dollars_per_month"`
+}
+
+type report struct {
+	ProjectID string `json:"project_id"`
+	// When the project was scraped, in RFC 3339 format.
+	Time            string  `json:"time"`
+	Bytes           int64   `json:"bytes"`
+	LongTermBytes   int64   `json:"long_term_bytes"`
+	DollarsPerMonth float64 `json:"dollars_per_month"`
+	// Most expensive first.
+	Datasets []*storage `json:"datasets"`
+	Tables   []*storage `json:"tables"`
+}
+
+// Sorts by cost, then size, then ID.
+func sortStorage(items []*storage) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].DollarsPerMonth != items[j].DollarsPerMonth {
+			return items[i].DollarsPerMonth > items[j].DollarsPerMonth
+		}
+		if items[i].Bytes != items[j].Bytes {
+			return items[i].Bytes > items[j].Bytes
+		}
+		return items[i].ID < items[j].ID
+	})
+}
+
+// Returns the storage report for tables at the prices in effect at now. Tables are billed in the
+// billing model of their dataset in bqDatasets; tables without one use logical billing. If
+// limit > 0, only the limit most expensive datasets and tables are listed; the totals include
+// all of them.
+func newReport(prices *pricing.Catalog, projectID string, now time.Time,
+	bqDatasets []*bigquery.Dataset, tables []*bigquery.Table, limit int) *report {
+
+	billing := map[string]pricing.Billing{}
+	for _, dataset := range bqDatasets {
+		billing[dataset.DatasetReference.DatasetId] =
+			pricing.BillingForModel(dataset.StorageBillingModel)
+	}
+
+	r := &report{ProjectID: projectID, Time: now.UTC().Format(time.RFC3339)}
+	datasets := map[string]*storage{}
+	for _, table := range tables {
+		tableBilling := billing[table.TableReference.DatasetId]
+		if tableBilling == "" {
+			tableBilling = pricing.Logical
+		}
+		bytes, longTermBytes := table.NumBytes, table.NumLongTermBytes
+		if tableBilling == pricing.Physical {
+			bytes = table.NumActivePhysicalBytes + table.NumLongTermPhysicalBytes
+			longTermBytes = table.NumLongTermPhysicalBytes
+		}
+		cost := prices.StorageCost(table.Location, tableBilling, now, bytes, longTermBytes).Total()
+		r.Tables = append(r.Tables, &storage{
+			ID:              table.TableReference.DatasetId + "." + table.TableReference.TableId,
+			Type:            table.Type,
+			Location:        table.Location,
+			Rows:            int64(table.NumRows),
+			Bytes:           table.NumBytes,
+			LongTermBytes:   table.NumLongTermBytes,
+			DollarsPerMonth: cost,
+		})
+		r.Bytes += table.NumBytes
+		r.LongTermBytes += table.NumLongTermBytes
+		r.DollarsPerMonth += cost
+
+		dataset := datasets[table.TableReference.DatasetId]
+		if dataset == nil {
+			dataset = &storage{ID: table.TableReference.DatasetId, Location: table.Location}
+			datasets[dataset.ID] = dataset
+			r.Datasets = append(r.Datasets, dataset)
+		}
+		dataset.Tables++
+		dataset.Rows += int64(table.NumRows)
+		dataset.Bytes += table.NumBytes
+		dataset.LongTermBytes += table.NumLongTermBytes
+		dataset.DollarsPerMonth += cost
+	}
+
+	sortStorage(r.Datasets)
+	sortStorage(r.Tables)
+	if limit > 0 && len(r.Datasets) > limit {
+		r.Datasets = r.Datasets[:limit]
+	}
+	if limit > 0 && len(r.Tables) > limit {
+		r.Tables = r.Tables[:limit]
+	}
+	return r
+}
+
+func writeText(w io.Writer, r *report) error {
+	_, err := fmt.Fprintf(w, "%s at %s: %s; $%.2f/month\n", r.ProjectID, r.Time,
+		templates.HumanBytes(r.Bytes), r.DollarsPerMonth)
+	if err != nil {
+		return err
+	}
+	writeSection := func(title string, items []*storage) error {
+		fmt.Fprintf(w, "\n%s\n", title)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "$/Month\tBytes\tLong-Term\tRows\tLocation\tID\t\n")
+		for _, item := range items {
+			fmt.Fprintf(tw, "$%.2f\t%s\t%s\t%d\t%s\t%s\t\n", item.DollarsPerMonth,
+				templates.HumanBytes(item.Bytes), templates.HumanBytes(item.LongTermBytes), item.Rows,
+				item.Location, item.ID)
+		}
+		return tw.Flush()
+	}
+	err = writeSection("Datasets", r.Datasets)
+	if err != nil {
+		return err
+	}
+	return writeSection("Tables", r.Tables)
+}
+
+func writeJSON(w io.Writer, r *report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Writes one row for each dataset and table. Tables have a type; datasets do not.
+func writeCSV(w io.Writer, r *report) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"kind", "id", "type", "location", "tables", "rows", "bytes",
+		"long_term_bytes", "dollars_per_month"})
+	if err != nil {
+		return err
+	}
+	writeRows := func(kind string, items []*storage) error {
+		for _, item := range items {
+			err := writer.Write([]string{kind, item.ID, item.Type, item.Location,
+				strconv.Itoa(item.Tables), strconv.FormatInt(item.Rows, 10),
+				strconv.FormatInt(item.Bytes, 10), strconv.FormatInt(item.LongTermBytes, 10),
+				strconv.FormatFloat(item.DollarsPerMonth, 'f', 2, 64)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = writeRows("dataset", r.Datasets)
+	if err != nil {
+		return err
+	}
+	err = writeRows("table", r.Tables)
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+var writers = map[string]func(io.Writer, *report) error{
+	"text": writeText,
+	"json": writeJSON,
+	"csv":  writeCSV,
+}
+
+func main() {
+	projectID := flag.String("project", "", "Project to report on (required unless --snapshot)")
+	keyPath := flag.String("credentials", "",
+		"Service account key file; uses application default credentials if empty")
+	format := flag.String("format", "text", "Output format: text, json or csv")
+	pricesPath := flag.String("prices", "", "Price catalog JSON file; uses the built-in catalog if empty")
+	limit := flag.Int("limit", 0, "Only list the most expensive datasets and tables; 0 lists all")
+	snapshotPath := flag.String("snapshot", "",
+		"Report on a snapshot file saved with --dump instead of reading BigQuery")
+	dumpPath := flag.String("dump", "", "Save the scraped metadata to this snapshot file")
+	flag.Parse()
+
+	if *projectID == "" && *snapshotPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: --project or --snapshot is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	write := writers[*format]
+	if write == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown --format=%s\n", *format)
+		os.Exit(2)
+	}
+	prices := pricing.Default()
+	if *pricesPath != "" {
+		var err error
+		prices, err = pricing.LoadFile(*pricesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	snapshot, err := cliauth.LoadSnapshot(*projectID, *keyPath, *snapshotPath, *dumpPath,
+		cliauth.LogProgress("bqcostreport"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// costs use the prices when the project was scraped
+	scrapeTime := time.Unix(0, snapshot.TimeMs*int64(time.Millisecond))
+	err = write(os.Stdout, newReport(prices, snapshot.ProjectID, scrapeTime, snapshot.Datasets,
+		snapshot.Tables, *limit))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
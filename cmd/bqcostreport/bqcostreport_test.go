@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/internal/cliauth"
+	"github.com/evanj/bqtools/pricing"
+	"google.golang.org/api/bigquery/v2"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func newTestReport(limit int) *report {
+	table := func(datasetID string, tableID string, bytes int64, longTermBytes int64) *bigquery.Table {
+		return &bigquery.Table{
+			TableReference: &bigquery.TableReference{ProjectId: "p", DatasetId: datasetID,
+				TableId: tableID},
+			Type: "TABLE", Location: "US", NumBytes: bytes, NumLongTermBytes: longTermBytes,
+			NumRows: 10,
+		}
+	}
+	tables := []*bigquery.Table{
+		table("a", "small", gib, 0),
+		table("b", "big", 100*gib, 50*gib),
+		table("a", "medium", 10*gib, 0),
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return newReport(pricing.Default(), "p", now, nil, tables, limit)
+}
+
+func TestNewReport(t *testing.T) {
+	r := newTestReport(0)
+	if !(r.Bytes == 111*gib && r.LongTermBytes == 50*gib && len(r.Datasets) == 2 &&
+		r.Datasets[0].ID == "b" && r.Datasets[1].ID == "a" && r.Datasets[1].Tables == 2 &&
+		r.Datasets[1].Rows == 20 && len(r.Tables) == 3 && r.Tables[0].ID == "b.big" &&
+		r.Tables[2].ID == "a.small") {
+		t.Error(r)
+	}
+	// 50 GiB active at $0.02 and 50 GiB long-term at $0.01
+	if !(r.Tables[0].DollarsPerMonth > 1.49 && r.Tables[0].DollarsPerMonth < 1.51) {
+		t.Error(r.Tables[0].DollarsPerMonth)
+	}
+
+	// limits the lists but not the totals
+	r = newTestReport(1)
+	if !(r.Bytes == 111*gib && len(r.Datasets) == 1 && len(r.Tables) == 1) {
+		t.Error(r)
+	}
+}
+
+func TestNewReportPhysicalBilling(t *testing.T) {
+	datasets := []*bigquery.Dataset{{
+		DatasetReference:    &bigquery.DatasetReference{ProjectId: "p", DatasetId: "d"},
+		StorageBillingModel: "PHYSICAL",
+	}}
+	tables := []*bigquery.Table{{
+		TableReference: &bigquery.TableReference{ProjectId: "p", DatasetId: "d", TableId: "t"},
+		Type:           "TABLE", Location: "US", NumBytes: 100 * gib, NumLongTermBytes: 50 * gib,
+		NumActivePhysicalBytes: 10 * gib, NumLongTermPhysicalBytes: 10 * gib,
+	}}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newReport(pricing.Default(), "p", now, datasets, tables, 0)
+	// 10 GiB active at $0.04 and 10 GiB long-term at $0.02; sizes are still logical
+	if !(r.Bytes == 100*gib && r.DollarsPerMonth > 0.59 && r.DollarsPerMonth < 0.61) {
+		t.Error(r.Bytes, r.DollarsPerMonth)
+	}
+}
+
+func TestWriters(t *testing.T) {
+	r := newTestReport(0)
+
+	buf := &bytes.Buffer{}
+	err := writeText(buf, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"p at 2024-01-01T00:00:00Z: 111.0 GiB", "Datasets",
+		"100.0 GiB", "b.big"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error(expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	err = writeJSON(buf, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &report{}
+	err = json.Unmarshal(buf.Bytes(), decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(decoded.ProjectID == "p" && len(decoded.Tables) == 3 && *decoded.Tables[0] == *r.Tables[0]) {
+		t.Error(buf.String())
+	}
+
+	buf.Reset()
+	err = writeCSV(buf, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !(len(lines) == 6 &&
+		lines[0] == "kind,id,type,location,tables,rows,bytes,long_term_bytes,dollars_per_month" &&
+		lines[1] == "dataset,b,,US,1,10,107374182400,53687091200,1.50" &&
+		lines[3] == "table,b.big,TABLE,US,0,10,107374182400,53687091200,1.50") {
+		t.Error(buf.String())
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	f, err := ioutil.TempFile("", "bqcostreport_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	writer, err := bqscrape.NewSnapshotWriter(f, "p", time.Unix(1500000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := &bigquery.Table{
+		TableReference: &bigquery.TableReference{ProjectId: "p", DatasetId: "d", TableId: "t"},
+		Location:       "EU", NumBytes: gib,
+	}
+	err = writer.Save(nil, []*bigquery.Table{table}, nil, bqscrape.Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// does not need credentials
+	snapshot, err := cliauth.LoadSnapshot("", "", f.Name(), "", cliauth.LogProgress("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newReport(pricing.Default(), snapshot.ProjectID,
+		time.Unix(0, snapshot.TimeMs*int64(time.Millisecond)), snapshot.Datasets, snapshot.Tables,
+		0)
+	if !(r.ProjectID == "p" && r.Time == "2017-07-14T02:40:00Z" && len(r.Tables) == 1 &&
+		r.Tables[0].ID == "d.t" && r.Tables[0].Location == "EU") {
+		t.Error(r)
+	}
+}
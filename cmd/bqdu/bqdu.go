@@ -0,0 +1,179 @@
+// Command bqdu prints the storage of a BigQuery project as a tree of datasets and tables, like
+// du. Each line shows the size, rows and percent of the parent's size.
+//
+//	bqdu --project=my-project --depth=1 --min=1GiB
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/bigquery/v2"
+
+	"github.com/evanj/bqtools/internal/cliauth"
+	"github.com/evanj/bqtools/templates"
+)
+
+// A project, dataset or table.
+type node struct {
+	name     string
+	bytes    int64
+	rows     int64
+	children []*node
+}
+
+// Returns the tree of tables in their datasets in projectID.
+func newTree(projectID string, tables []*bigquery.Table) *node {
+	root := &node{name: projectID}
+	datasets := map[string]*node{}
+	for _, table := range tables {
+		datasetID := table.TableReference.DatasetId
+		dataset := datasets[datasetID]
+		if dataset == nil {
+			dataset = &node{name: datasetID}
+			datasets[datasetID] = dataset
+			root.children = append(root.children, dataset)
+		}
+		dataset.children = append(dataset.children, &node{name: table.TableReference.TableId,
+			bytes: table.NumBytes, rows: int64(table.NumRows)})
+		dataset.bytes += table.NumBytes
+		dataset.rows += int64(table.NumRows)
+		root.bytes += table.NumBytes
+		root.rows += int64(table.NumRows)
+	}
+	return root
+}
+
+// Orders children in the tree; ties are sorted by name.
+var sorts = map[string]func(a *node, b *node) bool{
+	"size": func(a *node, b *node) bool { return a.bytes > b.bytes },
+	"rows": func(a *node, b *node) bool { return a.rows > b.rows },
+	"name": func(a *node, b *node) bool { return false },
+}
+
+func sortTree(n *node, less func(a *node, b *node) bool) {
+	sort.Slice(n.children, func(i, j int) bool {
+		a := n.children[i]
+		b := n.children[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.name < b.name
+	})
+	for _, child := range n.children {
+		sortTree(child, less)
+	}
+}
+
+type printOptions struct {
+	// Children deeper than this are not printed: 0 only prints the project.
+	maxDepth int
+	// Nodes smaller than this are summarized in one line per parent.
+	minBytes int64
+}
+
+func percent(bytes int64, parentBytes int64) string {
+	if parentBytes == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(bytes)*100/float64(parentBytes), 'f', 1, 64) + "%"
+}
+
+func printNode(w io.Writer, n *node, parentBytes int64, depth int, options *printOptions) {
+	fmt.Fprintf(w, "%10s %14d %6s  %s%s\n", templates.HumanBytes(n.bytes), n.rows,
+		percent(n.bytes, parentBytes), strings.Repeat("  ", depth), n.name)
+	if depth >= options.maxDepth {
+		return
+	}
+
+	var hidden node
+	hiddenCount := 0
+	for _, child := range n.children {
+		if child.bytes < options.minBytes {
+			hidden.bytes += child.bytes
+			hidden.rows += child.rows
+			hiddenCount++
+			continue
+		}
+		printNode(w, child, n.bytes, depth+1, options)
+	}
+	if hiddenCount > 0 {
+		hidden.name = fmt.Sprintf("(%d smaller)", hiddenCount)
+		fmt.Fprintf(w, "%10s %14d %6s  %s%s\n", templates.HumanBytes(hidden.bytes), hidden.rows,
+			percent(hidden.bytes, n.bytes), strings.Repeat("  ", depth+1), hidden.name)
+	}
+}
+
+// Byte suffixes accepted by parseBytes. Units are powers of 1024, like templates.HumanBytes.
+var byteSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"PiB", 1 << 50}, {"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"P", 1 << 50}, {"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// Parses sizes like 1024, 1.5GiB or 10G.
+func parseBytes(s string) (int64, error) {
+	number := strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, suffix := range byteSuffixes {
+		if strings.HasSuffix(number, suffix.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, suffix.suffix))
+			multiplier = suffix.multiplier
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("bqdu: invalid size %#v", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+func main() {
+	projectID := flag.String("project", "", "Project to list (required unless --snapshot)")
+	keyPath := flag.String("credentials", "",
+		"Service account key file; uses application default credentials if empty")
+	depth := flag.Int("depth", 2, "Levels to print: 1 prints datasets, 2 prints tables")
+	sortOrder := flag.String("sort", "size", "Sort by size, rows or name")
+	minSize := flag.String("min", "0", "Summarize datasets and tables smaller than this (e.g. 10GiB)")
+	snapshotPath := flag.String("snapshot", "",
+		"Read a snapshot file saved by bqcostreport --dump instead of BigQuery")
+	flag.Parse()
+
+	if *projectID == "" && *snapshotPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: --project or --snapshot is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	less := sorts[*sortOrder]
+	if less == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown --sort=%s\n", *sortOrder)
+		os.Exit(2)
+	}
+	minBytes, err := parseBytes(*minSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		os.Exit(2)
+	}
+
+	snapshot, err := cliauth.LoadSnapshot(*projectID, *keyPath, *snapshotPath, "",
+		cliauth.LogProgress("bqdu"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	root := newTree(snapshot.ProjectID, snapshot.Tables)
+	sortTree(root, less)
+	printNode(os.Stdout, root, root.bytes, 0, &printOptions{maxDepth: *depth, minBytes: minBytes})
+}
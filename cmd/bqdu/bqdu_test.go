@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func newTestTree() *node {
+	table := func(datasetID string, tableID string, bytes int64, rows uint64) *bigquery.Table {
+		return &bigquery.Table{
+			TableReference: &bigquery.TableReference{ProjectId: "p", DatasetId: datasetID,
+				TableId: tableID},
+			NumBytes: bytes, NumRows: rows,
+		}
+	}
+	return newTree("p", []*bigquery.Table{
+		table("a", "small", gib, 100),
+		table("b", "big", 30*gib, 10),
+		table("a", "medium", 9*gib, 5),
+	})
+}
+
+// Returns the names in the output, without sizes.
+func printedNames(root *node, options *printOptions) []string {
+	buf := &bytes.Buffer{}
+	printNode(buf, root, root.bytes, 0, options)
+	var names []string
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		names = append(names, strings.TrimSpace(line[34:]))
+	}
+	return names
+}
+
+func TestTree(t *testing.T) {
+	root := newTestTree()
+	if !(root.bytes == 40*gib && root.rows == 115 && len(root.children) == 2 &&
+		root.children[0].bytes == 10*gib && len(root.children[0].children) == 2) {
+		t.Error(root)
+	}
+
+	sortTree(root, sorts["size"])
+	buf := &bytes.Buffer{}
+	printNode(buf, root, root.bytes, 0, &printOptions{maxDepth: 2})
+	expected := "  40.0 GiB            115 100.0%  p\n" +
+		"  30.0 GiB             10  75.0%    b\n" +
+		"  30.0 GiB             10 100.0%      big\n" +
+		"  10.0 GiB            105  25.0%    a\n" +
+		"   9.0 GiB              5  90.0%      medium\n" +
+		"   1.0 GiB            100  10.0%      small\n"
+	if buf.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	tests := []struct {
+		sort     string
+		options  printOptions
+		expected string
+	}{
+		{"size", printOptions{maxDepth: 1}, "p b a"},
+		{"size", printOptions{maxDepth: 0}, "p"},
+		{"name", printOptions{maxDepth: 2}, "p a medium small b big"},
+		{"rows", printOptions{maxDepth: 2}, "p a small medium b big"},
+		{"size", printOptions{maxDepth: 2, minBytes: 5 * gib}, "p b big a medium (1 smaller)"},
+		{"size", printOptions{maxDepth: 2, minBytes: 20 * gib}, "p b big (1 smaller)"},
+	}
+	for _, test := range tests {
+		sortTree(root, sorts[test.sort])
+		names := strings.Join(printedNames(root, &test.options), " ")
+		if names != test.expected {
+			t.Errorf("sort=%s options=%v: %s; expected %s", test.sort, test.options, names,
+				test.expected)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"1KiB", 1024},
+		{"1.5GiB", 3 * gib / 2},
+		{"10G", 10 * gib},
+		{"2 TiB", 2 * 1024 * gib},
+		{"100B", 100},
+	}
+	for _, test := range tests {
+		output, err := parseBytes(test.input)
+		if err != nil || output != test.expected {
+			t.Errorf("parseBytes(%#v) = %d, %v; expected %d", test.input, output, err, test.expected)
+		}
+	}
+	for _, input := range []string{"", "G", "-1", "1X"} {
+		_, err := parseBytes(input)
+		if err == nil {
+			t.Errorf("parseBytes(%#v) expected error", input)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Tables not read or written for this many days are listed by default.
+const defaultColdDays = 90
+
+// Maximum number of tables listed in the cold storage report.
+const maxColdResults = 1000
+
+// Returns the tables in snapshot that were not modified or read by a loaded query in the days
+// before the snapshot, most expensive first.
+func queryColdStorage(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64,
+	project *bqdb.Project, snapshot *bqdb.Snapshot, days int) (*templates.ColdStorageReport, error) {
+
+	cutoffMs := snapshot.TimeMs - durationMs(time.Duration(days)*24*time.Hour)
+	tables, err := bqdb.ListTablesModifiedBefore(dbmap, userID, project.ProjectID, snapshot.ID,
+		cutoffMs)
+	if err != nil {
+		return nil, err
+	}
+	reads, err := bqdb.ListTableReads(dbmap, userID, project.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	lastReads := map[string]int64{}
+	for _, read := range reads {
+		lastReads[read.ReferencedTable] = read.LastReadTimeMs
+	}
+
+	data := &templates.ColdStorageReport{
+		ProjectID: project.ProjectID,
+		Days:      days,
+		CutoffMs:  cutoffMs,
+		Loaded:    project.QueryJobsLoadedMs != 0,
+	}
+	data.HistoryStartMs, err = bqdb.OldestQueryJobTimeMs(dbmap, userID, project.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		lastRead := lastReads[project.ProjectID+":"+table.DatasetID+"."+table.TableID]
+		if lastRead >= cutoffMs {
+			continue
+		}
+		report := newTableReport(prices, project.ProjectID, snapshot, table)
+		data.Tables = append(data.Tables, &templates.ColdTable{
+			Table:              report,
+			LastModifiedTimeMs: table.LastModifiedTimeMs,
+			LastReadTimeMs:     lastRead,
+		})
+		data.Count++
+		data.Bytes += report.Bytes
+		data.DollarsPerMonth += report.DollarsPerMonth
+	}
+	// tables are sorted by size: keep that order for tables with the same cost
+	sort.SliceStable(data.Tables, func(i, j int) bool {
+		return data.Tables[i].Table.DollarsPerMonth > data.Tables[j].Table.DollarsPerMonth
+	})
+	if len(data.Tables) > maxColdResults {
+		data.Tables = data.Tables[:maxColdResults]
+		data.Truncated = true
+	}
+	return data, nil
+}
+
+// Lists the tables in the latest snapshot that were not read or written in the number of days
+// in the days parameter, which defaults to defaultColdDays.
+func (s *server) projectCold(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	days := defaultColdDays
+	daysParam := r.FormValue("days")
+	if daysParam != "" {
+		var err error
+		days, err = strconv.Atoi(daysParam)
+		if err != nil || days <= 0 {
+			return fmt.Errorf("bqcost: invalid days %#v", daysParam)
+		}
+	}
+
+	user, snapshot, err := getLatestSnapshot(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+	project, err := bqdb.GetProjectByID(s.dbmap, user.ID, projectID)
+	if err != nil {
+		return err
+	}
+	data, err := queryColdStorage(s.dbmap, s.prices, user.ID, project, snapshot, days)
+	if err != nil {
+		return err
+	}
+	return templates.ColdStorage(w, data)
+}
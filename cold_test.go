@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+)
+
+func TestProjectCold(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := testIdentity(u)
+	const day = int64(24 * time.Hour / time.Millisecond)
+	nowMs := 1000 * day
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: nowMs, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dbmap.Insert(&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID,
+		QueryJobsLoadedMs: nowMs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const gib = 1024 * 1024 * 1024
+	table := func(tableID string, bytes int64, lastModifiedMs int64) *bqdb.Table {
+		return &bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			TableID: tableID, Type: "TABLE", NumBytes: bytes, LastModifiedTimeMs: lastModifiedMs}
+	}
+	err = dbmap.Insert(
+		table("written", 100*gib, nowMs-day),
+		table("read", 100*gib, nowMs-200*day),
+		table("small", gib, nowMs-200*day),
+		table("large", 10*gib, nowMs-200*day),
+		table("readlongago", 5*gib, nowMs-200*day),
+		&bqdb.QueryJob{UserID: u.ID, ProjectID: "p", JobID: "a", CreationTimeMs: nowMs - 150*day},
+		&bqdb.QueryJobTable{UserID: u.ID, ProjectID: "p", JobID: "a",
+			ReferencedTable: "p:d.readlongago", CreationTimeMs: nowMs - 150*day},
+		&bqdb.QueryJob{UserID: u.ID, ProjectID: "p", JobID: "b", CreationTimeMs: nowMs - 2*day},
+		&bqdb.QueryJobTable{UserID: u.ID, ProjectID: "p", JobID: "b", ReferencedTable: "p:d.read",
+			CreationTimeMs: nowMs - 2*day},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := queryColdStorage(dbmap, s.prices, u.ID, project, snapshot, 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(data.Count == 3 && data.Bytes == 16*gib && !data.PartialHistory() &&
+		len(data.Tables) == 3 && data.Tables[0].Table.ID == "d.large" &&
+		data.Tables[1].Table.ID == "d.readlongago" && data.Tables[2].Table.ID == "d.small" &&
+		data.Tables[1].LastReadTimeMs == nowMs-150*day && data.Tables[2].LastReadTimeMs == 0) {
+		t.Error(data)
+	}
+
+	// readlongago is not cold, and the query history only covers the last 150 days
+	data, err = queryColdStorage(dbmap, s.prices, u.ID, project, snapshot, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(data.Count == 2 && data.PartialHistory()) {
+		t.Error(data)
+	}
+
+	w := httptest.NewRecorder()
+	err = s.projectCold(w, httptest.NewRequest("GET", "/projects/p/cold?days=180", nil), identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Not Read or Written in 180 Days", "d.large",
+		"tables may have been read before then"} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Error("missing", expected)
+		}
+	}
+	err = s.projectCold(httptest.NewRecorder(), httptest.NewRequest("GET", "/?days=0", nil),
+		identity, "p")
+	if err == nil {
+		t.Error("expected error for invalid days")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Returns the tables in a snapshot, keyed by "dataset.table".
+func querySnapshotTables(dbmap *gorp.DbMap, userID int64, projectID string, snapshotID int64) (
+	map[string]*bqdb.Table, error) {
+
+	quotedTable, err := bqdb.QuotedTableForQuery(dbmap, bqdb.Table{})
+	if err != nil {
+		return nil, err
+	}
+	ifaces, err := dbmap.Select((*bqdb.Table)(nil),
+		"SELECT DatasetID, TableID, Location, NumBytes, NumLongTermBytes, NumRows,"+
+			" NumActivePhysicalBytes, NumLongTermPhysicalBytes, StorageBillingModel FROM "+quotedTable+
+			" WHERE UserID=? AND ProjectID=? AND SnapshotID=?",
+		userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]*bqdb.Table, len(ifaces))
+	for _, iface := range ifaces {
+		table := iface.(*bqdb.Table)
+		tables[table.DatasetID+"."+table.TableID] = table
+	}
+	return tables, nil
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Sorts tables by the size of the change, largest first, and returns at most maxTopResults.
+func largestTableDiffs(tables []*templates.TableDiff) []*templates.TableDiff {
+	sort.Slice(tables, func(i, j int) bool {
+		bi := abs(tables[i].Change.Bytes())
+		bj := abs(tables[j].Change.Bytes())
+		if bi != bj {
+			return bi > bj
+		}
+		return tables[i].ID < tables[j].ID
+	})
+	if len(tables) > maxTopResults {
+		tables = tables[:maxTopResults]
+	}
+	return tables
+}
+
+// Compares the tables in two snapshots of a project. Each snapshot's costs use the prices when
+// it was scraped.
+func diffSnapshots(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64, projectID string,
+	from *bqdb.Snapshot, to *bqdb.Snapshot) (*templates.SnapshotDiff, error) {
+
+	oldTables, err := querySnapshotTables(dbmap, userID, projectID, from.ID)
+	if err != nil {
+		return nil, err
+	}
+	newTables, err := querySnapshotTables(dbmap, userID, projectID, to.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &templates.SnapshotDiff{ProjectID: projectID}
+	datasets := map[string]*templates.DatasetDiff{}
+	getDataset := func(id string) *templates.DatasetDiff {
+		dataset := datasets[id]
+		if dataset == nil {
+			dataset = &templates.DatasetDiff{ID: id}
+			datasets[id] = dataset
+		}
+		return dataset
+	}
+
+	var created, deleted, changed []*templates.TableDiff
+	for id, table := range newTables {
+		cost := tableStorageCost(prices, table, to.TimeMs).Total()
+		dataset := getDataset(table.DatasetID)
+		dataset.Change.NewBytes += table.NumBytes
+		dataset.Change.NewLongTermBytes += table.NumLongTermBytes
+		dataset.Change.NewRows += table.NumRows
+		dataset.Change.NewDollarsPerMonth += cost
+
+		diff := &templates.TableDiff{ID: id, Change: templates.StorageChange{
+			NewBytes: table.NumBytes, NewLongTermBytes: table.NumLongTermBytes,
+			NewRows: table.NumRows, NewDollarsPerMonth: cost}}
+		oldTable := oldTables[id]
+		if oldTable == nil {
+			dataset.Created++
+			created = append(created, diff)
+			continue
+		}
+		diff.Change.OldBytes = oldTable.NumBytes
+		diff.Change.OldLongTermBytes = oldTable.NumLongTermBytes
+		diff.Change.OldRows = oldTable.NumRows
+		diff.Change.OldDollarsPerMonth = tableStorageCost(prices, oldTable, from.TimeMs).Total()
+		// moving to long-term storage changes the cost but not the size
+		if diff.Change.Bytes() != 0 || diff.Change.Rows() != 0 ||
+			diff.Change.OldLongTermBytes != diff.Change.NewLongTermBytes {
+			changed = append(changed, diff)
+		}
+	}
+	for id, table := range oldTables {
+		cost := tableStorageCost(prices, table, from.TimeMs).Total()
+		dataset := getDataset(table.DatasetID)
+		dataset.Change.OldBytes += table.NumBytes
+		dataset.Change.OldLongTermBytes += table.NumLongTermBytes
+		dataset.Change.OldRows += table.NumRows
+		dataset.Change.OldDollarsPerMonth += cost
+
+		if newTables[id] == nil {
+			dataset.Deleted++
+			deleted = append(deleted, &templates.TableDiff{ID: id, Change: templates.StorageChange{
+				OldBytes: table.NumBytes, OldLongTermBytes: table.NumLongTermBytes,
+				OldRows: table.NumRows, OldDollarsPerMonth: cost}})
+		}
+	}
+
+	for _, dataset := range datasets {
+		data.Total.OldBytes += dataset.Change.OldBytes
+		data.Total.NewBytes += dataset.Change.NewBytes
+		data.Total.OldLongTermBytes += dataset.Change.OldLongTermBytes
+		data.Total.NewLongTermBytes += dataset.Change.NewLongTermBytes
+		data.Total.OldRows += dataset.Change.OldRows
+		data.Total.NewRows += dataset.Change.NewRows
+		data.Total.OldDollarsPerMonth += dataset.Change.OldDollarsPerMonth
+		data.Total.NewDollarsPerMonth += dataset.Change.NewDollarsPerMonth
+		data.Datasets = append(data.Datasets, dataset)
+	}
+	sort.Slice(data.Datasets, func(i, j int) bool {
+		bi := abs(data.Datasets[i].Change.Bytes())
+		bj := abs(data.Datasets[j].Change.Bytes())
+		if bi != bj {
+			return bi > bj
+		}
+		return data.Datasets[i].ID < data.Datasets[j].ID
+	})
+
+	data.CreatedCount = len(created)
+	data.DeletedCount = len(deleted)
+	data.Created = largestTableDiffs(created)
+	data.Deleted = largestTableDiffs(deleted)
+	data.Changed = largestTableDiffs(changed)
+
+	data.From = &templates.SnapshotUsage{ID: from.ID, TimeMs: from.TimeMs,
+		Bytes: data.Total.OldBytes, LongTermBytes: data.Total.OldLongTermBytes,
+		DollarsPerMonth: data.Total.OldDollarsPerMonth}
+	data.To = &templates.SnapshotUsage{ID: to.ID, TimeMs: to.TimeMs,
+		Bytes: data.Total.NewBytes, LongTermBytes: data.Total.NewLongTermBytes,
+		DollarsPerMonth: data.Total.NewDollarsPerMonth}
+	return data, nil
+}
+
+// Returns the snapshot with the ID in param, or defaultSnapshot if param is empty.
+func findSnapshot(snapshots []*bqdb.Snapshot, param string, defaultSnapshot *bqdb.Snapshot) (
+	*bqdb.Snapshot, error) {
+
+	if param == "" {
+		return defaultSnapshot, nil
+	}
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.ID == id {
+			return snapshot, nil
+		}
+	}
+	return nil, fmt.Errorf("bqcost: snapshot %d does not exist", id)
+}
+
+// Shows the changes between the snapshots in the from and to parameters. By default, compares
+// the last two snapshots. Returns JSON if the format parameter is json.
+func (s *server) projectDiff(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	user, _, err := getExistingProject(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+	snapshots, err := bqdb.ListCompleteSnapshots(s.dbmap, user.ID, projectID)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) < 2 {
+		return fmt.Errorf("bqcost: project %s needs two snapshots to compare; has %d",
+			projectID, len(snapshots))
+	}
+
+	from, err := findSnapshot(snapshots, r.FormValue("from"), snapshots[len(snapshots)-2])
+	if err != nil {
+		return err
+	}
+	to, err := findSnapshot(snapshots, r.FormValue("to"), snapshots[len(snapshots)-1])
+	if err != nil {
+		return err
+	}
+	log.Printf("projectDiff %s snapshots %d to %d", projectID, from.ID, to.ID)
+
+	data, err := diffSnapshots(s.dbmap, s.prices, user.ID, projectID, from, to)
+	if err != nil {
+		return err
+	}
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(data)
+	}
+
+	data.Snapshots = make([]*templates.SnapshotUsage, len(snapshots))
+	for i, snapshot := range snapshots {
+		data.Snapshots[i] = &templates.SnapshotUsage{ID: snapshot.ID, TimeMs: snapshot.TimeMs}
+	}
+	return templates.Diff(w, data)
+}
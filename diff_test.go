@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+)
+
+func TestProjectDiff(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := testIdentity(u)
+	older := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	p := &bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: 1}
+	err = dbmap.Insert(older, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one snapshot: nothing to compare
+	err = s.projectDiff(httptest.NewRecorder(), httptest.NewRequest("GET", "/projects/p/diff", nil),
+		identity, "p")
+	if err == nil || !strings.Contains(err.Error(), "two snapshots") {
+		t.Error(err)
+	}
+
+	// d.a grows, d.b is deleted, e.c is created, d.same does not change
+	newer := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 2000, Complete: true}
+	err = dbmap.Insert(newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []*bqdb.Table{
+		{UserID: u.ID, ProjectID: "p", SnapshotID: older.ID, DatasetID: "d", TableID: "a",
+			NumBytes: 10, NumRows: 1},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: older.ID, DatasetID: "d", TableID: "b",
+			NumBytes: 20, NumRows: 2},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: older.ID, DatasetID: "d", TableID: "same",
+			NumBytes: 7, NumRows: 7},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: newer.ID, DatasetID: "d", TableID: "a",
+			NumBytes: 100, NumRows: 5},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: newer.ID, DatasetID: "d", TableID: "same",
+			NumBytes: 7, NumRows: 7},
+		{UserID: u.ID, ProjectID: "p", SnapshotID: newer.ID, DatasetID: "e", TableID: "c",
+			NumBytes: 5, NumRows: 3},
+	} {
+		err = dbmap.Insert(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := diffSnapshots(dbmap, s.prices, u.ID, "p", older, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(data.From.Bytes == 37 && data.To.Bytes == 112 && data.Total.Bytes() == 75 &&
+		data.Total.Rows() == 5) {
+		t.Error(data.From, data.To, data.Total)
+	}
+	if !(len(data.Datasets) == 2 && data.Datasets[0].ID == "d" &&
+		data.Datasets[0].Change.Bytes() == 70 && data.Datasets[0].Created == 0 &&
+		data.Datasets[0].Deleted == 1 && data.Datasets[1].ID == "e" &&
+		data.Datasets[1].Change.Bytes() == 5 && data.Datasets[1].Created == 1) {
+		t.Error(data.Datasets)
+	}
+	if !(data.CreatedCount == 1 && data.Created[0].ID == "e.c" &&
+		data.DeletedCount == 1 && data.Deleted[0].ID == "d.b" &&
+		data.Deleted[0].Change.Bytes() == -20) {
+		t.Error(data.Created, data.Deleted)
+	}
+	if !(len(data.Changed) == 1 && data.Changed[0].ID == "d.a" &&
+		data.Changed[0].Change.Bytes() == 90 && data.Changed[0].Change.Rows() == 4) {
+		t.Error(data.Changed)
+	}
+
+	// the page compares the last two snapshots by default
+	w := httptest.NewRecorder()
+	err = s.projectDiff(w, httptest.NewRequest("GET", "/projects/p/diff", nil), identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "&#43;90 B") || !strings.Contains(body, "-20 B") {
+		t.Error(body)
+	}
+
+	// the API returns the computed changes
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/projects/p/diff?format=json&from="+
+		strconv.FormatInt(newer.ID, 10)+"&to="+strconv.FormatInt(older.ID, 10), nil)
+	err = s.projectDiff(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output struct {
+		Total struct {
+			Bytes           int64
+			DollarsPerMonth float64
+		}
+		Changed []struct {
+			ID     string
+			Change struct{ OldBytes, NewBytes, Bytes int64 }
+		}
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &output)
+	if err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+	if !(output.Total.Bytes == -75 && output.Total.DollarsPerMonth < 0 &&
+		len(output.Changed) == 1 && output.Changed[0].ID == "d.a" &&
+		output.Changed[0].Change.OldBytes == 100 && output.Changed[0].Change.Bytes == -90) {
+		t.Error(w.Body.String())
+	}
+
+	// snapshots must exist
+	r = httptest.NewRequest("GET", "/projects/p/diff?from=999", nil)
+	err = s.projectDiff(httptest.NewRecorder(), r, identity, "p")
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Error(err)
+	}
+}
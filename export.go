@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/xlsx"
+)
+
+// Columns of the exported table inventory.
+var exportColumns = []string{"dataset_id", "table_id", "type", "location", "bytes",
+	"long_term_bytes", "rows", "creation_time", "last_modified_time", "active_dollars_per_month",
+	"long_term_dollars_per_month", "dollars_per_month"}
+
+// Writes exported tables in one file format. Tables are written in ID order.
+type tableExporter interface {
+	WriteTable(table *bqdb.Table, values []interface{}) error
+	Close() error
+}
+
+type exportFormat struct {
+	contentType string
+	extension   string
+	newExporter func(w io.Writer) (tableExporter, error)
+}
+
+// Formats for the export page format parameter.
+var exportFormats = map[string]*exportFormat{
+	"csv":  {"text/csv; charset=utf-8", ".csv", newCSVExporter},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx", newXLSXExporter},
+}
+
+// Returns the values of exportColumns for table.
+func exportValues(table *bqdb.Table, cost pricing.StorageCost) []interface{} {
+	return []interface{}{table.DatasetID, table.TableID, table.Type, table.Location,
+		table.NumBytes, table.NumLongTermBytes, table.NumRows, formatExportTime(table.CreationTimeMs),
+		formatExportTime(table.LastModifiedTimeMs), cost.Active, cost.LongTerm, cost.Total()}
+}
+
+// Returns timeMs formatted as RFC 3339, or the empty string if it is not set.
+func formatExportTime(timeMs int64) string {
+	if timeMs == 0 {
+		return ""
+	}
+	return time.Unix(0, timeMs*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+// Writes all tables to one CSV file with a header row.
+type csvExporter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVExporter(w io.Writer) (tableExporter, error) {
+	exporter := &csvExporter{csv.NewWriter(w), make([]string, len(exportColumns))}
+	err := exporter.w.Write(exportColumns)
+	if err != nil {
+		return nil, err
+	}
+	return exporter, nil
+}
+
+func (e *csvExporter) WriteTable(table *bqdb.Table, values []interface{}) error {
+	for i, value := range values {
+		switch v := value.(type) {
+		case string:
+			e.record[i] = v
+		case int64:
+			e.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Errorf("bqcost: unsupported export value type %T", value)
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// Writes an Excel workbook with a sheet for each dataset.
+type xlsxExporter struct {
+	w         *xlsx.Writer
+	datasetID string
+}
+
+func newXLSXExporter(w io.Writer) (tableExporter, error) {
+	writer, err := xlsx.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxExporter{w: writer}, nil
+}
+
+func (e *xlsxExporter) WriteTable(table *bqdb.Table, values []interface{}) error {
+	if table.DatasetID != e.datasetID {
+		e.datasetID = table.DatasetID
+		_, err := e.w.AddSheet(table.DatasetID)
+		if err != nil {
+			return err
+		}
+		header := make([]interface{}, len(exportColumns))
+		for i, column := range exportColumns {
+			header[i] = column
+		}
+		err = e.w.WriteRow(header...)
+		if err != nil {
+			return err
+		}
+	}
+	return e.w.WriteRow(values...)
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.w.Close()
+}
+
+// Writes every table in snapshot with its cost to w, without loading all tables in
+// memory.
+func (s *server) exportTables(w io.Writer, format *exportFormat, userID int64,
+	snapshot *bqdb.Snapshot) error {
+
+	exporter, err := format.newExporter(w)
+	if err != nil {
+		return err
+	}
+	err = bqdb.EachTable(s.dbmap, userID, snapshot.ProjectID, snapshot.ID,
+		func(table *bqdb.Table) error {
+			cost := tableStorageCost(s.prices, table, snapshot.TimeMs)
+			return exporter.WriteTable(table, exportValues(table, cost))
+		})
+	if err != nil {
+		return err
+	}
+	return exporter.Close()
+}
+
+// Downloads the full table inventory of the latest snapshot. The format parameter is one of the
+// keys of exportFormats, and defaults to csv.
+func (s *server) projectExport(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	formatName := r.FormValue("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format := exportFormats[formatName]
+	if format == nil {
+		return fmt.Errorf("bqcost: invalid format %#v", formatName)
+	}
+	user, snapshot, err := getLatestSnapshot(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+
+	// domain-scoped project IDs contain :
+	filename := strings.Replace(projectID, ":", "_", -1) + "-" +
+		time.Unix(0, snapshot.TimeMs*int64(time.Millisecond)).UTC().Format("20060102") +
+		format.extension
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	// errors after this point truncate the download
+	return s.exportTables(w, format, user.ID, snapshot)
+}
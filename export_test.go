@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+)
+
+func TestProjectExport(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := testIdentity(u)
+	// 2024-01-01
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1704067200000, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const gib = 1024 * 1024 * 1024
+	table := func(datasetID string, tableID string) *bqdb.Table {
+		return &bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID,
+			DatasetID: datasetID, TableID: tableID, Type: "TABLE", Location: "US", NumBytes: 2 * gib,
+			NumLongTermBytes: gib, NumRows: 10, CreationTimeMs: snapshot.TimeMs}
+	}
+	err = dbmap.Insert(
+		&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID},
+		table("d", "b"), table("d", "a"), table("e", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/projects/p/export", nil)
+	w := httptest.NewRecorder()
+	err = s.projectExport(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") &&
+		w.Header().Get("Content-Disposition") == "attachment; filename=p-20240101.csv") {
+		t.Error(w.Header())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(records) == 4 && reflect.DeepEqual(records[0], exportColumns) &&
+		reflect.DeepEqual(records[1], []string{"d", "a", "TABLE", "US", "2147483648", "1073741824",
+			"10", "2024-01-01T00:00:00Z", "", "0.02", "0.01", "0.03"}) &&
+		records[2][1] == "b" && records[3][1] == "c") {
+		t.Error(records)
+	}
+
+	r = httptest.NewRequest("GET", "/projects/p/export?format=xlsx", nil)
+	w = httptest.NewRecorder()
+	err = s.projectExport(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range reader.File {
+		fr, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadAll(fr)
+		fr.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(contents)
+	}
+	if !(strings.Contains(files["xl/workbook.xml"], `<sheet name="d"`) &&
+		strings.Contains(files["xl/workbook.xml"], `<sheet name="e"`) &&
+		strings.Count(files["xl/worksheets/sheet1.xml"], "<row") == 3 &&
+		strings.Count(files["xl/worksheets/sheet2.xml"], "<row") == 2) {
+		t.Error(files)
+	}
+
+	r = httptest.NewRequest("GET", "/projects/p/export?format=pdf", nil)
+	err = s.projectExport(httptest.NewRecorder(), r, identity, "p")
+	if err == nil {
+		t.Error("expected error for invalid format")
+	}
+}
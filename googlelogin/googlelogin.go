@@ -2,6 +2,7 @@ package googlelogin
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
@@ -34,45 +35,72 @@ const cookieExpiration = 24 * 30 * time.Hour
 var ErrNotAuthenticated = errors.New("googlelogin: not authenticated")
 var ErrTokenExpired = errors.New("googlelogin: oauth2 token expired")
 
-// HandlerWithToken handles an HTTP request with a required OAuth2 token. This
-// makes it explicit that this handler does not function without authentication.
-type HandlerWithToken func(w http.ResponseWriter, r *http.Request, token *oauth2.Token)
-
-// If you request email or profile scopes, you will get an id_token in the response with
-// details about the authenticated user. Otherwise, you just get an opaque token that you cannot
-// connect to other tokens in the future.
-// https://developers.google.com/identity/protocols/googlescopes#oauth2v2
-// "Know who you are on Google" and "View your email address"
-// hitting tokeninfo gives you scopes https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/plus.me
-// const UserEmailScope = "email"
-// "View your basic profile info"
-// tokeninfo does not include email and has scope: https://www.googleapis.com/auth/userinfo.profile
-// const UserProfileScope = "profile"
+// Identity is an authenticated Google account and its OAuth2 token.
+type Identity struct {
+	// Google's unique ID for the account, which never changes: the ID token's sub claim.
+	Subject string
+	// Empty if Google has not verified the address.
+	Email string
+	Token *oauth2.Token
+}
+
+// HandlerWithIdentity handles an HTTP request with a required identity. This makes it explicit
+// that this handler does not function without authentication.
+type HandlerWithIdentity func(w http.ResponseWriter, r *http.Request, identity *Identity)
 
 // Note: If you include "localhost" in the redirect_uri, Google may tell the user that you will "have offline access"
 // http://stackoverflow.com/a/31242454/413438
 
 // Authenticator obtains access tokens from Google on behalf of an end user web browser.
 type Authenticator struct {
-	oauthConfig   oauth2.Config
-	securecookies *securecookie.SecureCookie
-	noAuthPath    string
+	oauthConfig oauth2.Config
+	// cookies are encoded with the first codec, and decoded with any of them
+	codecs      []securecookie.Codec
+	noAuthPath  string
+	idTokenKeys *keyCache
+	revokeURL   string
+	// optional: stores sessions on the server instead of the cookie
+	sessions SessionStore
+
+	// offline access only: stores refresh tokens encrypted with tokenCipher
+	tokens      TokenStore
+	tokenCipher cipher.AEAD
 }
 
 // New creates a new Authenticator for authenticating users. The clientID, clientSecret, and
 // redirectURL must registered with Google. The scopes list the permissions required by this
 // application. The browser will be redirected to noAuthPath when HandleWithToken and they are
-// not authenticated.
+// not authenticated. Cookies are encoded with the first of codecs, and decoded with any of them,
+// so keys can be rotated by adding the new key first: see securecookie.CodecsFromPairs. Cookies
+// decoded with an old key are encoded again with the new key.
 func New(clientID string, clientSecret string, redirectURL string, scopes []string,
-	securecookies *securecookie.SecureCookie, noAuthPath string, mux *http.ServeMux) (
+	codecs []securecookie.Codec, noAuthPath string, mux *http.ServeMux) (
 	*Authenticator, error) {
 
+	if len(codecs) == 0 {
+		return nil, errors.New("googlelogin: at least one cookie codec is required")
+	}
+
 	// parse the redirect path and register it with mux
 	parsedRedirect, err := url.Parse(redirectURL)
 	if err != nil {
 		return nil, err
 	}
 
+	// identify users with the id_token
+	scopes = append([]string(nil), scopes...)
+	for _, scope := range []string{OpenIDScope, EmailScope} {
+		found := false
+		for _, existing := range scopes {
+			if existing == scope {
+				found = true
+			}
+		}
+		if !found {
+			scopes = append(scopes, scope)
+		}
+	}
+
 	// TODO: Validate parameters
 	auth := &Authenticator{
 		oauth2.Config{
@@ -82,30 +110,48 @@ func New(clientID string, clientSecret string, redirectURL string, scopes []stri
 			Scopes:       scopes,
 			RedirectURL:  redirectURL,
 		},
-		securecookies,
-		noAuthPath}
+		codecs,
+		noAuthPath,
+		&keyCache{url: googleCertsURL},
+		googleRevokeURL,
+		nil,
+		nil,
+		nil}
 
 	// TODO: Allow users to manually invoke the callback?
 	mux.HandleFunc(parsedRedirect.Path, auth.HandleCallback)
 	return auth, nil
 }
 
-func (a *Authenticator) Client(ctx context.Context, token *oauth2.Token) *http.Client {
-	return a.oauthConfig.Client(ctx, token)
+// Client returns an HTTP client that authenticates requests as identity. With offline access,
+// the token is refreshed when it expires.
+func (a *Authenticator) Client(ctx context.Context, identity *Identity) *http.Client {
+	source, err := a.TokenSource(ctx, identity)
+	if err != nil {
+		log.Printf("googlelogin: error: not refreshing token: %s", err.Error())
+		return a.oauthConfig.Client(ctx, identity.Token)
+	}
+	return oauth2.NewClient(ctx, source)
 }
 
 // Stores the user's Google OAuth access token and/or the state for an oauth login
 type authState struct {
 	// Token is gob serializable
 	Token *oauth2.Token
+	// the user's identity from the id_token; see Identity
+	Subject string
+	Email   string
 	// unique state to validate oauth requests
 	State []byte
 	// destination path to redirect to after the authentication is complete
 	Destination string
+	// with a SessionStore, the cookie only contains this ID; see EnableSessionStore
+	SessionID string
 }
 
-// Returns the current session, or a new zero session.
-func (a *Authenticator) getSession(r *http.Request) *authState {
+// Returns the session in the cookie, or a new zero session. Returns true if the cookie was
+// encoded with an old key and should be encoded again.
+func (a *Authenticator) decodeCookie(r *http.Request) (*authState, bool) {
 	cookie, err := r.Cookie(cookieName)
 	if err != nil {
 		if err != http.ErrNoCookie {
@@ -113,20 +159,42 @@ func (a *Authenticator) getSession(r *http.Request) *authState {
 			log.Printf("googlelogin: error: ignoring unexpected error getting cookie: %s", err.Error())
 		}
 		// no session: return an empty session
-		return &authState{}
+		return &authState{}, false
 	}
 
-	session := &authState{}
-	err = a.securecookies.Decode(cookie.Name, cookie.Value, session)
-	if err != nil {
-		log.Printf("googlelogin: error: ignoring invalid session cookie: %s", err.Error())
+	for i, codec := range a.codecs {
+		session := &authState{}
+		err = codec.Decode(cookie.Name, cookie.Value, session)
+		if err == nil {
+			return session, i > 0
+		}
+	}
+	log.Printf("googlelogin: error: ignoring invalid session cookie: %s", err.Error())
+	return &authState{}, false
+}
+
+// Returns the session for the cookie's session ID, or the cookie's session without a session
+// store.
+func (a *Authenticator) loadSession(session *authState) *authState {
+	if session.SessionID != "" {
+		return a.loadStoredSession(session)
+	}
+	if a.sessions != nil && session.Token != nil {
+		// saved in the cookie before the session store was enabled: it cannot be listed or revoked,
+		// so the user must log in again
 		return &authState{}
 	}
 	return session
 }
 
-func makeCookie(securecookies *securecookie.SecureCookie, session *authState) (*http.Cookie, error) {
-	serialized, err := securecookies.Encode(cookieName, session)
+// Returns the current session, or a new zero session.
+func (a *Authenticator) getSession(r *http.Request) *authState {
+	session, _ := a.decodeCookie(r)
+	return a.loadSession(session)
+}
+
+func makeCookie(codec securecookie.Codec, session *authState) (*http.Cookie, error) {
+	serialized, err := codec.Encode(cookieName, session)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +203,8 @@ func makeCookie(securecookies *securecookie.SecureCookie, session *authState) (*
 		Path:     "/",
 		Expires:  time.Now().Add(cookieExpiration),
 		HttpOnly: true,
+		// not sent with POSTs from other sites; Strict would not be sent on the redirect from Google
+		SameSite: http.SameSiteLaxMode,
 		Value:    serialized,
 		// TODO: Set this based on an option
 		// Secure:   true,
@@ -143,7 +213,7 @@ func makeCookie(securecookies *securecookie.SecureCookie, session *authState) (*
 }
 
 func (a *Authenticator) saveSession(w http.ResponseWriter, session *authState) error {
-	cookie, err := makeCookie(a.securecookies, session)
+	cookie, err := makeCookie(a.codecs[0], session)
 	if err != nil {
 		return err
 	}
@@ -159,6 +229,7 @@ func deleteSession(w http.ResponseWriter) {
 		// expires must be non-zero to get output
 		Expires:  time.Unix(1, 0),
 		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
 
@@ -171,12 +242,18 @@ func (a *Authenticator) Start(w http.ResponseWriter, r *http.Request, destinatio
 		return fmt.Errorf("googlelogin: destinationPath must be absolute")
 	}
 
+	// the cookie is replaced: the old session cannot be used again
+	err := a.deleteStoredSession(r)
+	if err != nil {
+		log.Printf("googlelogin: error: deleting replaced session: %s", err.Error())
+	}
+
 	// generate state to prevent CSRF: https://tools.ietf.org/html/rfc6749#section-10.12
 	state, err := makeState()
 	if err != nil {
 		return err
 	}
-	session := &authState{nil, state, destinationPath}
+	session := &authState{State: state, Destination: destinationPath}
 	err = a.saveSession(w, session)
 	if err != nil {
 		return err
@@ -187,8 +264,13 @@ func (a *Authenticator) Start(w http.ResponseWriter, r *http.Request, destinatio
 
 	// AccessTypeOnline only gives us an access token without a refresh token (lower security risk)
 	// use "auto" to get no prompt on "refresh"
-	url := a.oauthConfig.AuthCodeURL(stateSerialized, oauth2.AccessTypeOnline,
-		oauth2.SetAuthURLParam("approval_prompt", "auto"))
+	options := []oauth2.AuthCodeOption{oauth2.AccessTypeOnline,
+		oauth2.SetAuthURLParam("approval_prompt", "auto")}
+	if a.tokens != nil {
+		// Google only returns a refresh token when the user approves access
+		options = []oauth2.AuthCodeOption{oauth2.AccessTypeOffline, oauth2.ApprovalForce}
+	}
+	url := a.oauthConfig.AuthCodeURL(stateSerialized, options...)
 	http.Redirect(w, r, url, http.StatusFound)
 	return nil
 }
@@ -243,11 +325,43 @@ func (a *Authenticator) handleCallbackError(w http.ResponseWriter, r *http.Reque
 		deleteSession(w)
 		return fmt.Errorf("googlelogin: error exchanging code %s", err.Error())
 	}
-	// TODO: If we requested email or profile the may contain .Extra("id_token") but it is not
-	// serialized via gob. Read it and save it seperately?
+	// the id_token is not serialized with the token: save the identity separately
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		deleteSession(w)
+		return errors.New("googlelogin: token response is missing id_token")
+	}
+	claims, err := verifyIDToken(a.idTokenKeys, a.oauthConfig.ClientID, idToken, time.Now())
+	if err != nil {
+		deleteSession(w)
+		return err
+	}
+	email := claims.Email
+	if !claims.EmailVerified {
+		email = ""
+	}
+
+	if a.tokens != nil {
+		err = a.storeToken(claims.Subject, token)
+		if err != nil {
+			deleteSession(w)
+			return fmt.Errorf("googlelogin: error storing token: %s", err.Error())
+		}
+		// the refresh token is only stored on the server
+		sessionToken := *token
+		sessionToken.RefreshToken = ""
+		token = &sessionToken
+	}
 
 	// save the token in the session, clear all temp variables
-	session = &authState{Token: token}
+	session = &authState{Token: token, Subject: claims.Subject, Email: email}
+	if a.sessions != nil {
+		session, err = a.newStoredSession(token, claims.Subject, email)
+		if err != nil {
+			deleteSession(w)
+			return fmt.Errorf("googlelogin: error storing session: %s", err.Error())
+		}
+	}
 	err = a.saveSession(w, session)
 	if err != nil {
 		deleteSession(w)
@@ -269,9 +383,29 @@ func (a *Authenticator) GetToken(r *http.Request) (*oauth2.Token, error) {
 	return session.Token, nil
 }
 
-func (a *Authenticator) Handler(handler HandlerWithToken) http.Handler {
+// Returns the Identity corresponding to this request. Returns ErrTokenExpired if the token has
+// expired and cannot be refreshed.
+func (a *Authenticator) GetIdentity(r *http.Request) (*Identity, error) {
+	session := a.getSession(r)
+	// sessions from before identities were saved must log in again
+	if session.Token == nil || session.Subject == "" {
+		return nil, ErrNotAuthenticated
+	}
+	identity := session.identity()
+	if !identity.Token.Valid() && !a.canRefresh(identity) {
+		return nil, ErrTokenExpired
+	}
+	return identity, nil
+}
+
+func (s *authState) identity() *Identity {
+	return &Identity{Subject: s.Subject, Email: s.Email, Token: s.Token}
+}
+
+func (a *Authenticator) Handler(handler HandlerWithIdentity) http.Handler {
 	httpHandleFunc := func(w http.ResponseWriter, r *http.Request) {
-		session := a.getSession(r)
+		cookieSession, oldKey := a.decodeCookie(r)
+		session := a.loadSession(cookieSession)
 		if session.Token == nil {
 			// no authentication: inform the user that they need to log in by redirecting;
 			// TODO: save their original destination and redirect there, ideally in a query parameter
@@ -280,7 +414,7 @@ func (a *Authenticator) Handler(handler HandlerWithToken) http.Handler {
 			http.Redirect(w, r, a.noAuthPath, http.StatusFound)
 			return
 		}
-		if !session.Token.Valid() {
+		if session.Subject == "" || (!session.Token.Valid() && !a.canRefresh(session.identity())) {
 			// user previously did authenticate: try to automatically "refresh"
 			log.Printf("googlelogin: expired token; attempting to renew")
 			err := a.Start(w, r, r.URL.String())
@@ -291,12 +425,30 @@ func (a *Authenticator) Handler(handler HandlerWithToken) http.Handler {
 			return
 		}
 
+		if oldKey {
+			// keys were rotated: encode the same cookie with the new key
+			err := a.saveSession(w, cookieSession)
+			if err != nil {
+				log.Printf("googlelogin: error: ignoring error re-encoding cookie: %s", err.Error())
+			}
+		}
+
 		// looks valid: execute the real handler
-		handler(w, r, session.Token)
+		handler(w, r, session.identity())
 	}
 	return http.HandlerFunc(httpHandleFunc)
 }
 
+// Returns true if an expired token can be refreshed with a stored refresh token.
+func (a *Authenticator) canRefresh(identity *Identity) bool {
+	stored, err := a.storedToken(identity)
+	if err != nil {
+		log.Printf("googlelogin: error: loading stored token: %s", err.Error())
+		return false
+	}
+	return stored != nil && stored.RefreshToken != ""
+}
+
 // see https://tools.ietf.org/html/rfc6749#section-10.12
 func makeState() ([]byte, error) {
 	state := make([]byte, stateLength)
@@ -1,10 +1,16 @@
 package googlelogin
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -26,28 +32,74 @@ func (h *harness) sessionFromResponse(w *httptest.ResponseRecorder) *authState {
 		panic("invalid cookies")
 	}
 	session := &authState{}
-	err := h.auth.securecookies.Decode(cookies[0].Name, cookies[0].Value, session)
+	err := h.securecookies.Decode(cookies[0].Name, cookies[0].Value, session)
 	if err != nil {
 		panic(err)
 	}
 	return session
 }
 
+// Signs test ID tokens. Generating keys is slow, so all tests share one key.
+var testIDTokenKey = mustGenerateKey()
+
+const testIDTokenKeyID = "testkey"
+
+func mustGenerateKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Returns an ID token with claims signed by testIDTokenKey.
+func signIDToken(claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": testIDTokenKeyID})
+	if err != nil {
+		panic(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	hashed := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, testIDTokenKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Returns valid claims for the test harness's client ID.
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{"iss": "https://accounts.google.com", "aud": "clientID",
+		"sub": "subject", "email": "user@example.com", "email_verified": true,
+		"exp": time.Now().Add(time.Hour).Unix()}
+}
+
 func setupTestHarness() *harness {
 	hashKey := make([]byte, cookieHashKeyLength)
 	encryptionKey := make([]byte, cookieEncryptionKeyLength)
 	securecookies := securecookie.New(hashKey, encryptionKey)
 	mux := http.NewServeMux()
 	auth, err := New("clientID", "clientSecret", "https://example.com/redirect", []string{"scope"},
-		securecookies, "/noauth", mux)
+		[]securecookie.Codec{securecookies}, "/noauth", mux)
 	if err != nil {
 		panic(err)
 	}
+	// verify ID tokens without fetching Google's keys
+	auth.idTokenKeys.keys = map[string]*rsa.PublicKey{testIDTokenKeyID: &testIDTokenKey.PublicKey}
 	return &harness{securecookies, auth, mux}
 }
 
 func TestNew(t *testing.T) {
 	h := setupTestHarness()
+	// identity scopes are added
+	if !reflect.DeepEqual(h.auth.oauthConfig.Scopes, []string{"scope", OpenIDScope, EmailScope}) {
+		t.Error(h.auth.oauthConfig.Scopes)
+	}
 	// check that the mux handles the redirect
 	r := httptest.NewRequest("GET", "/redirect", nil)
 	_, pattern := h.mux.Handler(r)
@@ -135,14 +187,35 @@ func TestCallback(t *testing.T) {
 		}
 	}
 
-	// successful request: start a server to exchange the code; stolen from oauth2_test.go
+	// start a server to exchange the code; stolen from oauth2_test.go
+	idToken := ""
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"access_token": "90d", "scope": "user", "token_type": "bearer", "expires": 100}`))
+		response := map[string]interface{}{"access_token": "90d", "scope": "user",
+			"token_type": "bearer", "expires": 100}
+		if idToken != "" {
+			response["id_token"] = idToken
+		}
+		json.NewEncoder(w).Encode(response)
 	}))
 	defer ts.Close()
 	h.auth.oauthConfig.Endpoint.TokenURL = ts.URL + "/token"
 
+	// the token response must have a valid id_token
+	wrongClient := validClaims()
+	wrongClient["aud"] = "otherClientID"
+	for _, idToken = range []string{"", signIDToken(wrongClient)} {
+		w, err = doCallback(map[string]string{"state": stateSerialized, "code": "code"}, validCookie)
+		if err == nil {
+			t.Error("expected error for id_token", idToken)
+		}
+		if !hasExpiredCookie(w) {
+			t.Error(w)
+		}
+	}
+
+	// successful request
+	idToken = signIDToken(validClaims())
 	w, err = doCallback(map[string]string{"state": stateSerialized, "code": "code"}, validCookie)
 	if err != nil {
 		t.Error(err)
@@ -172,6 +245,9 @@ func TestCallback(t *testing.T) {
 	if session.Token == nil {
 		t.Error(session.Token)
 	}
+	if !(session.Subject == "subject" && session.Email == "user@example.com") {
+		t.Error(session.Subject, session.Email)
+	}
 
 	r = httptest.NewRequest("GET", "/foo", nil)
 	r.Header.Set("Cookie", finalCookie.String())
@@ -182,6 +258,10 @@ func TestCallback(t *testing.T) {
 	if token.Type() != "Bearer" {
 		t.Error(token.Type(), token.TokenType, token)
 	}
+	identity, err := h.auth.GetIdentity(r)
+	if !(err == nil && identity.Subject == "subject" && identity.Token.AccessToken == "90d") {
+		t.Error(identity, err)
+	}
 }
 
 func TestToken(t *testing.T) {
@@ -191,13 +271,30 @@ func TestToken(t *testing.T) {
 	if err != ErrNotAuthenticated {
 		t.Error(err, token)
 	}
+	identity, err := h.auth.GetIdentity(httptest.NewRequest("GET", "/foo", nil))
+	if err != ErrNotAuthenticated {
+		t.Error(err, identity)
+	}
+
+	// sessions from before identities were saved are not authenticated
+	cookie, err := makeCookie(h.auth.codecs[0], &authState{
+		Token: &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/foo", nil)
+	r.AddCookie(cookie)
+	identity, err = h.auth.GetIdentity(r)
+	if err != ErrNotAuthenticated {
+		t.Error(err, identity)
+	}
 }
 
 func TestHandleWithClient(t *testing.T) {
 	h := setupTestHarness()
-	var token *oauth2.Token
-	handleFunc := func(w http.ResponseWriter, r *http.Request, t *oauth2.Token) {
-		token = t
+	var identity *Identity
+	handleFunc := func(w http.ResponseWriter, r *http.Request, i *Identity) {
+		identity = i
 	}
 	handler := h.auth.Handler(handleFunc)
 
@@ -217,37 +314,119 @@ func TestHandleWithClient(t *testing.T) {
 	if redir.Path != "/noauth" && redir.Query().Get("path") != origPath {
 		t.Error(redir)
 	}
-	if token != nil {
-		t.Error(token)
+	if identity != nil {
+		t.Error(identity)
+	}
+
+	// create a session with an expired token, or without a subject: redirected to Google
+	for _, session := range []*authState{
+		{Token: &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(-time.Hour)},
+			Subject: "subject"},
+		{Token: &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}},
+	} {
+		cookie, err := makeCookie(h.auth.codecs[0], session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("GET", origPath, nil)
+		r.AddCookie(cookie)
+		handler.ServeHTTP(w, r)
+		resp = w.Result()
+		if resp.StatusCode != http.StatusFound {
+			t.Error(resp.Status)
+		}
+		redir, err = url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			t.Error(err)
+		}
+		if redir.Host != "accounts.google.com" {
+			t.Error(redir)
+		}
+		if identity != nil {
+			t.Error(identity)
+		}
+		// check that the session has the correct destination
+		session = h.sessionFromResponse(w)
+		if session.Destination != origPath {
+			t.Error(session.Destination)
+		}
 	}
 
-	// create a session with an expired token: redirected to Google
-	session := &authState{Token: &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(-time.Hour)}}
-	cookie, err := makeCookie(h.auth.securecookies, session)
+	// valid session: the handler gets the identity
+	cookie, err := makeCookie(h.auth.codecs[0], &authState{
+		Token:   &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)},
+		Subject: "subject", Email: "user@example.com"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	w = httptest.NewRecorder()
 	r = httptest.NewRequest("GET", origPath, nil)
 	r.AddCookie(cookie)
-	handler.ServeHTTP(w, r)
-	resp = w.Result()
-	if resp.StatusCode != http.StatusFound {
-		t.Error(resp.Status)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if !(identity != nil && identity.Subject == "subject" && identity.Email == "user@example.com" &&
+		identity.Token.AccessToken == "access") {
+		t.Error(identity)
 	}
-	redir, err = url.Parse(resp.Header.Get("Location"))
-	if err != nil {
-		t.Error(err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	_, err := New("clientID", "clientSecret", "https://example.com/redirect", nil, nil, "/noauth",
+		http.NewServeMux())
+	if err == nil {
+		t.Error("New without codecs must fail")
 	}
-	if redir.Host != "accounts.google.com" {
-		t.Error(redir)
+
+	h := setupTestHarness()
+	newKeys := securecookie.CodecsFromPairs(securecookie.GenerateRandomKey(cookieHashKeyLength),
+		securecookie.GenerateRandomKey(cookieEncryptionKeyLength))
+	oldCodec := h.auth.codecs[0]
+	h.auth.codecs = append(newKeys, oldCodec)
+	var identity *Identity
+	handler := h.auth.Handler(func(w http.ResponseWriter, r *http.Request, i *Identity) {
+		identity = i
+	})
+	serve := func(codec securecookie.Codec) *httptest.ResponseRecorder {
+		cookie, err := makeCookie(codec, &authState{
+			Token:   &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)},
+			Subject: "subject"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		identity = nil
+		r := httptest.NewRequest("GET", "/page", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
 	}
-	if token != nil {
-		t.Error(token)
+
+	// cookies with the old key are accepted and re-issued with the new key
+	w := serve(oldCodec)
+	cookies := w.Result().Cookies()
+	if !(identity != nil && identity.Subject == "subject" && len(cookies) == 1) {
+		t.Fatal(identity, cookies)
 	}
-	// check that the session has the correct destination
-	session = h.sessionFromResponse(w)
-	if session.Destination != origPath {
-		t.Error(session.Destination)
+	session := &authState{}
+	err = newKeys[0].Decode(cookies[0].Name, cookies[0].Value, session)
+	if !(err == nil && session.Subject == "subject" && session.Token.AccessToken == "access") {
+		t.Error(session, err)
+	}
+	err = oldCodec.Decode(cookies[0].Name, cookies[0].Value, &authState{})
+	if err == nil {
+		t.Error("re-issued cookie must not use the old key")
+	}
+
+	// cookies with the new key are not re-issued
+	w = serve(newKeys[0])
+	if !(identity != nil && len(w.Result().Cookies()) == 0) {
+		t.Error(identity, w.Result().Cookies())
+	}
+
+	// cookies with unknown keys are not authenticated
+	unknown := securecookie.New(securecookie.GenerateRandomKey(cookieHashKeyLength),
+		securecookie.GenerateRandomKey(cookieEncryptionKeyLength))
+	w = serve(unknown)
+	if !(identity == nil && w.Code == http.StatusFound) {
+		t.Error(identity, w.Code)
 	}
 }
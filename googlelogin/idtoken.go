@@ -0,0 +1,186 @@
+package googlelogin
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes that return an id_token with the user's stable ID and email address. Without them, the
+// login only returns an opaque token that cannot be connected to later logins. New adds them to
+// the requested scopes.
+// https://developers.google.com/identity/openid-connect/openid-connect#scope-param
+const OpenIDScope = "openid"
+const EmailScope = "email"
+
+// Google's public keys for verifying ID tokens, as a JSON Web Key Set.
+// https://developers.google.com/identity/openid-connect/openid-connect#validatinganidtoken
+const googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// Tokens are accepted this long after they expire, in case clocks are not synchronized.
+const idTokenClockSkew = 5 * time.Minute
+
+var googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+
+// The claims in an ID token used by this package.
+type idTokenClaims struct {
+	Issuer        string `json:"iss"`
+	Audience      string `json:"aud"`
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	ExpiresAt     int64  `json:"exp"`
+}
+
+type jsonWebKey struct {
+	KeyID   string `json:"kid"`
+	KeyType string `json:"kty"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// Caches the public keys from a JSON Web Key Set URL.
+type keyCache struct {
+	url string
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+// Returns the key with keyID. Google rotates its keys, so the keys are fetched again when an
+// unknown key is requested.
+func (c *keyCache) key(keyID string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := c.keys[keyID]
+	if key != nil {
+		return key, nil
+	}
+
+	keys, err := fetchKeys(c.url)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	key = c.keys[keyID]
+	if key == nil {
+		return nil, fmt.Errorf("googlelogin: unknown ID token key %#v", keyID)
+	}
+	return key, nil
+}
+
+func fetchKeys(url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("googlelogin: error fetching ID token keys: %s", resp.Status)
+	}
+	var keySet struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range keySet.Keys {
+		if key.KeyType != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("googlelogin: invalid key exponent for key %#v", key.KeyID)
+		}
+		keys[key.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	return keys, nil
+}
+
+func decodeJSONPart(part string, output interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, output)
+}
+
+// Verifies the signature and claims of an ID token issued by Google for clientID.
+func verifyIDToken(keys *keyCache, clientID string, idToken string, now time.Time) (
+	*idTokenClaims, error) {
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("googlelogin: ID token must have 3 parts")
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	err := decodeJSONPart(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("googlelogin: invalid ID token header: %s", err.Error())
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("googlelogin: unsupported ID token algorithm %#v", header.Algorithm)
+	}
+	key, err := keys.key(header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("googlelogin: invalid ID token signature: %s", err.Error())
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+	if err != nil {
+		return nil, fmt.Errorf("googlelogin: invalid ID token signature: %s", err.Error())
+	}
+
+	claims := &idTokenClaims{}
+	err = decodeJSONPart(parts[1], claims)
+	if err != nil {
+		return nil, fmt.Errorf("googlelogin: invalid ID token claims: %s", err.Error())
+	}
+	validIssuer := false
+	for _, issuer := range googleIssuers {
+		if claims.Issuer == issuer {
+			validIssuer = true
+		}
+	}
+	if !validIssuer {
+		return nil, fmt.Errorf("googlelogin: invalid ID token issuer %#v", claims.Issuer)
+	}
+	if claims.Audience != clientID {
+		return nil, fmt.Errorf("googlelogin: ID token is for a different client %#v",
+			claims.Audience)
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(idTokenClockSkew)) {
+		return nil, errors.New("googlelogin: ID token expired")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("googlelogin: ID token has no subject")
+	}
+	return claims, nil
+}
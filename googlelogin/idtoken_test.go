@@ -0,0 +1,101 @@
+package googlelogin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyCache(t *testing.T) {
+	fetches := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		key := &testIDTokenKey.PublicKey
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []*jsonWebKey{
+			{KeyID: "other", KeyType: "EC"},
+			{KeyID: testIDTokenKeyID, KeyType: "RSA",
+				N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())},
+		}})
+	}))
+	defer ts.Close()
+
+	cache := &keyCache{url: ts.URL}
+	for i := 0; i < 2; i++ {
+		key, err := cache.key(testIDTokenKeyID)
+		if !(err == nil && key.N.Cmp(testIDTokenKey.N) == 0 && key.E == testIDTokenKey.E) {
+			t.Error(key, err)
+		}
+	}
+	if fetches != 1 {
+		t.Error("keys should be cached", fetches)
+	}
+	// unknown keys are fetched again, since Google rotates keys
+	key, err := cache.key("other")
+	if !(key == nil && err != nil && fetches == 2) {
+		t.Error(key, err, fetches)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	h := setupTestHarness()
+	now := time.Now()
+	verify := func(idToken string) (*idTokenClaims, error) {
+		return verifyIDToken(h.auth.idTokenKeys, "clientID", idToken, now)
+	}
+
+	claims, err := verify(signIDToken(validClaims()))
+	if !(err == nil && claims.Subject == "subject" && claims.Email == "user@example.com" &&
+		claims.EmailVerified) {
+		t.Error(claims, err)
+	}
+	// tokens are accepted slightly after they expire
+	recent := validClaims()
+	recent["exp"] = now.Add(-time.Minute).Unix()
+	claims, err = verify(signIDToken(recent))
+	if err != nil {
+		t.Error(claims, err)
+	}
+
+	invalid := []struct {
+		claim string
+		value interface{}
+		err   string
+	}{
+		{"iss", "https://example.com", "issuer"},
+		{"aud", "otherClientID", "different client"},
+		{"exp", now.Add(-time.Hour).Unix(), "expired"},
+		{"sub", "", "no subject"},
+	}
+	for _, test := range invalid {
+		claims := validClaims()
+		claims[test.claim] = test.value
+		output, err := verify(signIDToken(claims))
+		if !(output == nil && err != nil && strings.Contains(err.Error(), test.err)) {
+			t.Error(test.claim, output, err)
+		}
+	}
+
+	// modified claims do not match the signature
+	parts := strings.Split(signIDToken(validClaims()), ".")
+	otherParts := strings.Split(signIDToken(map[string]interface{}{"sub": "other"}), ".")
+	for _, idToken := range []string{"", "a.b", parts[0] + "." + otherParts[1] + "." + parts[2]} {
+		output, err := verify(idToken)
+		if !(output == nil && err != nil) {
+			t.Error(idToken, output, err)
+		}
+	}
+
+	// only RS256 is accepted
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	output, err := verify(header + "." + parts[1] + ".")
+	if !(output == nil && err != nil && strings.Contains(err.Error(), "algorithm")) {
+		t.Error(output, err)
+	}
+}
@@ -0,0 +1,104 @@
+package googlelogin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// Google's OAuth2 token revocation endpoint.
+// https://developers.google.com/identity/protocols/oauth2/web-server#tokenrevoke
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// Revokes token at revokeURL. Revoking a refresh token also revokes its access tokens.
+func revokeToken(revokeURL string, token string) error {
+	resp, err := http.PostForm(revokeURL, url.Values{"token": []string{token}})
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	err2 := resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if err2 != nil {
+		return err2
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("googlelogin: revoke error: %s %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+// Logout revokes identity's access to the user's Google account, and deletes the token stored for
+// offline access and all of the user's stored sessions. Revocation errors are only logged, since
+// Google rejects tokens that have already expired or been revoked.
+func (a *Authenticator) Logout(identity *Identity) error {
+	// the refresh token revokes the whole grant; the cookie only has the access token
+	token := identity.Token.AccessToken
+	stored, err := a.storedToken(identity)
+	if err != nil {
+		log.Printf("googlelogin: error: loading stored token: %s", err.Error())
+	} else if stored != nil && stored.RefreshToken != "" {
+		token = stored.RefreshToken
+	}
+	err = revokeToken(a.revokeURL, token)
+	if err != nil {
+		log.Printf("googlelogin: warning: subject %s: %s", identity.Subject, err.Error())
+	}
+
+	// the revoked token cannot be used by any of the user's sessions
+	err = a.deleteSubjectSessions(identity.Subject)
+	if err != nil {
+		return err
+	}
+	if a.tokens == nil {
+		return nil
+	}
+	return a.tokens.DeleteToken(identity.Subject)
+}
+
+// LogoutHandler returns a handler that logs out the user with a POST request, then redirects
+// to destinationPath. It clears the session cookie, calls Logout, and calls onLogout to delete
+// the application's state for the user. onLogout may be nil. Requests without a session are
+// only redirected. The session cookie is SameSite=Lax, so browsers do not send it with a POST
+// from another site, and other sites cannot log users out.
+func (a *Authenticator) LogoutHandler(destinationPath string,
+	onLogout func(identity *Identity) error) http.Handler {
+
+	httpHandleFunc := func(w http.ResponseWriter, r *http.Request) {
+		// a GET could be triggered by another site with a link, which includes the SameSite=Lax
+		// cookie
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// without the cookie, this may be a POST from another site: do not clear it
+		_, err := r.Cookie(cookieName)
+		if err != nil {
+			http.Redirect(w, r, destinationPath, http.StatusSeeOther)
+			return
+		}
+		session := a.getSession(r)
+		deleteSession(w)
+		if session.Token != nil && session.Subject != "" {
+			identity := session.identity()
+			err = a.Logout(identity)
+			if err == nil && onLogout != nil {
+				err = onLogout(identity)
+			}
+			if err != nil {
+				log.Printf("googlelogin: error: logout for subject %s: %s", identity.Subject,
+					err.Error())
+				http.Error(w, "logout error please try again", http.StatusInternalServerError)
+				return
+			}
+		}
+		http.Redirect(w, r, destinationPath, http.StatusSeeOther)
+	}
+	return http.HandlerFunc(httpHandleFunc)
+}
@@ -0,0 +1,110 @@
+package googlelogin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestLogout(t *testing.T) {
+	h, store := setupOfflineHarness()
+	var revoked []string
+	revokeStatus := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Error("revoke must be a POST", r.Method)
+		}
+		revoked = append(revoked, r.FormValue("token"))
+		w.WriteHeader(revokeStatus)
+	}))
+	defer ts.Close()
+	h.auth.revokeURL = ts.URL + "/revoke"
+
+	var loggedOut []string
+	var onLogoutErr error
+	handler := h.auth.LogoutHandler("/", func(identity *Identity) error {
+		loggedOut = append(loggedOut, identity.Subject)
+		return onLogoutErr
+	})
+	logout := func(session *authState) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/logout", nil)
+		if session != nil {
+			cookie, err := makeCookie(h.auth.codecs[0], session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	isLoggedOut := func(w *httptest.ResponseRecorder) bool {
+		cookies := w.Result().Cookies()
+		return w.Code == http.StatusSeeOther && w.Header().Get("Location") == "/" &&
+			len(cookies) == 1 && cookies[0].Value == "" && cookies[0].Expires.Before(time.Now())
+	}
+
+	// the stored refresh token is revoked and deleted
+	err := h.auth.saveToken("subject", &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &authState{Token: &oauth2.Token{AccessToken: "access"}, Subject: "subject"}
+	w := logout(session)
+	if !isLoggedOut(w) {
+		t.Error(w.Code, w.Header())
+	}
+	value, err := store.GetToken("subject")
+	if !(value == nil && err == nil) {
+		t.Error("stored token not deleted", value, err)
+	}
+	if !(reflect.DeepEqual(revoked, []string{"refresh"}) &&
+		reflect.DeepEqual(loggedOut, []string{"subject"})) {
+		t.Error(revoked, loggedOut)
+	}
+
+	// without a stored token, the access token is revoked; Google rejects invalid tokens
+	revokeStatus = http.StatusBadRequest
+	w = logout(session)
+	if !(isLoggedOut(w) && len(revoked) == 2 && revoked[1] == "access" && len(loggedOut) == 2) {
+		t.Error(w.Code, revoked, loggedOut)
+	}
+
+	// sessions without a token are only redirected
+	w = logout(&authState{State: []byte("state"), Destination: "/x"})
+	if !(isLoggedOut(w) && len(revoked) == 2 && len(loggedOut) == 2) {
+		t.Error(w.Code, revoked, loggedOut)
+	}
+	// browsers do not send the cookie with POSTs from other sites: it must not be cleared
+	w = logout(nil)
+	if !(w.Code == http.StatusSeeOther && len(w.Result().Cookies()) == 0 &&
+		len(revoked) == 2 && len(loggedOut) == 2) {
+		t.Error(w.Code, w.Result().Cookies(), revoked, loggedOut)
+	}
+	cookie, err := makeCookie(h.auth.codecs[0], session)
+	if !(err == nil && cookie.SameSite == http.SameSiteLaxMode) {
+		t.Error(cookie, err)
+	}
+
+	// errors deleting the application's state are reported; the cookie is still cleared
+	onLogoutErr = errors.New("db error")
+	w = logout(session)
+	if !(w.Code == http.StatusInternalServerError && len(w.Result().Cookies()) == 1 &&
+		len(loggedOut) == 3) {
+		t.Error(w.Code, loggedOut)
+	}
+
+	// GET requests could come from other sites
+	r := httptest.NewRequest("GET", "/logout", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if !(w.Code == http.StatusMethodNotAllowed && len(w.Result().Cookies()) == 0) {
+		t.Error(w.Code, w.Result().Cookies())
+	}
+}
@@ -0,0 +1,201 @@
+package googlelogin
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Length of the random session IDs stored in cookies.
+const sessionIDLength = 32
+
+// Session is a login saved on the server. With a SessionStore, the cookie only contains the ID.
+type Session struct {
+	ID      string
+	Subject string
+	Email   string
+	// Without the refresh token: see EnableOfflineAccess
+	Token     *oauth2.Token
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SessionStore saves sessions on the server, so they can be listed and revoked. Implementations
+// must be safe for concurrent use.
+type SessionStore interface {
+	// PutSession creates or replaces the session with session.ID.
+	PutSession(session *Session) error
+	// GetSession returns nil, nil if id does not exist. It may return expired sessions.
+	GetSession(id string) (*Session, error)
+	// ListSessions returns the sessions for subject, oldest first.
+	ListSessions(subject string) ([]*Session, error)
+	// DeleteSession does nothing if id does not exist.
+	DeleteSession(id string) error
+	// DeleteExpiredSessions deletes sessions that expired before now, and returns the number
+	// deleted.
+	DeleteExpiredSessions(now time.Time) (int, error)
+}
+
+// MemorySessionStore is a SessionStore for tests and single process servers. Sessions are lost
+// when the process exits.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]*Session{}}
+}
+
+// Returns a copy of session so callers cannot modify the store.
+func copySession(session *Session) *Session {
+	output := *session
+	if session.Token != nil {
+		token := *session.Token
+		output.Token = &token
+	}
+	return &output
+}
+
+func (m *MemorySessionStore) PutSession(session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = copySession(session)
+	return nil
+}
+
+func (m *MemorySessionStore) GetSession(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session := m.sessions[id]
+	if session == nil {
+		return nil, nil
+	}
+	return copySession(session), nil
+}
+
+func (m *MemorySessionStore) ListSessions(subject string) ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := []*Session{}
+	for _, session := range m.sessions {
+		if session.Subject == subject {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+	sort.Slice(sessions, func(i int, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (m *MemorySessionStore) DeleteSession(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemorySessionStore) DeleteExpiredSessions(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	for id, session := range m.sessions {
+		if session.ExpiresAt.Before(now) {
+			delete(m.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// EnableSessionStore saves logins in store instead of the cookie. The cookie only contains a
+// random session ID, so sessions can be revoked on the server. Existing cookies that contain a
+// token are not accepted, so those users log in again.
+func (a *Authenticator) EnableSessionStore(store SessionStore) {
+	a.sessions = store
+}
+
+func makeSessionID() (string, error) {
+	id := make([]byte, sessionIDLength)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+// Saves a new login in the session store, and returns the cookie session that refers to it.
+func (a *Authenticator) newStoredSession(token *oauth2.Token, subject string, email string) (
+	*authState, error) {
+
+	id, err := makeSessionID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = a.sessions.PutSession(&Session{ID: id, Subject: subject, Email: email, Token: token,
+		CreatedAt: now, ExpiresAt: now.Add(cookieExpiration)})
+	if err != nil {
+		return nil, err
+	}
+	return &authState{SessionID: id}, nil
+}
+
+// Replaces the cookie's session ID with the stored session. Returns an empty session if it does
+// not exist or has expired.
+func (a *Authenticator) loadStoredSession(session *authState) *authState {
+	if a.sessions == nil {
+		log.Printf("googlelogin: error: ignoring session ID without a session store")
+		return &authState{}
+	}
+	stored, err := a.sessions.GetSession(session.SessionID)
+	if err != nil {
+		log.Printf("googlelogin: error: ignoring session that could not be loaded: %s", err.Error())
+		return &authState{}
+	}
+	if stored == nil || stored.ExpiresAt.Before(time.Now()) {
+		return &authState{}
+	}
+	return &authState{Token: stored.Token, Subject: stored.Subject, Email: stored.Email,
+		SessionID: stored.ID}
+}
+
+// Deletes the stored session for the request's cookie, if there is one.
+func (a *Authenticator) deleteStoredSession(r *http.Request) error {
+	if a.sessions == nil {
+		return nil
+	}
+	session := a.getSession(r)
+	if session.SessionID == "" {
+		return nil
+	}
+	return a.sessions.DeleteSession(session.SessionID)
+}
+
+// Deletes all stored sessions for subject.
+func (a *Authenticator) deleteSubjectSessions(subject string) error {
+	if a.sessions == nil {
+		return nil
+	}
+	sessions, err := a.sessions.ListSessions(subject)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err = a.sessions.DeleteSession(session.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package googlelogin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	session, err := store.GetSession("a")
+	if !(session == nil && err == nil) {
+		t.Error(session, err)
+	}
+
+	now := time.Now()
+	input := &Session{ID: "b", Subject: "subject", Token: &oauth2.Token{AccessToken: "access"},
+		CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	err = store.PutSession(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutSession(&Session{ID: "a", Subject: "subject", CreatedAt: now.Add(time.Minute),
+		ExpiresAt: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutSession(&Session{ID: "c", Subject: "other", CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the store keeps a copy
+	input.Token.AccessToken = "modified"
+	session, err = store.GetSession("b")
+	if !(err == nil && session.Subject == "subject" && session.Token.AccessToken == "access") {
+		t.Error(session, err)
+	}
+
+	sessions, err := store.ListSessions("subject")
+	if !(err == nil && len(sessions) == 2 && sessions[0].ID == "b" && sessions[1].ID == "a") {
+		t.Error(sessions, err)
+	}
+	sessions, err = store.ListSessions("missing")
+	if !(err == nil && sessions != nil && len(sessions) == 0) {
+		t.Error(sessions, err)
+	}
+
+	deleted, err := store.DeleteExpiredSessions(now)
+	if !(err == nil && deleted == 1) {
+		t.Error(deleted, err)
+	}
+	err = store.DeleteSession("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "c"} {
+		session, err = store.GetSession(id)
+		if !(session == nil && err == nil) {
+			t.Error(id, session, err)
+		}
+	}
+}
+
+func TestStoredSessions(t *testing.T) {
+	h := setupTestHarness()
+	store := NewMemorySessionStore()
+	h.auth.EnableSessionStore(store)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access",
+			"token_type": "bearer", "expires_in": 3600, "id_token": signIDToken(validClaims())})
+	}))
+	defer ts.Close()
+	h.auth.oauthConfig.Endpoint.TokenURL = ts.URL + "/token"
+
+	// logs in with the cookie from a previous request, and returns the new cookie
+	login := func(previous *http.Cookie) *http.Cookie {
+		r := httptest.NewRequest("POST", "/start", nil)
+		if previous != nil {
+			r.AddCookie(previous)
+		}
+		w := httptest.NewRecorder()
+		err := h.auth.Start(w, r, "/dest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = httptest.NewRequest("GET", "/callback?code=code&state="+parsed.Query().Get("state"), nil)
+		r.AddCookie(w.Result().Cookies()[0])
+		w = httptest.NewRecorder()
+		err = h.auth.handleCallbackError(w, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w.Result().Cookies()[0]
+	}
+	getIdentity := func(cookie *http.Cookie) (*Identity, error) {
+		r := httptest.NewRequest("GET", "/page", nil)
+		r.AddCookie(cookie)
+		return h.auth.GetIdentity(r)
+	}
+
+	// the cookie only contains the session ID
+	cookie := login(nil)
+	session := &authState{}
+	err := h.securecookies.Decode(cookie.Name, cookie.Value, session)
+	if !(err == nil && session.Token == nil && session.Subject == "" && session.SessionID != "") {
+		t.Error(session, err)
+	}
+	identity, err := getIdentity(cookie)
+	if !(err == nil && identity.Subject == "subject" && identity.Email == "user@example.com" &&
+		identity.Token.AccessToken == "access") {
+		t.Error(identity, err)
+	}
+	sessions, err := store.ListSessions("subject")
+	if !(err == nil && len(sessions) == 1 && sessions[0].ID == session.SessionID &&
+		sessions[0].ExpiresAt.After(time.Now())) {
+		t.Error(sessions, err)
+	}
+
+	// logging in again replaces the session
+	second := login(cookie)
+	_, err = getIdentity(cookie)
+	if err != ErrNotAuthenticated {
+		t.Error("replaced session should be deleted", err)
+	}
+	third := login(nil)
+
+	// revoked sessions are not authenticated
+	sessions, err = store.ListSessions("subject")
+	if !(err == nil && len(sessions) == 2) {
+		t.Fatal(sessions, err)
+	}
+	err = store.DeleteSession(sessions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getIdentity(second)
+	if err != ErrNotAuthenticated {
+		t.Error("revoked session should not be authenticated", err)
+	}
+
+	// expired sessions are not authenticated
+	sessions[1].ExpiresAt = time.Now().Add(-time.Second)
+	err = store.PutSession(sessions[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getIdentity(third)
+	if err != ErrNotAuthenticated {
+		t.Error("expired session should not be authenticated", err)
+	}
+
+	// cookies with tokens from before the store was enabled cannot be revoked: log in again
+	cookie, err = makeCookie(h.auth.codecs[0], &authState{
+		Token:   &oauth2.Token{AccessToken: "old", Expiry: time.Now().Add(time.Hour)},
+		Subject: "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getIdentity(cookie)
+	if err != ErrNotAuthenticated {
+		t.Error("cookies with tokens should not be authenticated", err)
+	}
+	// logins in progress are still read from the cookie
+	cookie, err = makeCookie(h.auth.codecs[0], &authState{State: []byte("state"),
+		Destination: "/dest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/callback", nil)
+	r.AddCookie(cookie)
+	session = h.auth.getSession(r)
+	if !(string(session.State) == "state" && session.Destination == "/dest") {
+		t.Error(session)
+	}
+
+	// logging out deletes all of the user's sessions
+	login(nil)
+	login(nil)
+	revokes := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer revokes.Close()
+	h.auth.revokeURL = revokes.URL
+	err = h.auth.Logout(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err = store.ListSessions("subject")
+	if !(err == nil && len(sessions) == 0) {
+		t.Error(sessions, err)
+	}
+}
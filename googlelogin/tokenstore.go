@@ -0,0 +1,193 @@
+package googlelogin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+// Length of the AES-256 key that encrypts stored tokens.
+const TokenEncryptionKeyLength = 32
+
+// TokenStore saves tokens for offline access on the server. Values are encrypted by the
+// Authenticator before they are stored. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// PutToken replaces the value for key.
+	PutToken(key string, value []byte) error
+	// GetToken returns nil, nil if key does not exist.
+	GetToken(key string) ([]byte, error)
+	// DeleteToken does nothing if key does not exist.
+	DeleteToken(key string) error
+}
+
+// MemoryTokenStore is a TokenStore for tests and single process servers. Tokens are lost when
+// the process exits.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{values: map[string][]byte{}}
+}
+
+func (m *MemoryTokenStore) PutToken(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *MemoryTokenStore) GetToken(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+func (m *MemoryTokenStore) DeleteToken(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// EnableOfflineAccess requests refresh tokens from Google, and stores them in store encrypted with
+// encryptionKey, keyed by the user's subject. Tokens are refreshed when they expire, so users stay
+// logged in and TokenSource works after the browser's access token expires. Users are asked to
+// approve access on every login, since Google only returns refresh tokens when the user approves.
+func (a *Authenticator) EnableOfflineAccess(store TokenStore, encryptionKey []byte) error {
+	if len(encryptionKey) != TokenEncryptionKeyLength {
+		return fmt.Errorf("googlelogin: token encryption key must be %d bytes; was %d",
+			TokenEncryptionKeyLength, len(encryptionKey))
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return err
+	}
+	a.tokenCipher, err = cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	a.tokens = store
+	return nil
+}
+
+func (a *Authenticator) saveToken(key string, token *oauth2.Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, a.tokenCipher.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	// the key is authenticated so values cannot be swapped between keys
+	ciphertext := a.tokenCipher.Seal(nonce, nonce, plaintext, []byte(key))
+	return a.tokens.PutToken(key, ciphertext)
+}
+
+// Returns nil, nil if there is no stored token for key.
+func (a *Authenticator) loadToken(key string) (*oauth2.Token, error) {
+	ciphertext, err := a.tokens.GetToken(key)
+	if err != nil || ciphertext == nil {
+		return nil, err
+	}
+	nonceSize := a.tokenCipher.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("googlelogin: stored token is too short")
+	}
+	plaintext, err := a.tokenCipher.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:],
+		[]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("googlelogin: error decrypting stored token: %s", err.Error())
+	}
+	token := &oauth2.Token{}
+	err = json.Unmarshal(plaintext, token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Saves a token from a new login for subject. Google only returns a refresh token the first
+// time the user approves access, so the stored refresh token is kept if token has none.
+func (a *Authenticator) storeToken(subject string, token *oauth2.Token) error {
+	if token.RefreshToken == "" {
+		stored, err := a.loadToken(subject)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			log.Printf("googlelogin: warning: offline access did not return a refresh token")
+			return nil
+		}
+		refreshed := *token
+		refreshed.RefreshToken = stored.RefreshToken
+		token = &refreshed
+	}
+	return a.saveToken(subject, token)
+}
+
+// Returns the stored token for identity, or nil if there is none. Tokens are stored by subject,
+// so a new login replaces the token from the previous login.
+func (a *Authenticator) storedToken(identity *Identity) (*oauth2.Token, error) {
+	if a.tokens == nil {
+		return nil, nil
+	}
+	return a.loadToken(identity.Subject)
+}
+
+// Saves tokens when they are refreshed.
+type storingTokenSource struct {
+	auth   *Authenticator
+	key    string
+	source oauth2.TokenSource
+
+	mu              sync.Mutex
+	lastAccessToken string
+}
+
+func (s *storingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.lastAccessToken {
+		// the refreshed token still works if it is not saved; it is refreshed again next time
+		err = s.auth.saveToken(s.key, token)
+		if err != nil {
+			log.Printf("googlelogin: error: saving refreshed token: %s", err.Error())
+		} else {
+			s.lastAccessToken = token.AccessToken
+		}
+	}
+	return token, nil
+}
+
+// TokenSource returns a source of access tokens for identity. With offline access, it refreshes
+// the stored token when it expires and saves the refreshed token. Otherwise, it always returns
+// identity.Token.
+func (a *Authenticator) TokenSource(ctx context.Context, identity *Identity) (
+	oauth2.TokenSource, error) {
+
+	stored, err := a.storedToken(identity)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return oauth2.StaticTokenSource(identity.Token), nil
+	}
+	return &storingTokenSource{auth: a, key: identity.Subject,
+		source: a.oauthConfig.TokenSource(ctx, stored), lastAccessToken: stored.AccessToken}, nil
+}
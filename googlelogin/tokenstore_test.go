@@ -0,0 +1,211 @@
+package googlelogin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+func TestMemoryTokenStore(t *testing.T) {
+	store := NewMemoryTokenStore()
+	value, err := store.GetToken("k")
+	if !(value == nil && err == nil) {
+		t.Error(value, err)
+	}
+	input := []byte("value")
+	err = store.PutToken("k", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the store keeps a copy
+	input[0] = 'X'
+	value, err = store.GetToken("k")
+	if !(string(value) == "value" && err == nil) {
+		t.Error(string(value), err)
+	}
+	err = store.DeleteToken("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err = store.GetToken("k")
+	if !(value == nil && err == nil) {
+		t.Error(value, err)
+	}
+}
+
+func setupOfflineHarness() (*harness, *MemoryTokenStore) {
+	h := setupTestHarness()
+	store := NewMemoryTokenStore()
+	err := h.auth.EnableOfflineAccess(store, make([]byte, TokenEncryptionKeyLength))
+	if err != nil {
+		panic(err)
+	}
+	return h, store
+}
+
+func TestEncryptedTokens(t *testing.T) {
+	h := setupTestHarness()
+	err := h.auth.EnableOfflineAccess(NewMemoryTokenStore(), []byte("short"))
+	if err == nil {
+		t.Error("expected error for short key")
+	}
+
+	h, store := setupOfflineHarness()
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}
+	err = h.auth.saveToken("a", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, _ := store.GetToken("a")
+	if bytes.Contains(ciphertext, []byte("refresh")) {
+		t.Error("token is not encrypted")
+	}
+	loaded, err := h.auth.loadToken("a")
+	if !(err == nil && loaded.AccessToken == "access" && loaded.RefreshToken == "refresh") {
+		t.Error(loaded, err)
+	}
+	loaded, err = h.auth.loadToken("missing")
+	if !(err == nil && loaded == nil) {
+		t.Error(loaded, err)
+	}
+
+	// values are bound to their key
+	store.PutToken("b", ciphertext)
+	loaded, err = h.auth.loadToken("b")
+	if err == nil {
+		t.Error("expected error", loaded)
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+	store.PutToken("a", ciphertext)
+	loaded, err = h.auth.loadToken("a")
+	if err == nil {
+		t.Error("expected error", loaded)
+	}
+}
+
+func TestOfflineLogin(t *testing.T) {
+	h, _ := setupOfflineHarness()
+	w := httptest.NewRecorder()
+	err := h.auth.Start(w, httptest.NewRequest("POST", "/start", nil), "/dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if !(query.Get("access_type") == "offline" && query.Get("prompt") == "consent" &&
+		query.Get("approval_prompt") == "") {
+		t.Error(query)
+	}
+
+	refreshes := 0
+	loginRefreshToken := "refresh"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("grant_type") == "refresh_token" {
+			refreshes++
+			if r.FormValue("refresh_token") != "refresh" {
+				t.Error("unexpected refresh token", r.FormValue("refresh_token"))
+			}
+			w.Write([]byte(`{"access_token": "refreshed", "token_type": "bearer", "expires_in": 3600}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "first",
+			"refresh_token": loginRefreshToken, "token_type": "bearer", "expires_in": 3600,
+			"id_token": signIDToken(validClaims())})
+	}))
+	defer ts.Close()
+	h.auth.oauthConfig.Endpoint.TokenURL = ts.URL + "/token"
+
+	r := httptest.NewRequest("GET", "/callback?code=code&state="+query.Get("state"), nil)
+	r.AddCookie(w.Result().Cookies()[0])
+	w = httptest.NewRecorder()
+	err = h.auth.handleCallbackError(w, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the refresh token is stored on the server, not in the cookie
+	session := h.sessionFromResponse(w)
+	if !(session.Token.AccessToken == "first" && session.Token.RefreshToken == "") {
+		t.Error(session.Token)
+	}
+	stored, err := h.auth.storedToken(session.identity())
+	if !(err == nil && stored.RefreshToken == "refresh") {
+		t.Error(stored, err)
+	}
+
+	// Google does not always return a refresh token: the stored one is kept
+	loginRefreshToken = ""
+	err = h.auth.handleCallbackError(httptest.NewRecorder(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err = h.auth.storedToken(session.identity())
+	if !(err == nil && stored.RefreshToken == "refresh") {
+		t.Error(stored, err)
+	}
+
+	// an expired cookie token is accepted since it can be refreshed
+	expired := *session.Token
+	expired.Expiry = time.Now().Add(-time.Minute)
+	identity := &Identity{Subject: session.Subject, Token: &expired}
+	cookie, err := makeCookie(h.auth.codecs[0], &authState{Token: &expired,
+		Subject: session.Subject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	handler := h.auth.Handler(func(w http.ResponseWriter, r *http.Request, identity *Identity) {
+		called = true
+	})
+	r = httptest.NewRequest("GET", "/page", nil)
+	r.AddCookie(cookie)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if !called {
+		t.Error("handler not called with refreshable token")
+	}
+
+	// the token source refreshes the expired stored token and saves the new one
+	err = h.auth.saveToken(session.Subject, &oauth2.Token{AccessToken: "first",
+		RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := h.auth.TokenSource(context.Background(), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		token, err := source.Token()
+		if !(err == nil && token.AccessToken == "refreshed") {
+			t.Error(token, err)
+		}
+	}
+	stored, err = h.auth.storedToken(identity)
+	if !(err == nil && refreshes == 1 && stored.AccessToken == "refreshed" &&
+		stored.RefreshToken == "refresh") {
+		t.Error(refreshes, stored, err)
+	}
+}
+
+func TestOnlineTokenSource(t *testing.T) {
+	h := setupTestHarness()
+	identity := &Identity{Subject: "subject", Token: &oauth2.Token{AccessToken: "access"}}
+	source, err := h.auth.TokenSource(context.Background(), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := source.Token()
+	if !(err == nil && output.AccessToken == "access") {
+		t.Error(output, err)
+	}
+}
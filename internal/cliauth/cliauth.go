@@ -0,0 +1,28 @@
+// Package cliauth creates authenticated HTTP clients for the command line tools, and loads the
+// project snapshots they report on.
+package cliauth
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+)
+
+// NewClient returns an HTTP client with scope that uses the service account key in keyPath,
+// or application default credentials if keyPath is empty.
+func NewClient(ctx context.Context, keyPath string, scope string) (*http.Client, error) {
+	if keyPath == "" {
+		return google.DefaultClient(ctx, scope)
+	}
+	key, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	config, err := google.JWTConfigFromJSON(key, scope)
+	if err != nil {
+		return nil, err
+	}
+	return config.Client(ctx), nil
+}
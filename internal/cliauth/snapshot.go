@@ -0,0 +1,52 @@
+package cliauth
+
+import (
+	"bytes"
+	"log"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/bigquery/v2"
+
+	"github.com/evanj/bqtools/bqscrape"
+)
+
+// LogProgress logs scraping progress to stderr, prefixed with the command's name.
+type LogProgress string
+
+func (l LogProgress) Progress(percent int, message string) {
+	log.Printf("%s: %d%% %s", string(l), percent, message)
+}
+
+// LoadSnapshot returns the snapshot file at snapshotPath if it is set. Otherwise it scrapes
+// projectID with the credentials in keyPath, as for NewClient, and saves it as a snapshot file
+// at dumpPath if it is set.
+func LoadSnapshot(projectID string, keyPath string, snapshotPath string, dumpPath string,
+	progress bqscrape.ProgressReporter) (*bqscrape.Snapshot, error) {
+
+	if snapshotPath != "" {
+		return bqscrape.ReadSnapshotFile(snapshotPath)
+	}
+	client, err := NewClient(context.Background(), keyPath, bqscrape.Scope)
+	if err != nil {
+		return nil, err
+	}
+	bq, err := bigquery.New(client)
+	if err != nil {
+		return nil, err
+	}
+	if dumpPath != "" {
+		err = bqscrape.WriteSnapshotFile(bq, projectID, progress, dumpPath)
+		if err != nil {
+			return nil, err
+		}
+		return bqscrape.ReadSnapshotFile(dumpPath)
+	}
+
+	// scrape to memory to get the datasets' billing models along with the tables
+	buf := &bytes.Buffer{}
+	err = bqscrape.WriteSnapshot(bq, projectID, progress, buf)
+	if err != nil {
+		return nil, err
+	}
+	return bqscrape.ReadSnapshot(buf)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Maximum number of tables of one type listed in the inventory.
+const maxInventoryResults = 1000
+
+// Returns the number, storage and cost of tables of each type in a snapshot, sorted by type.
+func queryTypeUsage(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64, projectID string,
+	snapshot *bqdb.Snapshot) ([]*templates.TypeUsage, error) {
+
+	rows, err := bqdb.QueryStorageByType(dbmap, userID, projectID, snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	var types []*templates.TypeUsage
+	for _, row := range rows {
+		// rows are sorted by type then location
+		if len(types) == 0 || types[len(types)-1].Type != row.Type {
+			types = append(types, &templates.TypeUsage{Type: row.Type})
+		}
+		usage := types[len(types)-1]
+		usage.Tables += row.Tables
+		usage.Bytes += row.Bytes
+		usage.DollarsPerMonth += storageCost(prices, row.Location, row.StorageBillingModel,
+			snapshot.TimeMs, row.BilledBytes, row.BilledLongTermBytes).Total()
+	}
+	return types, nil
+}
+
+// Lists the tables, views and external tables in the latest snapshot with the type in the type
+// parameter. Defaults to tables.
+func (s *server) projectInventory(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	user, snapshot, err := getLatestSnapshot(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+
+	data := &templates.InventoryReport{ProjectID: projectID}
+	data.Types, err = queryTypeUsage(s.dbmap, s.prices, user.ID, projectID, snapshot)
+	if err != nil {
+		return err
+	}
+	data.Type = r.FormValue("type")
+	if data.Type == "" {
+		data.Type = bqscrape.TypeTable
+	}
+
+	tables, err := bqdb.ListTablesByType(s.dbmap, user.ID, projectID, snapshot.ID, data.Type,
+		maxInventoryResults+1)
+	if err != nil {
+		return err
+	}
+	if len(tables) > maxInventoryResults {
+		tables = tables[:maxInventoryResults]
+		data.Truncated = true
+	}
+	for _, table := range tables {
+		data.Tables = append(data.Tables, newTableReport(s.prices, projectID, snapshot, table))
+	}
+	return templates.Inventory(w, data)
+}
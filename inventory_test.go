@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/pricing"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxBytes int
+		expected string
+	}{
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc"},
+		// é is two bytes: do not split it
+		{"aéb", 2, "a"},
+		{"aéb", 3, "aé"},
+	}
+	for _, test := range tests {
+		output := truncate(test.input, test.maxBytes)
+		if output != test.expected {
+			t.Errorf("truncate(%#v, %d) = %#v; expected %#v",
+				test.input, test.maxBytes, output, test.expected)
+		}
+	}
+}
+
+func TestProjectInventory(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := testIdentity(u)
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dbmap.Insert(&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const gib = 1024 * 1024 * 1024
+	ref := func(tableID string) *bigquery.TableReference {
+		return &bigquery.TableReference{ProjectId: "p", DatasetId: "d", TableId: tableID}
+	}
+	tables := []*bigquery.Table{
+		{Type: bqscrape.TypeTable, TableReference: ref("table"), NumBytes: 10 * gib},
+		{Type: bqscrape.TypeView, TableReference: ref("view"),
+			View: &bigquery.ViewDefinition{Query: "SELECT * FROM d.table"}},
+		{Type: bqscrape.TypeMaterializedView, TableReference: ref("mv"), NumBytes: 5 * gib,
+			MaterializedView: &bigquery.MaterializedViewDefinition{Query: "SELECT COUNT(*) FROM d.table"}},
+		{Type: bqscrape.TypeExternal, TableReference: ref("external"),
+			ExternalDataConfiguration: &bigquery.ExternalDataConfiguration{SourceFormat: "CSV",
+				SourceUris: []string{"gs://bucket/a.csv", "gs://bucket/b.csv"}}},
+		{Type: bqscrape.TypeSnapshot, TableReference: ref("snapshot"),
+			SnapshotDefinition: &bigquery.SnapshotDefinition{BaseTableReference: ref("table")}},
+		{Type: bqscrape.TypeTable, TableReference: ref("clone"),
+			CloneDefinition: &bigquery.CloneDefinition{BaseTableReference: ref("table")}},
+	}
+	// previously, types other than TABLE were skipped and left nil rows that failed to insert
+	err = s.saveBigqueryTables(dbmap, u.ID, snapshot.ID, "", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	view, err := bqdb.GetTable(dbmap, u.ID, "p", snapshot.ID, "d", "view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(view.Type == bqscrape.TypeView && view.ViewQuery == "SELECT * FROM d.table") {
+		t.Error(view)
+	}
+	external, err := bqdb.GetTable(dbmap, u.ID, "p", snapshot.ID, "d", "external")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(external.SourceFormat == "CSV" &&
+		external.SourceURIs == "gs://bucket/a.csv\ngs://bucket/b.csv") {
+		t.Error(external)
+	}
+	clone, err := bqdb.GetTable(dbmap, u.ID, "p", snapshot.ID, "d", "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.BaseTable != "p:d.table" {
+		t.Error(clone)
+	}
+
+	// materialized views are billed for their own storage
+	types, err := queryTypeUsage(dbmap, s.prices, u.ID, "p", snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(types) == 5 && types[0].Type == bqscrape.TypeExternal &&
+		types[1].Type == bqscrape.TypeMaterializedView && types[1].Bytes == 5*gib &&
+		math.Abs(types[1].DollarsPerMonth-0.1) < 1e-9 &&
+		types[3].Type == bqscrape.TypeTable && types[3].Tables == 2) {
+		t.Error(types)
+	}
+	data, err := queryProject(dbmap, s.prices, u.ID, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(data.TotalBytes == 15*gib && data.TableStorage[1].ID == "d.mv" &&
+		data.TableStorage[1].Type == bqscrape.TypeMaterializedView) {
+		t.Error(data.TotalBytes, data.TableStorage)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/projects/p/inventory?type=EXTERNAL", nil)
+	err = s.projectInventory(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"<strong>EXTERNAL</strong>", "gs://bucket/a.csv", "(2 URIs)",
+		`href="/projects/p/inventory?type=VIEW"`} {
+		if !strings.Contains(body, expected) {
+			t.Error("missing", expected)
+		}
+	}
+
+	// defaults to tables
+	w = httptest.NewRecorder()
+	err = s.projectInventory(w, httptest.NewRequest("GET", "/projects/p/inventory", nil), identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = w.Body.String()
+	if !strings.Contains(body, "clone of p:d.table") || strings.Contains(body, "d.view") {
+		t.Error(body)
+	}
+}
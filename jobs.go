@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+)
+
+// Jobs are leased by a worker. The worker renews the lease while it runs the job. If the process
+// dies, the lease expires and the job is claimed and re-run by another worker.
+const jobLeaseDuration = 2 * time.Minute
+const jobLeaseRenewInterval = jobLeaseDuration / 4
+
+// Workers look for abandoned jobs at this interval, even if no new jobs are created.
+const jobPollInterval = 30 * time.Second
+
+// Loading a project is retried this many times before the project is marked as failed.
+const maxJobAttempts = 3
+
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func durationMs(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// Returns a unique name for this process's workers: hostname-pid-random
+func makeJobOwner() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	random := make([]byte, 4)
+	_, err = rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(random)), nil
+}
+
+// Creates a new snapshot and a job to load it. Called in the transaction that creates or
+// refreshes the project, so the job exists if and only if the project is loading.
+func (s *server) startLoadingJob(txn gorp.SqlExecutor, userID int64, projectID string,
+	accessToken string) error {
+
+	snapshot := &bqdb.Snapshot{UserID: userID, ProjectID: projectID, TimeMs: nowMs()}
+	err := txn.Insert(snapshot)
+	if err != nil {
+		return err
+	}
+	job := &bqdb.Job{UserID: userID, ProjectID: projectID, SnapshotID: snapshot.ID,
+		State: bqdb.JobPending}
+	return txn.Insert(job)
+}
+
+// Wakes up a job worker without blocking. Call after committing a new job.
+func (s *server) wakeJobWorker() {
+	select {
+	case s.jobCreated <- struct{}{}:
+	default:
+	}
+}
+
+// Runs jobs forever. Multiple workers, including workers in other processes, can run at once.
+func (s *server) jobWorker() {
+	log.Printf("bqcost: job worker %s starting", s.jobOwner)
+	for {
+		job, err := bqdb.ClaimJob(s.dbmap, s.jobOwner, nowMs(), durationMs(jobLeaseDuration))
+		if err != nil {
+			log.Printf("bqcost: error claiming job: %s", err.Error())
+		}
+		if job != nil {
+			s.runJob(job)
+			continue
+		}
+
+		select {
+		case <-s.jobCreated:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// Runs the job to load a project. Failures are retried by releasing the job, until it has been
+// attempted maxJobAttempts times.
+func (s *server) runJob(job *bqdb.Job) {
+	log.Printf("bqcost: job %d attempt %d: loading user %d project %s",
+		job.ID, job.Attempts, job.UserID, job.ProjectID)
+
+	var loadErr error
+	if job.Attempts > maxJobAttempts {
+		// the process died while running the last attempt
+		loadErr = fmt.Errorf("bqcost: loading failed after %d attempts", maxJobAttempts)
+	} else {
+		loadErr = s.runJobWithLease(job)
+		if loadErr != nil && job.Attempts < maxJobAttempts {
+			log.Printf("bqcost: job %d attempt %d failed; will retry: %s",
+				job.ID, job.Attempts, loadErr.Error())
+			err := bqdb.EndJobLease(s.dbmap, s.dbmap, job, bqdb.JobPending)
+			if err != nil {
+				log.Printf("bqcost: job %d error releasing job: %s", job.ID, err.Error())
+			}
+			return
+		}
+	}
+
+	err := s.finishJob(job, loadErr)
+	if err != nil {
+		log.Printf("bqcost: job %d error finishing job: %s", job.ID, err.Error())
+	}
+	log.Printf("bqcost: job %d finished user %d project %s", job.ID, job.UserID, job.ProjectID)
+}
+
+// Loads the job's project while periodically renewing the lease. Loading is cancelled if the
+// lease cannot be renewed, since another worker may claim the job when it expires.
+func (s *server) runJobWithLease(job *bqdb.Job) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	// copy the job to avoid data races
+	go s.renewJobLease(*job, jobLeaseRenewInterval, cancel, stop, stopped)
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+
+	user, err := bqdb.GetUserByID(s.dbmap, job.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("bqcost: job %d: user %d does not exist", job.ID, job.UserID)
+	}
+
+	if job.CheckpointDatasetID == "" && job.CheckpointPageToken == "" && !job.CheckpointDone {
+		// starting from the beginning: remove anything left from a previous load
+		err = bqdb.DeleteSnapshotTables(s.dbmap, job.SnapshotID)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Printf("bqcost: job %d resuming at dataset %s after %d tables",
+			job.ID, job.CheckpointDatasetID, job.CheckpointTables)
+	}
+	// with offline access, the token is refreshed if the load takes longer than it is valid
+	identity := &googlelogin.Identity{Subject: user.Subject, Email: user.Email,
+		Token: &oauth2.Token{AccessToken: user.AccessToken}}
+	tokens, err := s.auth.TokenSource(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = s.loadProject(ctx, job, tokens)
+	if ctx.Err() != nil {
+		return fmt.Errorf("bqcost: job %d: loading cancelled: the lease was not renewed", job.ID)
+	}
+	return err
+}
+
+// Renews the job's lease every interval until stop is closed. Calls cancel and stops if the lease
+// cannot be renewed.
+func (s *server) renewJobLease(job bqdb.Job, interval time.Duration, cancel func(),
+	stop <-chan struct{}, stopped chan<- struct{}) {
+
+	defer close(stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := bqdb.RenewJobLease(s.dbmap, s.dbmap, &job, nowMs(), durationMs(jobLeaseDuration))
+			if err != nil {
+				log.Printf("bqcost: job %d error renewing lease; cancelling: %s", job.ID, err.Error())
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// Marks the job and its project as finished in one transaction.
+func (s *server) finishJob(job *bqdb.Job, loadErr error) error {
+	txn, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	// don't forget to rollback
+	defer txn.Rollback()
+
+	state := bqdb.JobDone
+	if loadErr != nil {
+		log.Printf("bqcost: job %d loading error: %s", job.ID, loadErr.Error())
+		state = bqdb.JobFailed
+	}
+	err = bqdb.EndJobLease(s.dbmap, txn, job, state)
+	if err != nil {
+		return err
+	}
+	if loadErr == nil {
+		err = bqdb.CompleteSnapshot(txn, job.SnapshotID)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.finishLoading(txn, job.UserID, job.ProjectID, job.SnapshotID, loadErr)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
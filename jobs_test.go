@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/go-gorp/gorp"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func getJob(dbmap *gorp.DbMap, projectID string) *bqdb.Job {
+	job := &bqdb.Job{}
+	err := dbmap.SelectOne(job, "SELECT * FROM Job WHERE ProjectID=?", projectID)
+	if err != nil {
+		panic(err)
+	}
+	return job
+}
+
+func TestRunJob(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+
+	var loadErr error
+	loadCalls := 0
+	loader := func(ctx context.Context, job *bqdb.Job, tokens oauth2.TokenSource) error {
+		loadCalls++
+		token, err := tokens.Token()
+		if err != nil || token.AccessToken != "token" {
+			t.Error("unexpected access token", token, err)
+		}
+		return loadErr
+	}
+	s := &server{auth: newTestAuthenticator(), dbmap: dbmap, loadProject: loader,
+		jobOwner: "owner"}
+	s.startLoading = s.startLoadingJob
+
+	// starting to load a project creates a pending job
+	identity := &googlelogin.Identity{Subject: "subject", Token: &oauth2.Token{AccessToken: "token"}}
+	_, _, err := s.getProjectOrStartLoading(identity, "project")
+	if err != errIsLoading {
+		t.Fatal(err)
+	}
+	job := getJob(dbmap, "project")
+	if job.State != bqdb.JobPending {
+		t.Error(job)
+	}
+
+	// the first attempt fails: the job is released to be retried
+	loadErr = errors.New("temporary error")
+	job, err = bqdb.ClaimJob(dbmap, s.jobOwner, nowMs(), durationMs(jobLeaseDuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.runJob(job)
+	job = getJob(dbmap, "project")
+	if !(loadCalls == 1 && job.State == bqdb.JobPending && job.Attempts == 1) {
+		t.Error(loadCalls, job)
+	}
+
+	// the second attempt works: the project is loaded
+	loadErr = nil
+	job, err = bqdb.ClaimJob(dbmap, s.jobOwner, nowMs(), durationMs(jobLeaseDuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.runJob(job)
+	job = getJob(dbmap, "project")
+	if !(loadCalls == 2 && job.State == bqdb.JobDone && job.Attempts == 2) {
+		t.Error(loadCalls, job)
+	}
+	userID, project, err := s.getProjectOrStartLoading(identity, "project")
+	if !(userID > 0 && project != nil && !project.IsLoading && err == nil) {
+		t.Fatal(userID, project, err)
+	}
+	// the project shows the job's complete snapshot
+	snapshot, err := bqdb.GetSnapshot(dbmap, userID, "project", project.SnapshotID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(snapshot != nil && snapshot.ID == job.SnapshotID && snapshot.Complete) {
+		t.Error(snapshot, job)
+	}
+}
+
+func TestRunAbandonedJob(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+
+	loadCalls := 0
+	loader := func(ctx context.Context, job *bqdb.Job, tokens oauth2.TokenSource) error {
+		loadCalls++
+		return nil
+	}
+	s := &server{auth: newTestAuthenticator(), dbmap: dbmap, loadProject: loader,
+		jobOwner: "owner"}
+
+	// a process died while running the last attempt
+	u := &bqdb.User{ID: 3, Subject: "subject", AccessToken: "token"}
+	p := &bqdb.Project{UserID: u.ID, ProjectID: "project", IsLoading: true}
+	job := &bqdb.Job{UserID: u.ID, ProjectID: p.ProjectID, State: bqdb.JobRunning,
+		Attempts: maxJobAttempts, LeaseOwner: "dead", LeaseExpiryMs: 1}
+	err := dbmap.Insert(u, p, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = bqdb.ClaimJob(dbmap, s.jobOwner, nowMs(), durationMs(jobLeaseDuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.runJob(job)
+	if loadCalls != 0 {
+		t.Error("should not load after too many attempts")
+	}
+	job = getJob(dbmap, "project")
+	if job.State != bqdb.JobFailed {
+		t.Error(job)
+	}
+	p, err = bqdb.GetProjectByID(dbmap, p.UserID, p.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IsLoading || !strings.Contains(p.LoadingError, "attempts") {
+		t.Error(p)
+	}
+}
+
+func TestLostLease(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, jobOwner: "owner"}
+
+	job := &bqdb.Job{UserID: 1, ProjectID: "p", State: bqdb.JobPending}
+	err := dbmap.Insert(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = bqdb.ClaimJob(dbmap, s.jobOwner, nowMs(), durationMs(jobLeaseDuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lease is renewed while it is owned
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go s.renewJobLease(*job, time.Millisecond, cancel, stop, stopped)
+	time.Sleep(10 * time.Millisecond)
+	if ctx.Err() != nil || getJob(dbmap, "p").LeaseExpiryMs <= job.LeaseExpiryMs {
+		t.Error("lease should be renewed", ctx.Err(), getJob(dbmap, "p"))
+	}
+
+	// another worker claimed the expired lease: loading is cancelled and renewing stops
+	_, err = dbmap.Exec("UPDATE Job SET LeaseOwner=? WHERE ID=?", "other", job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("renewing should stop after losing the lease")
+	}
+	if ctx.Err() == nil {
+		t.Error("loading should be cancelled")
+	}
+
+	// the old worker cannot finish the job
+	err = s.finishJob(job, nil)
+	if err != bqdb.ErrLeaseLost {
+		t.Error(err)
+	}
+	if getJob(dbmap, "p").State != bqdb.JobRunning {
+		t.Error(getJob(dbmap, "p"))
+	}
+}
+
+func TestSaveChunk(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, jobOwner: "owner"}
+
+	err := dbmap.Insert(&bqdb.Job{UserID: 42, ProjectID: "p", State: bqdb.JobPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := bqdb.ClaimJob(dbmap, s.jobOwner, nowMs(), durationMs(jobLeaseDuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []*bigquery.Table{
+		&bigquery.Table{
+			Type: bqscrape.TypeTable,
+			TableReference: &bigquery.TableReference{
+				ProjectId: "p", DatasetId: "d", TableId: "table"},
+			TimePartitioning: &bigquery.TimePartitioning{Type: "DAY", ExpirationMs: 1000},
+			Clustering:       &bigquery.Clustering{Fields: []string{"a", "b"}},
+		},
+		&bigquery.Table{
+			Type: bqscrape.TypeTable,
+			TableReference: &bigquery.TableReference{
+				ProjectId: "p", DatasetId: "d", TableId: "range"},
+			RangePartitioning: &bigquery.RangePartitioning{Field: "id",
+				Range: &bigquery.RangePartitioningRange{Start: 0, End: 100, Interval: 10}},
+		},
+	}
+	partitions := []*bqscrape.Partition{
+		{DatasetID: "d", TableID: "table", PartitionID: "20170101", NumBytes: 5, LongTerm: true},
+	}
+	dataset := &bigquery.Dataset{
+		DatasetReference:    &bigquery.DatasetReference{ProjectId: "p", DatasetId: "d"},
+		Location:            "EU",
+		Labels:              map[string]string{"team": "data"},
+		StorageBillingModel: bqdb.StorageBillingPhysical,
+	}
+	next := bqscrape.Checkpoint{DatasetID: "d", PageToken: "page2", Tables: 1}
+	err = s.saveChunk(job, dataset, tables, partitions, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(job.CheckpointDatasetID == "d" && job.CheckpointPageToken == "page2" &&
+		job.CheckpointTables == 1 && !job.CheckpointDone) {
+		t.Error(job)
+	}
+	saved := getJob(dbmap, "p")
+	if !reflect.DeepEqual(saved, job) {
+		t.Error(saved, job)
+	}
+	datasets, err := bqdb.ListDatasets(dbmap, 42, "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(datasets) == 1 && datasets[0].DatasetID == "d" && datasets[0].Location == "EU" &&
+		datasets[0].StorageBillingModel == bqdb.StorageBillingPhysical) {
+		t.Error(datasets)
+	}
+	labels, err := bqdb.ListDatasetLabels(dbmap, 42, "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(labels) == 1 && labels[0].LabelKey == "team" && labels[0].LabelValue == "data") {
+		t.Error(labels)
+	}
+	table, err := bqdb.GetTable(dbmap, 42, "p", 0, "d", "table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(table.PartitionType == "DAY" && table.PartitionExpirationMs == 1000 &&
+		table.ClusteringFields == "a,b" && table.StorageBillingModel == bqdb.StorageBillingPhysical) {
+		t.Error(table)
+	}
+	table, err = bqdb.GetTable(dbmap, 42, "p", 0, "d", "range")
+	if !(err == nil && table.PartitionType == bqdb.PartitionTypeRange &&
+		table.PartitionField == "id") {
+		t.Error(table, err)
+	}
+	savedPartitions, err := bqdb.ListTablePartitions(dbmap, 42, "p", 0, "d", "table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(savedPartitions) == 1 && savedPartitions[0].PartitionID == "20170101" &&
+		savedPartitions[0].NumBytes == 5 && savedPartitions[0].LongTerm) {
+		t.Error(savedPartitions)
+	}
+
+	// later chunks of a dataset do not include it: tables get the saved dataset's billing model
+	later := []*bigquery.Table{{Type: bqscrape.TypeTable, TableReference: &bigquery.TableReference{
+		ProjectId: "p", DatasetId: "d", TableId: "later"}}}
+	err = s.saveChunk(job, nil, later, nil, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err = bqdb.GetTable(dbmap, 42, "p", 0, "d", "later")
+	if !(err == nil && table.StorageBillingModel == bqdb.StorageBillingPhysical) {
+		t.Error(table, err)
+	}
+
+	// another worker claimed the job: the chunk is not saved
+	lost := *job
+	lost.LeaseOwner = "other"
+	tables[0].TableReference.TableId = "table2"
+	err = s.saveChunk(&lost, nil, tables[:1], nil, bqscrape.Checkpoint{Done: true})
+	if err != bqdb.ErrLeaseLost {
+		t.Error(err)
+	}
+	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM `Table`")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Error(count)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Sums the storage and cost of the tables in a snapshot by the value of label key. Tables
+// without the label are summed in one unlabeled row.
+func queryLabelUsage(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64, projectID string,
+	snapshot *bqdb.Snapshot, key string) ([]*templates.LabelUsage, int64, error) {
+
+	rows, err := bqdb.QueryStorageByLabel(dbmap, userID, projectID, snapshot.ID, key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	type valueKey struct {
+		unlabeled bool
+		value     string
+	}
+	byValue := map[valueKey]*templates.LabelUsage{}
+	var values []*templates.LabelUsage
+	var totalBytes int64
+	for _, row := range rows {
+		k := valueKey{row.Unlabeled, row.Value}
+		usage := byValue[k]
+		if usage == nil {
+			usage = &templates.LabelUsage{Unlabeled: row.Unlabeled, Value: row.Value}
+			byValue[k] = usage
+			values = append(values, usage)
+		}
+		usage.Tables += row.Tables
+		usage.Bytes += row.Bytes
+		usage.LongTermBytes += row.LongTermBytes
+		usage.DollarsPerMonth += storageCost(prices, row.Location, row.StorageBillingModel,
+			snapshot.TimeMs, row.BilledBytes, row.BilledLongTermBytes).Total()
+		totalBytes += row.Bytes
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].DollarsPerMonth != values[j].DollarsPerMonth {
+			return values[i].DollarsPerMonth > values[j].DollarsPerMonth
+		}
+		return values[i].Value < values[j].Value
+	})
+	return values, totalBytes, nil
+}
+
+// Shows storage costs of the latest snapshot by the values of the label in the key parameter.
+// Defaults to the first label key.
+func (s *server) projectLabels(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	user, snapshot, err := getLatestSnapshot(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+
+	data := &templates.LabelReport{ProjectID: projectID}
+	data.Keys, err = bqdb.ListTableLabelKeys(s.dbmap, user.ID, projectID, snapshot.ID)
+	if err != nil {
+		return err
+	}
+	data.Key = r.FormValue("key")
+	if data.Key == "" && len(data.Keys) > 0 {
+		data.Key = data.Keys[0]
+	}
+	if data.Key != "" {
+		data.Values, data.TotalBytes, err = queryLabelUsage(s.dbmap, s.prices, user.ID, projectID,
+			snapshot, data.Key)
+		if err != nil {
+			return err
+		}
+	}
+	return templates.Labels(w, data)
+}
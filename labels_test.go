@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/pricing"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestProjectLabels(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dbmap.Insert(&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// saving tables saves their labels
+	const gib = 1024 * 1024 * 1024
+	tables := []*bigquery.Table{}
+	for _, table := range []struct {
+		id     string
+		bytes  int64
+		labels map[string]string
+	}{
+		{"a", 10 * gib, map[string]string{"team": "ads", "env": "prod"}},
+		{"b", 20 * gib, map[string]string{"team": "search"}},
+		{"c", 5 * gib, map[string]string{"team": "ads"}},
+		{"d", 1 * gib, nil},
+	} {
+		tables = append(tables, &bigquery.Table{
+			Type: bqscrape.TypeTable,
+			TableReference: &bigquery.TableReference{
+				ProjectId: "p", DatasetId: "d", TableId: table.id},
+			NumBytes: table.bytes,
+			Labels:   table.labels,
+		})
+	}
+	err = s.saveBigqueryTables(dbmap, u.ID, snapshot.ID, "", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := bqdb.ListTableLabelKeys(dbmap, u.ID, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(keys) == 2 && keys[0] == "env" && keys[1] == "team") {
+		t.Error(keys)
+	}
+
+	values, total, err := queryLabelUsage(dbmap, s.prices, u.ID, "p", snapshot, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(total == 36*gib && len(values) == 3 &&
+		values[0].Value == "search" && values[0].Tables == 1 &&
+		math.Abs(values[0].DollarsPerMonth-0.4) < 1e-9 &&
+		values[1].Value == "ads" && values[1].Tables == 2 && values[1].Bytes == 15*gib &&
+		values[2].Unlabeled && values[2].Tables == 1 && values[2].Bytes == 1*gib) {
+		t.Error(total, values)
+	}
+
+	// tables without env are unlabeled
+	values, _, err = queryLabelUsage(dbmap, s.prices, u.ID, "p", snapshot, "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(values) == 2 && values[0].Unlabeled && values[0].Tables == 3 &&
+		values[1].Value == "prod") {
+		t.Error(values)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/projects/p/labels?key=team", nil)
+	err = s.projectLabels(w, r, testIdentity(u), "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{">search<", "$0.40", "<em>unlabeled</em>", `class="is-active"`} {
+		if !strings.Contains(body, expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
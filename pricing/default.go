@@ -0,0 +1,33 @@
+package pricing
+
+// The built-in catalog, in the format read by Load. Rates are from
+// https://cloud.google.com/bigquery/pricing#storage and #on_demand_pricing ; regional storage
+// rates differ from the default multi-region rates. Add a rate with a later effective_date when
+// prices change, so old snapshots keep the prices that applied when they were scraped.
+const defaultCatalogJSON = `{
+  "version": "2023-07-05",
+  "rates": [
+    {"location": "", "billing": "logical", "class": "active",
+      "effective_date": "2011-01-01", "dollars_per_gib_month": 0.02},
+    {"location": "", "billing": "logical", "class": "long_term",
+      "effective_date": "2011-01-01", "dollars_per_gib_month": 0.01},
+    {"location": "", "billing": "physical", "class": "active",
+      "effective_date": "2023-07-05", "dollars_per_gib_month": 0.04},
+    {"location": "", "billing": "physical", "class": "long_term",
+      "effective_date": "2023-07-05", "dollars_per_gib_month": 0.02},
+
+    {"location": "asia-northeast1", "billing": "logical", "class": "active",
+      "effective_date": "2011-01-01", "dollars_per_gib_month": 0.023},
+    {"location": "asia-northeast1", "billing": "logical", "class": "long_term",
+      "effective_date": "2011-01-01", "dollars_per_gib_month": 0.016},
+    {"location": "europe-west2", "billing": "logical", "class": "active",
+      "effective_date": "2011-01-01", "dollars_per_gib_month": 0.023},
+    {"location": "europe-west2", "billing": "logical", "class": "long_term",
+      "effective_date": "2011-01-01", "dollars_per_gib_month": 0.016}
+  ],
+  "query_rates": [
+    {"location": "", "effective_date": "2011-01-01", "dollars_per_tib": 5.0},
+    {"location": "", "effective_date": "2023-07-05", "dollars_per_tib": 6.25}
+  ]
+}
+`
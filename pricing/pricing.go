@@ -0,0 +1,270 @@
+// Package pricing computes BigQuery storage and query costs from a versioned price table.
+//
+// Storage prices are the same in every BigQuery edition. Query costs use on-demand rates;
+// capacity (slot) pricing for editions is out of scope.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Billing is how a dataset's storage is measured.
+type Billing string
+
+const (
+	// Uncompressed bytes: the default.
+	Logical Billing = "logical"
+	// Compressed bytes, including time travel and fail-safe storage.
+	Physical Billing = "physical"
+)
+
+// BillingForModel returns the Billing of a dataset with storageBillingModel, which is PHYSICAL,
+// LOGICAL, or empty for datasets that use the default.
+// https://cloud.google.com/bigquery/docs/datasets-intro#dataset_storage_billing_models
+func BillingForModel(storageBillingModel string) Billing {
+	if storageBillingModel == "PHYSICAL" {
+		return Physical
+	}
+	return Logical
+}
+
+// StorageClass is the rate storage is billed at.
+type StorageClass string
+
+const (
+	Active StorageClass = "active"
+	// Tables and partitions not modified for 90 days.
+	LongTerm StorageClass = "long_term"
+)
+
+// Format of EffectiveDate.
+const dateLayout = "2006-01-02"
+
+const bytesPerGiB = 1024 * 1024 * 1024
+const bytesPerTiB = 1024 * bytesPerGiB
+
+// Rate is the price of one kind of storage from EffectiveDate until the next rate for the
+// same location, billing and class.
+type Rate struct {
+	// BigQuery location, e.g. "US", "EU" or "asia-northeast1". Empty for the default rate used
+	// by locations without their own rate.
+	Location      string       `json:"location"`
+	Billing       Billing      `json:"billing"`
+	Class         StorageClass `json:"class"`
+	EffectiveDate string       `json:"effective_date"`
+
+	DollarsPerGiBMonth float64 `json:"dollars_per_gib_month"`
+
+	effective time.Time
+}
+
+// QueryRate is the on-demand price of bytes billed by queries from EffectiveDate until the
+// next rate for the same location.
+type QueryRate struct {
+	// Empty for the default rate, as in Rate.
+	Location      string  `json:"location"`
+	EffectiveDate string  `json:"effective_date"`
+	DollarsPerTiB float64 `json:"dollars_per_tib"`
+
+	effective time.Time
+}
+
+// Catalog is a table of storage and query rates.
+type Catalog struct {
+	Version string  `json:"version"`
+	Rates   []*Rate `json:"rates"`
+	// Optional: queries cost nothing if there are no query rates.
+	QueryRates []*QueryRate `json:"query_rates"`
+}
+
+// StorageCost is the cost of storage in dollars per month.
+type StorageCost struct {
+	Active   float64
+	LongTerm float64
+}
+
+func (c StorageCost) Total() float64 {
+	return c.Active + c.LongTerm
+}
+
+func (c StorageCost) Add(other StorageCost) StorageCost {
+	return StorageCost{c.Active + other.Active, c.LongTerm + other.LongTerm}
+}
+
+// Load reads a JSON catalog. Every combination of billing and class must have a default rate.
+func Load(r io.Reader) (*Catalog, error) {
+	catalog := &Catalog{}
+	err := json.NewDecoder(r).Decode(catalog)
+	if err != nil {
+		return nil, err
+	}
+	err = catalog.init()
+	if err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// LoadFile reads a JSON catalog from path.
+func LoadFile(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("pricing: %s: %s", path, err.Error())
+	}
+	return catalog, nil
+}
+
+// Validates and indexes the rates.
+func (c *Catalog) init() error {
+	type rateKey struct {
+		billing Billing
+		class   StorageClass
+	}
+	defaults := map[rateKey]bool{}
+	for _, rate := range c.Rates {
+		if !(rate.Billing == Logical || rate.Billing == Physical) {
+			return fmt.Errorf("pricing: invalid billing %#v", rate.Billing)
+		}
+		if !(rate.Class == Active || rate.Class == LongTerm) {
+			return fmt.Errorf("pricing: invalid storage class %#v", rate.Class)
+		}
+		if rate.DollarsPerGiBMonth < 0 {
+			return fmt.Errorf("pricing: negative rate %f", rate.DollarsPerGiBMonth)
+		}
+		var err error
+		rate.effective, err = time.Parse(dateLayout, rate.EffectiveDate)
+		if err != nil {
+			return err
+		}
+		if rate.Location == "" {
+			defaults[rateKey{rate.Billing, rate.Class}] = true
+		}
+	}
+	for _, billing := range []Billing{Logical, Physical} {
+		for _, class := range []StorageClass{Active, LongTerm} {
+			if !defaults[rateKey{billing, class}] {
+				return fmt.Errorf("pricing: missing default rate for %s %s storage", billing, class)
+			}
+		}
+	}
+
+	sort.SliceStable(c.Rates, func(i, j int) bool {
+		return c.Rates[i].effective.Before(c.Rates[j].effective)
+	})
+
+	hasDefaultQueryRate := false
+	for _, rate := range c.QueryRates {
+		if rate.DollarsPerTiB < 0 {
+			return fmt.Errorf("pricing: negative query rate %f", rate.DollarsPerTiB)
+		}
+		var err error
+		rate.effective, err = time.Parse(dateLayout, rate.EffectiveDate)
+		if err != nil {
+			return err
+		}
+		if rate.Location == "" {
+			hasDefaultQueryRate = true
+		}
+	}
+	if len(c.QueryRates) > 0 && !hasDefaultQueryRate {
+		return fmt.Errorf("pricing: missing default query rate")
+	}
+	sort.SliceStable(c.QueryRates, func(i, j int) bool {
+		return c.QueryRates[i].effective.Before(c.QueryRates[j].effective)
+	})
+	return nil
+}
+
+// Returns the rate for location in effect at time at. Uses the default rate if location has
+// no rates, and the earliest rate if at is before all rates.
+func (c *Catalog) rate(location string, billing Billing, class StorageClass, at time.Time) *Rate {
+	var found *Rate
+	for _, rate := range c.Rates {
+		if !(rate.Billing == billing && rate.Class == class &&
+			strings.EqualFold(rate.Location, location)) {
+			continue
+		}
+		// rates are sorted by effective date
+		if found == nil || !rate.effective.After(at) {
+			found = rate
+		}
+	}
+	if found == nil && location != "" {
+		return c.rate("", billing, class, at)
+	}
+	return found
+}
+
+// DollarsPerGiBMonth returns the rate for storage in location at time at.
+func (c *Catalog) DollarsPerGiBMonth(location string, billing Billing, class StorageClass,
+	at time.Time) float64 {
+
+	return c.rate(location, billing, class, at).DollarsPerGiBMonth
+}
+
+// StorageCost returns the monthly cost of bytes stored in location at the rates in effect at
+// time at. bytes includes longTermBytes, as in BigQuery's table resource.
+func (c *Catalog) StorageCost(location string, billing Billing, at time.Time, bytes int64,
+	longTermBytes int64) StorageCost {
+
+	active := c.DollarsPerGiBMonth(location, billing, Active, at)
+	longTerm := c.DollarsPerGiBMonth(location, billing, LongTerm, at)
+	return StorageCost{
+		Active:   float64(bytes-longTermBytes) * active / bytesPerGiB,
+		LongTerm: float64(longTermBytes) * longTerm / bytesPerGiB,
+	}
+}
+
+// Returns the query rate for location in effect at time at, with the same fallbacks as rate.
+// Returns nil if there are no query rates.
+func (c *Catalog) queryRate(location string, at time.Time) *QueryRate {
+	var found *QueryRate
+	for _, rate := range c.QueryRates {
+		if !strings.EqualFold(rate.Location, location) {
+			continue
+		}
+		if found == nil || !rate.effective.After(at) {
+			found = rate
+		}
+	}
+	if found == nil && location != "" {
+		return c.queryRate("", at)
+	}
+	return found
+}
+
+// QueryCost returns the on-demand cost in dollars of a query in location at time at that billed
+// bytesBilled.
+func (c *Catalog) QueryCost(location string, at time.Time, bytesBilled int64) float64 {
+	rate := c.queryRate(location, at)
+	if rate == nil {
+		return 0
+	}
+	return float64(bytesBilled) * rate.DollarsPerTiB / bytesPerTiB
+}
+
+// Default returns the built-in catalog. It must not be modified.
+func Default() *Catalog {
+	return defaultCatalog
+}
+
+var defaultCatalog = mustLoad(defaultCatalogJSON)
+
+func mustLoad(catalogJSON string) *Catalog {
+	catalog, err := Load(strings.NewReader(catalogJSON))
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
@@ -0,0 +1,160 @@
+package pricing
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCatalog = `{
+  "version": "test",
+  "rates": [
+    {"location": "", "billing": "logical", "class": "active",
+      "effective_date": "2016-01-01", "dollars_per_gib_month": 0.02},
+    {"location": "", "billing": "logical", "class": "long_term",
+      "effective_date": "2016-01-01", "dollars_per_gib_month": 0.01},
+    {"location": "", "billing": "physical", "class": "active",
+      "effective_date": "2016-01-01", "dollars_per_gib_month": 0.04},
+    {"location": "", "billing": "physical", "class": "long_term",
+      "effective_date": "2016-01-01", "dollars_per_gib_month": 0.02},
+    {"location": "tokyo", "billing": "logical", "class": "active",
+      "effective_date": "2017-01-01", "dollars_per_gib_month": 0.05},
+    {"location": "tokyo", "billing": "logical", "class": "active",
+      "effective_date": "2016-01-01", "dollars_per_gib_month": 0.03}
+  ]
+}`
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRates(t *testing.T) {
+	catalog, err := Load(strings.NewReader(testCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		location string
+		billing  Billing
+		class    StorageClass
+		at       string
+		rate     float64
+	}{
+		{"US", Logical, Active, "2016-06-01", 0.02},
+		{"US", Logical, LongTerm, "2016-06-01", 0.01},
+		{"US", Physical, Active, "2016-06-01", 0.04},
+		// before the first rate: uses the first rate
+		{"US", Logical, Active, "2010-01-01", 0.02},
+		{"tokyo", Logical, Active, "2016-06-01", 0.03},
+		{"tokyo", Logical, Active, "2017-01-01", 0.05},
+		{"TOKYO", Logical, Active, "2018-01-01", 0.05},
+		// tokyo has no long-term rate: uses the default
+		{"tokyo", Logical, LongTerm, "2018-01-01", 0.01},
+	}
+	for i, test := range tests {
+		rate := catalog.DollarsPerGiBMonth(test.location, test.billing, test.class, date(test.at))
+		if rate != test.rate {
+			t.Errorf("%d: DollarsPerGiBMonth(%s, %s, %s, %s) = %f ; expected %f",
+				i, test.location, test.billing, test.class, test.at, rate, test.rate)
+		}
+	}
+}
+
+func TestStorageCost(t *testing.T) {
+	catalog, err := Load(strings.NewReader(testCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := catalog.StorageCost("US", Logical, date("2016-06-01"), 100*bytesPerGiB, 40*bytesPerGiB)
+	if !(math.Abs(cost.Active-1.2) < 1e-9 && math.Abs(cost.LongTerm-0.4) < 1e-9 &&
+		math.Abs(cost.Total()-1.6) < 1e-9) {
+		t.Error(cost)
+	}
+	total := cost.Add(StorageCost{1, 2})
+	if !(math.Abs(total.Active-2.2) < 1e-9 && math.Abs(total.LongTerm-2.4) < 1e-9) {
+		t.Error(total)
+	}
+}
+
+func TestBillingForModel(t *testing.T) {
+	if !(BillingForModel("PHYSICAL") == Physical && BillingForModel("LOGICAL") == Logical &&
+		BillingForModel("") == Logical) {
+		t.Error(BillingForModel("PHYSICAL"), BillingForModel("LOGICAL"), BillingForModel(""))
+	}
+}
+
+func TestQueryCost(t *testing.T) {
+	// no query rates: queries are free
+	catalog, err := Load(strings.NewReader(testCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := catalog.QueryCost("US", date("2016-06-01"), bytesPerTiB)
+	if cost != 0 {
+		t.Error(cost)
+	}
+
+	catalog, err = Load(strings.NewReader(strings.Replace(testCatalog, `]
+}`, `], "query_rates": [
+		{"location": "", "effective_date": "2017-01-01", "dollars_per_tib": 6},
+		{"location": "", "effective_date": "2016-01-01", "dollars_per_tib": 5},
+		{"location": "eu", "effective_date": "2016-01-01", "dollars_per_tib": 7}]}`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		location string
+		at       string
+		expected float64
+	}{
+		{"US", "2016-06-01", 2.5},
+		{"US", "2018-01-01", 3},
+		{"US", "2015-01-01", 2.5},
+		{"EU", "2018-01-01", 3.5},
+	} {
+		cost := catalog.QueryCost(test.location, date(test.at), bytesPerTiB/2)
+		if math.Abs(cost-test.expected) > 1e-9 {
+			t.Error(test, cost)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for i, catalogJSON := range []string{
+		`{"rates": [{"billing": "logical", "class": "active", "effective_date": "2016-01-01"}]}`,
+		`{"rates": [{"billing": "bad", "class": "active", "effective_date": "2016-01-01"}]}`,
+		`{"rates": [{"billing": "logical", "class": "bad", "effective_date": "2016-01-01"}]}`,
+		`{"rates": [{"billing": "logical", "class": "active", "effective_date": "January"}]}`,
+		`not json`,
+		strings.Replace(testCatalog, `]
+}`, `], "query_rates": [{"location": "EU", "effective_date": "2016-01-01"}]}`, 1),
+		strings.Replace(testCatalog, `]
+}`, `], "query_rates": [{"location": "", "effective_date": "2016-01-01", "dollars_per_tib": -1}]}`, 1),
+	} {
+		_, err := Load(strings.NewReader(catalogJSON))
+		if err == nil {
+			t.Error(i, "expected error")
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	catalog := Default()
+	if catalog.Version == "" {
+		t.Error(catalog)
+	}
+	rate := catalog.DollarsPerGiBMonth("US", Logical, Active, time.Now())
+	if rate != 0.02 {
+		t.Error(rate)
+	}
+	cost := catalog.QueryCost("US", time.Now(), bytesPerTiB)
+	if cost != 6.25 {
+		t.Error(cost)
+	}
+}
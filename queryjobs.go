@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/sqlfingerprint"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Query jobs are loaded and reported for this long before each load or snapshot.
+const queryJobHistory = 30 * 24 * time.Hour
+
+// Queries can run for 6 hours and are only listed when they finish: re-load this much before
+// the last load to find queries that were running.
+const queryJobOverlap = 6 * time.Hour
+
+// Maximum number of query fingerprints listed on the queries page.
+const maxFingerprintResults = 200
+
+// Sorts for the queries page sort parameter.
+var fingerprintOrders = map[string]bqdb.SpendOrder{
+	"bytes": bqdb.OrderByBytesBilled,
+	"jobs":  bqdb.OrderByJobs,
+	"cost":  bqdb.OrderByDollars,
+}
+
+// Lists query jobs created between min and max, calling save with each page. Returns true if
+// it stopped before listing the oldest jobs.
+type queryJobLister func(min time.Time, max time.Time, save bqscrape.QueryJobSaver) (bool, error)
+
+// Saves a page of query jobs and the tables they read.
+func saveQueryJobs(executor gorp.SqlExecutor, prices *pricing.Catalog, userID int64,
+	projectID string, jobs []*bqscrape.QueryJob) error {
+
+	var rows []interface{}
+	for _, job := range jobs {
+		rows = append(rows, &bqdb.QueryJob{
+			UserID:              userID,
+			ProjectID:           projectID,
+			JobID:               job.JobID,
+			Location:            job.Location,
+			UserEmail:           job.UserEmail,
+			Query:               truncate(job.Query, bqdb.MaxQueryLength),
+			QueryFingerprint:    sqlfingerprint.Fingerprint(job.Query),
+			StatementType:       job.StatementType,
+			CreationTimeMs:      job.CreationTimeMs,
+			TotalBytesBilled:    job.TotalBytesBilled,
+			TotalBytesProcessed: job.TotalBytesProcessed,
+			TotalSlotMs:         job.TotalSlotMs,
+			CacheHit:            job.CacheHit,
+			DollarsBilled: prices.QueryCost(job.Location,
+				time.Unix(0, job.CreationTimeMs*int64(time.Millisecond)), job.TotalBytesBilled),
+		})
+		// the API can list a table more than once
+		referenced := map[string]bool{}
+		for _, table := range job.ReferencedTables {
+			if referenced[table] {
+				continue
+			}
+			referenced[table] = true
+			rows = append(rows, &bqdb.QueryJobTable{UserID: userID, ProjectID: projectID,
+				JobID: job.JobID, ReferencedTable: table, CreationTimeMs: job.CreationTimeMs})
+		}
+	}
+	return executor.Insert(rows...)
+}
+
+// Loads the query jobs created since the last load, or in the last queryJobHistory. If this
+// fails, the next load starts from the same time.
+func (s *server) loadQueryJobs(userID int64, projectID string, now time.Time,
+	list queryJobLister) error {
+
+	project, err := bqdb.GetProjectByID(s.dbmap, userID, projectID)
+	if err != nil {
+		return err
+	}
+	min := now.Add(-queryJobHistory)
+	if project != nil && project.QueryJobsLoadedMs > 0 {
+		loaded := time.Unix(0, project.QueryJobsLoadedMs*int64(time.Millisecond))
+		if loaded.Add(-queryJobOverlap).After(min) {
+			min = loaded.Add(-queryJobOverlap)
+		}
+	}
+	minMs := min.UnixNano() / int64(time.Millisecond)
+
+	// replace the jobs since min with the first page, so a failed list keeps the old jobs
+	saved := 0
+	deleted := false
+	save := func(jobs []*bqscrape.QueryJob) error {
+		txn, err := s.dbmap.Begin()
+		if err != nil {
+			return err
+		}
+		// don't forget to rollback
+		defer txn.Rollback()
+		if !deleted {
+			err = bqdb.DeleteQueryJobsSince(txn, userID, projectID, minMs)
+			if err != nil {
+				return err
+			}
+		}
+		err = saveQueryJobs(txn, s.prices, userID, projectID, jobs)
+		if err != nil {
+			return err
+		}
+		err = txn.Commit()
+		if err != nil {
+			return err
+		}
+		deleted = true
+		saved += len(jobs)
+		return nil
+	}
+	truncated, err := list(min, now, save)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		err = save(nil)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("bqcost: uid %d project %s: loaded %d query jobs since %s; truncated:%t",
+		userID, projectID, saved, min.UTC().Format(time.RFC3339), truncated)
+	// jobs missing from an earlier load stay missing until a load covers the whole history
+	if project != nil && project.QueryJobsTruncated && min.After(now.Add(-queryJobHistory)) {
+		truncated = true
+	}
+	return bqdb.SetQueryJobsLoaded(s.dbmap, userID, projectID,
+		now.UnixNano()/int64(time.Millisecond), truncated)
+}
+
+func newQuerySpend(spend *bqdb.QuerySpend) *templates.QuerySpend {
+	return &templates.QuerySpend{Name: spend.Name, Example: spend.Example, Jobs: spend.Jobs,
+		BytesBilled: spend.BytesBilled, SlotMs: spend.SlotMs, Dollars: spend.Dollars}
+}
+
+func newQuerySpends(spends []*bqdb.QuerySpend) []*templates.QuerySpend {
+	output := make([]*templates.QuerySpend, len(spends))
+	for i, spend := range spends {
+		output[i] = newQuerySpend(spend)
+	}
+	return output
+}
+
+// Returns the most expensive users, queries and tables for queries in the queryJobHistory
+// before snapshot. Returns nil if query jobs have never been loaded.
+func queryQueryCosts(dbmap *gorp.DbMap, userID int64, project *bqdb.Project,
+	snapshot *bqdb.Snapshot) (*templates.QueryCosts, error) {
+
+	if project.QueryJobsLoadedMs == 0 {
+		return nil, nil
+	}
+	minMs := snapshot.TimeMs - durationMs(queryJobHistory)
+	total, err := bqdb.QueryTotalSpend(dbmap, userID, project.ProjectID, minMs)
+	if err != nil {
+		return nil, err
+	}
+	users, err := bqdb.QuerySpendByUser(dbmap, userID, project.ProjectID, minMs, maxTopResults)
+	if err != nil {
+		return nil, err
+	}
+	queries, err := bqdb.QuerySpendByFingerprint(dbmap, userID, project.ProjectID, minMs,
+		bqdb.OrderByDollars, maxTopResults)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := bqdb.QuerySpendByTable(dbmap, userID, project.ProjectID, minMs, maxTopResults)
+	if err != nil {
+		return nil, err
+	}
+	return &templates.QueryCosts{
+		Days:      int(queryJobHistory / (24 * time.Hour)),
+		Total:     newQuerySpend(total),
+		Users:     newQuerySpends(users),
+		Queries:   newQuerySpends(queries),
+		Tables:    newQuerySpends(tables),
+		Truncated: project.QueryJobsTruncated,
+	}, nil
+}
+
+// Lists the repeated queries in the queryJobHistory before the latest snapshot, grouped by
+// their normalized SQL. The sort parameter is one of the keys of fingerprintOrders, and
+// defaults to bytes billed.
+func (s *server) projectQueries(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	data := &templates.QueryFingerprints{ProjectID: projectID,
+		Days: int(queryJobHistory / (24 * time.Hour)), Sort: r.FormValue("sort")}
+	if data.Sort == "" {
+		data.Sort = "bytes"
+	}
+	order, ok := fingerprintOrders[data.Sort]
+	if !ok {
+		return fmt.Errorf("bqcost: invalid sort %#v", data.Sort)
+	}
+
+	user, snapshot, err := getLatestSnapshot(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+	project, err := bqdb.GetProjectByID(s.dbmap, user.ID, projectID)
+	if err != nil {
+		return err
+	}
+	data.Loaded = project.QueryJobsLoadedMs != 0
+	data.Truncated = project.QueryJobsTruncated
+	minMs := snapshot.TimeMs - durationMs(queryJobHistory)
+	queries, err := bqdb.QuerySpendByFingerprint(s.dbmap, user.ID, projectID, minMs, order,
+		maxFingerprintResults)
+	if err != nil {
+		return err
+	}
+	data.Queries = newQuerySpends(queries)
+	for _, query := range data.Queries {
+		query.Normalized = sqlfingerprint.Normalize(query.Example)
+	}
+	return templates.Queries(w, data)
+}
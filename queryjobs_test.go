@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/pricing"
+)
+
+func TestLoadQueryJobs(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dbmap.Insert(&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const tib = 1024 * 1024 * 1024 * 1024
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	jobs := []*bqscrape.QueryJob{
+		{JobID: "a", UserEmail: "a@example.com", Query: "SELECT * FROM d.t",
+			CreationTimeMs: nowMs - 1000, TotalBytesBilled: tib,
+			ReferencedTables: []string{"p:d.t", "p:d.t"}},
+		{JobID: "b", UserEmail: "b@example.com", Query: "SELECT 1", CreationTimeMs: nowMs - 2000,
+			TotalBytesBilled: 2 * tib},
+		{JobID: "c", UserEmail: "b@example.com", Query: "select 2 -- again",
+			CreationTimeMs: nowMs - 3000},
+	}
+	var listedMin, listedMax time.Time
+	var listErr error
+	listTruncated := false
+	list := func(min time.Time, max time.Time, save bqscrape.QueryJobSaver) (bool, error) {
+		listedMin = min
+		listedMax = max
+		if listErr != nil {
+			return false, listErr
+		}
+		return listTruncated, save(jobs)
+	}
+
+	// never loaded: loads the full history
+	err = s.loadQueryJobs(u.ID, "p", now, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(listedMin.Equal(now.Add(-queryJobHistory)) && listedMax.Equal(now)) {
+		t.Error(listedMin, listedMax)
+	}
+	total, err := bqdb.QueryTotalSpend(dbmap, u.ID, "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(total.Jobs == 3 && total.BytesBilled == 3*tib && math.Abs(total.Dollars-18.75) < 1e-9) {
+		t.Error(total)
+	}
+
+	// loading again only re-loads the overlap, without duplicating jobs
+	later := now.Add(time.Hour)
+	err = s.loadQueryJobs(u.ID, "p", later, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !listedMin.Equal(now.Add(-queryJobOverlap)) {
+		t.Error(listedMin)
+	}
+	total, err = bqdb.QueryTotalSpend(dbmap, u.ID, "p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Jobs != 3 {
+		t.Error(total)
+	}
+
+	// failures do not advance the loaded time or delete jobs
+	listErr = errors.New("list failed")
+	err = s.loadQueryJobs(u.ID, "p", later.Add(time.Hour), list)
+	if err != listErr {
+		t.Error(err)
+	}
+	project, err := bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.QueryJobsLoadedMs != later.UnixNano()/int64(time.Millisecond) {
+		t.Error(project)
+	}
+
+	// the project page shows the costs before the snapshot
+	snapshot.TimeMs = nowMs
+	_, err = dbmap.Update(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := queryProject(dbmap, s.prices, u.ID, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	costs := data.QueryCosts
+	if !(costs.Days == 30 && costs.Total.Jobs == 3 && len(costs.Users) == 2 &&
+		costs.Users[0].Name == "b@example.com" && len(costs.Queries) == 2 &&
+		costs.Queries[0].Jobs == 2 && len(costs.Tables) == 1 &&
+		costs.Tables[0].Name == "p:d.t" && costs.Tables[0].Jobs == 1) {
+		t.Error(costs)
+	}
+
+	w := httptest.NewRecorder()
+	err = s.projectIndex(w, httptest.NewRequest("GET", "/projects/p", nil),
+		testIdentity(u), "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"Query Costs: Last 30 Days", "$12.50", "b@example.com",
+		"<code>select 2 -- again</code>"} {
+		if !strings.Contains(body, expected) {
+			t.Error("missing", expected)
+		}
+	}
+
+	// repeated queries are grouped by their normalized text
+	w = httptest.NewRecorder()
+	err = s.projectQueries(w, httptest.NewRequest("GET", "/projects/p/queries?sort=jobs", nil),
+		testIdentity(u), "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = w.Body.String()
+	if !(strings.Contains(body, "<td>\n              <code>SELECT ?</code>") &&
+		strings.Contains(body, "<strong>runs</strong>")) {
+		t.Error(body)
+	}
+	err = s.projectQueries(httptest.NewRecorder(),
+		httptest.NewRequest("GET", "/projects/p/queries?sort=x", nil),
+		testIdentity(u), "p")
+	if err == nil {
+		t.Error("expected error for invalid sort")
+	}
+
+	// a truncated load still advances the loaded time, and is recorded until a full load
+	listErr = nil
+	listTruncated = true
+	truncatedTime := later.Add(time.Hour)
+	err = s.loadQueryJobs(u.ID, "p", truncatedTime, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err = bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(project.QueryJobsLoadedMs == truncatedTime.UnixNano()/int64(time.Millisecond) &&
+		project.QueryJobsTruncated) {
+		t.Error(project)
+	}
+	w = httptest.NewRecorder()
+	err = s.projectQueries(w, httptest.NewRequest("GET", "/projects/p/queries", nil),
+		testIdentity(u), "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.Body.String(), "too many queries") {
+		t.Error(w.Body.String())
+	}
+
+	listTruncated = false
+	err = s.loadQueryJobs(u.ID, "p", truncatedTime.Add(time.Hour), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err = bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil || !project.QueryJobsTruncated {
+		t.Error(project, err)
+	}
+	// the jobs are now older than the whole history
+	jobs = nil
+	err = s.loadQueryJobs(u.ID, "p", truncatedTime.Add(queryJobHistory), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err = bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil || project.QueryJobsTruncated {
+		t.Error(project, err)
+	}
+}
@@ -0,0 +1,287 @@
+// Package recommend finds ways to reduce the cost of a BigQuery project from its scraped
+// datasets, tables and query history.
+package recommend
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Identifies the rule that produced a Finding. Saved in the database: do not change.
+const (
+	RulePartitionExpiration = "partition_expiration"
+	RuleDatasetExpiration   = "dataset_expiration"
+	RuleFullScans           = "unpartitioned_full_scans"
+	RuleDuplicateTable      = "duplicate_table"
+	RuleStaleStreaming      = "stale_streaming_buffer"
+)
+
+// Title returns a short description of rule.
+func Title(rule string) string {
+	switch rule {
+	case RulePartitionExpiration:
+		return "Set a partition expiration"
+	case RuleDatasetExpiration:
+		return "Set a default table expiration"
+	case RuleFullScans:
+		return "Partition a frequently scanned table"
+	case RuleDuplicateTable:
+		return "Delete a duplicate table"
+	case RuleStaleStreaming:
+		return "Check a stale streaming buffer"
+	}
+	return rule
+}
+
+// Tables at least this large are worth partitioning.
+const hugeTableBytes = 100 * 1024 * 1024 * 1024
+
+// Unpartitioned tables read by at least this many queries that each read at least
+// fullScanFraction of the table are frequently full-scanned.
+const (
+	frequentScans    = 10
+	fullScanFraction = 0.5
+)
+
+// Guess at the fraction of a full scan that partitioning or clustering avoids.
+const partitionedScanSavings = 0.5
+
+// Tables smaller than this are not reported as duplicates: identical small tables are common
+// and cheap.
+const minDuplicateBytes = 100 * 1024 * 1024
+
+// A streaming buffer in a table not modified for this long is stale.
+const staleStreamingAge = 7 * 24 * time.Hour
+
+// Finding is one recommendation for a project.
+type Finding struct {
+	// One of the Rule constants.
+	Rule string
+	// The dataset or dataset.table the finding applies to. With Rule, identifies the finding
+	// in all snapshots.
+	Resource string
+	Message  string
+	// Estimated savings if the recommendation is followed.
+	DollarsPerMonth float64
+}
+
+// Inventory is a scraped project snapshot and its query history.
+type Inventory struct {
+	ProjectID string
+	// When the snapshot was scraped; costs use the prices in effect at this time.
+	TimeMs   int64
+	Prices   *pricing.Catalog
+	Datasets []*bqdb.Dataset
+	Tables   []*bqdb.Table
+	// The cost of queries that read each table, keyed by project:dataset.table. Nil if query
+	// jobs were not loaded.
+	Reads map[string]*bqdb.QuerySpend
+}
+
+// Returns the cost of bytes of table's storage in its dataset's billing model.
+func (inv *Inventory) storageCost(table *bqdb.Table, bytes int64, longTermBytes int64) float64 {
+	at := time.Unix(0, inv.TimeMs*int64(time.Millisecond))
+	billing := pricing.BillingForModel(table.StorageBillingModel)
+	return inv.Prices.StorageCost(table.Location, billing, at, bytes, longTermBytes).Total()
+}
+
+func (inv *Inventory) tableCost(table *bqdb.Table) float64 {
+	bytes, longTermBytes := table.BilledBytes()
+	return inv.storageCost(table, bytes, longTermBytes)
+}
+
+// Returns the cost of the table's long-term storage.
+func (inv *Inventory) longTermCost(table *bqdb.Table) float64 {
+	_, longTermBytes := table.BilledBytes()
+	return inv.storageCost(table, longTermBytes, longTermBytes)
+}
+
+func tableResource(table *bqdb.Table) string {
+	return table.DatasetID + "." + table.TableID
+}
+
+// Rule returns the findings for one kind of problem.
+type Rule func(inv *Inventory) []*Finding
+
+// Rules are all the rules run by Run.
+var Rules = []Rule{
+	PartitionExpiration,
+	DatasetExpiration,
+	FullScans,
+	DuplicateTables,
+	StaleStreaming,
+}
+
+// Run returns the findings of all Rules, largest savings first.
+func Run(inv *Inventory) []*Finding {
+	var findings []*Finding
+	for _, rule := range Rules {
+		findings = append(findings, rule(inv)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].DollarsPerMonth > findings[j].DollarsPerMonth
+	})
+	return findings
+}
+
+// PartitionExpiration finds partitioned tables whose partitions never expire, and have
+// partitions that were not modified for 90 days. An expiration of 90 days or less would delete
+// them. Integer range partitions can't expire.
+func PartitionExpiration(inv *Inventory) []*Finding {
+	var findings []*Finding
+	for _, table := range inv.Tables {
+		if !hasTimePartitions(table) || table.PartitionExpirationMs != 0 ||
+			table.NumLongTermBytes == 0 {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Rule:     RulePartitionExpiration,
+			Resource: tableResource(table),
+			Message: fmt.Sprintf("Partitions never expire, and %s are in partitions not modified "+
+				"in 90 days. Set a partition expiration if old partitions are not needed.",
+				templates.HumanBytes(table.NumLongTermBytes)),
+			DollarsPerMonth: inv.longTermCost(table),
+		})
+	}
+	return findings
+}
+
+func hasTimePartitions(table *bqdb.Table) bool {
+	return table.PartitionType != "" && table.PartitionType != bqdb.PartitionTypeRange
+}
+
+// DatasetExpiration finds datasets without a default table expiration that contain tables not
+// modified for 90 days. The default only applies to new tables, so the savings are the cost of
+// the old tables that an expiration would have deleted.
+func DatasetExpiration(inv *Inventory) []*Finding {
+	longTerm := map[string]float64{}
+	tables := map[string]int{}
+	for _, table := range inv.Tables {
+		// long-term partitions are handled by PartitionExpiration
+		if hasTimePartitions(table) || table.NumLongTermBytes == 0 {
+			continue
+		}
+		longTerm[table.DatasetID] += inv.longTermCost(table)
+		tables[table.DatasetID]++
+	}
+
+	var findings []*Finding
+	for _, dataset := range inv.Datasets {
+		if dataset.DefaultTableExpirationMs != 0 || tables[dataset.DatasetID] == 0 {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Rule:     RuleDatasetExpiration,
+			Resource: dataset.DatasetID,
+			Message: fmt.Sprintf("Tables never expire by default, and %d tables were not modified "+
+				"in 90 days. Set a default table expiration if tables in this dataset are temporary.",
+				tables[dataset.DatasetID]),
+			DollarsPerMonth: longTerm[dataset.DatasetID],
+		})
+	}
+	return findings
+}
+
+// FullScans finds huge unpartitioned tables that many queries read most of. Partitioning or
+// clustering the table would let queries read less.
+func FullScans(inv *Inventory) []*Finding {
+	var findings []*Finding
+	for _, table := range inv.Tables {
+		if table.PartitionType != "" || table.ClusteringFields != "" ||
+			table.NumBytes < hugeTableBytes {
+			continue
+		}
+		reads := inv.Reads[inv.ProjectID+":"+tableResource(table)]
+		if reads == nil || reads.Jobs < frequentScans {
+			continue
+		}
+		averageBytes := reads.BytesBilled / reads.Jobs
+		if float64(averageBytes) < fullScanFraction*float64(table.NumBytes) {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Rule:     RuleFullScans,
+			Resource: tableResource(table),
+			Message: fmt.Sprintf("%d queries read an average of %s from this %s table, which is not "+
+				"partitioned or clustered. Partition or cluster it by the columns queries filter on.",
+				reads.Jobs, templates.HumanBytes(averageBytes), templates.HumanBytes(table.NumBytes)),
+			DollarsPerMonth: reads.Dollars * partitionedScanSavings,
+		})
+	}
+	return findings
+}
+
+// DuplicateTables finds tables with exactly the same number of bytes and rows as an older
+// table, which are probably copies. Snapshots and clones are excluded, since they are only
+// billed for the data that differs from their base table.
+func DuplicateTables(inv *Inventory) []*Finding {
+	type size struct {
+		bytes int64
+		rows  int64
+	}
+	bySize := map[size][]*bqdb.Table{}
+	var sizes []size
+	for _, table := range inv.Tables {
+		if table.NumBytes < minDuplicateBytes || table.BaseTable != "" {
+			continue
+		}
+		key := size{table.NumBytes, table.NumRows}
+		if bySize[key] == nil {
+			sizes = append(sizes, key)
+		}
+		bySize[key] = append(bySize[key], table)
+	}
+
+	var findings []*Finding
+	for _, key := range sizes {
+		tables := bySize[key]
+		if len(tables) < 2 {
+			continue
+		}
+		// the oldest table is the original
+		sort.SliceStable(tables, func(i, j int) bool {
+			return tables[i].CreationTimeMs < tables[j].CreationTimeMs
+		})
+		original := tableResource(tables[0])
+		for _, table := range tables[1:] {
+			findings = append(findings, &Finding{
+				Rule:     RuleDuplicateTable,
+				Resource: tableResource(table),
+				Message: fmt.Sprintf("Has the same size (%s) and number of rows (%d) as the older "+
+					"table %s, so it may be a copy. Delete it if it is not needed.",
+					templates.HumanBytes(table.NumBytes), table.NumRows, original),
+				DollarsPerMonth: inv.tableCost(table),
+			})
+		}
+	}
+	return findings
+}
+
+// StaleStreaming finds tables with rows in the streaming buffer that were not modified for
+// staleStreamingAge. Streamed rows are usually written to the table within a few hours, so
+// the writer may have been abandoned. The savings are the cost of storing the buffered rows.
+func StaleStreaming(inv *Inventory) []*Finding {
+	var findings []*Finding
+	staleMs := inv.TimeMs - int64(staleStreamingAge/time.Millisecond)
+	for _, table := range inv.Tables {
+		if table.StreamingEstimatedRows == 0 || table.LastModifiedTimeMs >= staleMs {
+			continue
+		}
+		lastModified := time.Unix(0, table.LastModifiedTimeMs*int64(time.Millisecond)).UTC()
+		findings = append(findings, &Finding{
+			Rule:     RuleStaleStreaming,
+			Resource: tableResource(table),
+			Message: fmt.Sprintf("The streaming buffer has %d rows (%s), but the table was last "+
+				"modified on %s. Check the program that streams rows into this table.",
+				table.StreamingEstimatedRows, templates.HumanBytes(table.StreamingEstimatedBytes),
+				lastModified.Format("2006-01-02")),
+			DollarsPerMonth: inv.storageCost(table, table.StreamingEstimatedBytes, 0),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,186 @@
+package recommend
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+)
+
+const gib = 1024 * 1024 * 1024
+const dayMs = int64(24 * time.Hour / time.Millisecond)
+
+func newInventory(tables ...*bqdb.Table) *Inventory {
+	return &Inventory{
+		ProjectID: "p",
+		TimeMs:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond),
+		Prices:    pricing.Default(),
+		Tables:    tables,
+	}
+}
+
+func findingsString(findings []*Finding) string {
+	var out []string
+	for _, finding := range findings {
+		out = append(out, finding.Rule+" "+finding.Resource)
+	}
+	return strings.Join(out, ", ")
+}
+
+func TestPartitionExpiration(t *testing.T) {
+	inv := newInventory(
+		&bqdb.Table{DatasetID: "d", TableID: "expires", PartitionType: "DAY",
+			PartitionExpirationMs: 30 * dayMs, NumBytes: 2 * gib, NumLongTermBytes: gib},
+		&bqdb.Table{DatasetID: "d", TableID: "new", PartitionType: "DAY", NumBytes: gib},
+		&bqdb.Table{DatasetID: "d", TableID: "old", PartitionType: "DAY", NumBytes: 200 * gib,
+			NumLongTermBytes: 100 * gib},
+		&bqdb.Table{DatasetID: "d", TableID: "range", PartitionType: bqdb.PartitionTypeRange,
+			NumBytes: 200 * gib, NumLongTermBytes: 100 * gib},
+		&bqdb.Table{DatasetID: "d", TableID: "unpartitioned", NumBytes: gib,
+			NumLongTermBytes: gib},
+	)
+	findings := PartitionExpiration(inv)
+	if findingsString(findings) != "partition_expiration d.old" {
+		t.Fatal(findingsString(findings))
+	}
+	// 100 GiB of long-term storage at $0.01/GiB
+	if math.Abs(findings[0].DollarsPerMonth-1.0) > 1e-9 {
+		t.Error(findings[0].DollarsPerMonth)
+	}
+	if !strings.Contains(findings[0].Message, "100.0 GiB") {
+		t.Error(findings[0].Message)
+	}
+}
+
+func TestPhysicalBilling(t *testing.T) {
+	inv := newInventory(&bqdb.Table{DatasetID: "d", TableID: "t", PartitionType: "DAY",
+		NumBytes: 200 * gib, NumLongTermBytes: 100 * gib, NumActivePhysicalBytes: 20 * gib,
+		NumLongTermPhysicalBytes: 10 * gib, StorageBillingModel: bqdb.StorageBillingPhysical})
+	findings := PartitionExpiration(inv)
+	if findingsString(findings) != "partition_expiration d.t" {
+		t.Fatal(findingsString(findings))
+	}
+	// 10 GiB of compressed long-term storage at $0.02/GiB
+	if math.Abs(findings[0].DollarsPerMonth-0.2) > 1e-9 {
+		t.Error(findings[0].DollarsPerMonth)
+	}
+}
+
+func TestDatasetExpiration(t *testing.T) {
+	inv := newInventory(
+		&bqdb.Table{DatasetID: "expires", TableID: "t", NumBytes: gib, NumLongTermBytes: gib},
+		&bqdb.Table{DatasetID: "new", TableID: "t", NumBytes: gib},
+		&bqdb.Table{DatasetID: "old", TableID: "a", NumBytes: 10 * gib, NumLongTermBytes: 10 * gib},
+		&bqdb.Table{DatasetID: "old", TableID: "b", NumBytes: 10 * gib, NumLongTermBytes: 10 * gib},
+	)
+	inv.Datasets = []*bqdb.Dataset{
+		{DatasetID: "expires", DefaultTableExpirationMs: dayMs},
+		{DatasetID: "new"},
+		{DatasetID: "old"},
+	}
+	findings := DatasetExpiration(inv)
+	if findingsString(findings) != "dataset_expiration old" {
+		t.Fatal(findingsString(findings))
+	}
+	if math.Abs(findings[0].DollarsPerMonth-0.2) > 1e-9 ||
+		!strings.Contains(findings[0].Message, "2 tables") {
+		t.Error(findings[0])
+	}
+}
+
+func TestFullScans(t *testing.T) {
+	inv := newInventory(
+		&bqdb.Table{DatasetID: "d", TableID: "scanned", NumBytes: 200 * gib},
+		&bqdb.Table{DatasetID: "d", TableID: "filtered", NumBytes: 200 * gib},
+		&bqdb.Table{DatasetID: "d", TableID: "rare", NumBytes: 200 * gib},
+		&bqdb.Table{DatasetID: "d", TableID: "partitioned", NumBytes: 200 * gib,
+			PartitionType: "DAY"},
+		&bqdb.Table{DatasetID: "d", TableID: "small", NumBytes: gib},
+	)
+	inv.Reads = map[string]*bqdb.QuerySpend{
+		"p:d.scanned":     {Jobs: 20, BytesBilled: 20 * 150 * gib, Dollars: 100},
+		"p:d.filtered":    {Jobs: 20, BytesBilled: 20 * gib, Dollars: 1},
+		"p:d.rare":        {Jobs: 2, BytesBilled: 2 * 200 * gib, Dollars: 10},
+		"p:d.partitioned": {Jobs: 20, BytesBilled: 20 * 200 * gib, Dollars: 100},
+		"p:d.small":       {Jobs: 20, BytesBilled: 20 * gib, Dollars: 1},
+	}
+	findings := FullScans(inv)
+	if findingsString(findings) != "unpartitioned_full_scans d.scanned" {
+		t.Fatal(findingsString(findings))
+	}
+	if findings[0].DollarsPerMonth != 50 || !strings.Contains(findings[0].Message, "150.0 GiB") {
+		t.Error(findings[0])
+	}
+
+	// no query history
+	inv.Reads = nil
+	findings = FullScans(inv)
+	if len(findings) != 0 {
+		t.Error(findingsString(findings))
+	}
+}
+
+func TestDuplicateTables(t *testing.T) {
+	inv := newInventory(
+		&bqdb.Table{DatasetID: "d", TableID: "copy", NumBytes: 10 * gib, NumRows: 5,
+			CreationTimeMs: 2000},
+		&bqdb.Table{DatasetID: "d", TableID: "original", NumBytes: 10 * gib, NumRows: 5,
+			CreationTimeMs: 1000},
+		&bqdb.Table{DatasetID: "e", TableID: "copy2", NumBytes: 10 * gib, NumRows: 5,
+			CreationTimeMs: 3000},
+		&bqdb.Table{DatasetID: "d", TableID: "snapshot", NumBytes: 10 * gib, NumRows: 5,
+			BaseTable: "p:d.original"},
+		&bqdb.Table{DatasetID: "d", TableID: "other", NumBytes: 10 * gib, NumRows: 6},
+		&bqdb.Table{DatasetID: "d", TableID: "small", NumBytes: 100, NumRows: 1},
+		&bqdb.Table{DatasetID: "d", TableID: "small2", NumBytes: 100, NumRows: 1},
+	)
+	findings := DuplicateTables(inv)
+	if findingsString(findings) != "duplicate_table d.copy, duplicate_table e.copy2" {
+		t.Fatal(findingsString(findings))
+	}
+	if math.Abs(findings[0].DollarsPerMonth-0.2) > 1e-9 ||
+		!strings.Contains(findings[0].Message, "d.original") {
+		t.Error(findings[0])
+	}
+}
+
+func TestStaleStreaming(t *testing.T) {
+	inv := newInventory()
+	inv.Tables = []*bqdb.Table{
+		{DatasetID: "d", TableID: "active", StreamingEstimatedRows: 10,
+			StreamingEstimatedBytes: gib, LastModifiedTimeMs: inv.TimeMs - dayMs},
+		{DatasetID: "d", TableID: "stale", StreamingEstimatedRows: 10,
+			StreamingEstimatedBytes: gib, LastModifiedTimeMs: inv.TimeMs - 30*dayMs},
+		{DatasetID: "d", TableID: "flushed", LastModifiedTimeMs: inv.TimeMs - 30*dayMs},
+	}
+	findings := StaleStreaming(inv)
+	if findingsString(findings) != "stale_streaming_buffer d.stale" {
+		t.Fatal(findingsString(findings))
+	}
+	if math.Abs(findings[0].DollarsPerMonth-0.02) > 1e-9 ||
+		!strings.Contains(findings[0].Message, "2023-12-02") {
+		t.Error(findings[0])
+	}
+}
+
+func TestRun(t *testing.T) {
+	inv := newInventory(
+		&bqdb.Table{DatasetID: "d", TableID: "cheap", PartitionType: "DAY", NumBytes: gib,
+			NumLongTermBytes: gib},
+		&bqdb.Table{DatasetID: "d", TableID: "expensive", PartitionType: "DAY", NumBytes: 10 * gib,
+			NumLongTermBytes: 10 * gib},
+	)
+	findings := Run(inv)
+	if findingsString(findings) != "partition_expiration d.expensive, partition_expiration d.cheap" {
+		t.Error(findingsString(findings))
+	}
+	for _, rule := range []string{RulePartitionExpiration, RuleDatasetExpiration, RuleFullScans,
+		RuleDuplicateTable, RuleStaleStreaming} {
+		if Title(rule) == rule {
+			t.Error("missing title for", rule)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/recommend"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Maximum number of tables with query costs passed to the recommendation rules.
+const maxRecommendationReads = 100000
+
+// Runs the recommendation rules over a snapshot and saves the findings.
+func saveRecommendations(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64,
+	projectID string, snapshotID int64) error {
+
+	snapshot, err := bqdb.GetSnapshot(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("bqcost: snapshot %d for project %s does not exist",
+			snapshotID, projectID)
+	}
+	inv := &recommend.Inventory{ProjectID: projectID, TimeMs: snapshot.TimeMs, Prices: prices}
+	inv.Datasets, err = bqdb.ListDatasets(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	inv.Tables, err = bqdb.ListTables(dbmap, userID, projectID, snapshotID)
+	if err != nil {
+		return err
+	}
+	project, err := bqdb.GetProjectByID(dbmap, userID, projectID)
+	if err != nil {
+		return err
+	}
+	if project != nil && project.QueryJobsLoadedMs != 0 {
+		reads, err := bqdb.QuerySpendByTable(dbmap, userID, projectID,
+			snapshot.TimeMs-durationMs(queryJobHistory), maxRecommendationReads)
+		if err != nil {
+			return err
+		}
+		inv.Reads = map[string]*bqdb.QuerySpend{}
+		for _, read := range reads {
+			inv.Reads[read.Name] = read
+		}
+	}
+
+	var recommendations []*bqdb.Recommendation
+	for _, finding := range recommend.Run(inv) {
+		recommendations = append(recommendations, &bqdb.Recommendation{
+			UserID:          userID,
+			ProjectID:       projectID,
+			SnapshotID:      snapshotID,
+			Rule:            finding.Rule,
+			Resource:        finding.Resource,
+			Message:         truncate(finding.Message, bqdb.MaxRecommendationMessageLength),
+			DollarsPerMonth: finding.DollarsPerMonth,
+		})
+	}
+	txn, err := dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	// don't forget to rollback
+	defer txn.Rollback()
+	err = bqdb.SaveRecommendations(txn, userID, projectID, snapshotID, recommendations)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// Returns the recommendations for a snapshot that the user has not dismissed.
+func queryRecommendations(getter gorp.SqlExecutor, userID int64, projectID string,
+	snapshotID int64) ([]*templates.Recommendation, error) {
+
+	recommendations, err := bqdb.ListRecommendations(getter, userID, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]*templates.Recommendation, len(recommendations))
+	for i, recommendation := range recommendations {
+		output[i] = &templates.Recommendation{
+			Rule:            recommendation.Rule,
+			Title:           recommend.Title(recommendation.Rule),
+			Resource:        recommendation.Resource,
+			Message:         recommendation.Message,
+			DollarsPerMonth: recommendation.DollarsPerMonth,
+		}
+	}
+	return output, nil
+}
+
+// Hides the recommendation in the rule and resource parameters from the user, then returns to
+// the project page.
+func (s *server) projectDismiss(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	if r.Method != http.MethodPost {
+		return fmt.Errorf("bqcost: dismiss must use POST; got %s", r.Method)
+	}
+	rule := r.FormValue("rule")
+	resource := r.FormValue("resource")
+	if rule == "" || resource == "" {
+		return fmt.Errorf("bqcost: dismiss requires rule and resource")
+	}
+	user, _, err := getExistingProject(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+	err = bqdb.DismissRecommendation(s.dbmap, user.ID, projectID, rule, resource, nowMs())
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, "/projects/"+projectID, http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+)
+
+func TestRecommendations(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := testIdentity(u)
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const gib = 1024 * 1024 * 1024
+	err = dbmap.Insert(
+		&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID},
+		&bqdb.Dataset{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d"},
+		&bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			TableID: "events", Type: "TABLE", PartitionType: "DAY", NumBytes: 200 * gib,
+			NumLongTermBytes: 100 * gib},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// saving twice replaces the recommendations
+	for i := 0; i < 2; i++ {
+		err = saveRecommendations(dbmap, s.prices, u.ID, "p", snapshot.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := queryProject(dbmap, s.prices, u.ID, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(data.Recommendations) == 1 && data.Recommendations[0].Resource == "d.events" &&
+		data.Recommendations[0].Title == "Set a partition expiration" &&
+		data.RecommendationSavings() > 0) {
+		t.Error(data.Recommendations)
+	}
+
+	// dismissing requires POST
+	form := url.Values{"rule": {"partition_expiration"}, "resource": {"d.events"}}
+	err = s.projectDismiss(httptest.NewRecorder(),
+		httptest.NewRequest("GET", "/projects/p/dismiss?"+form.Encode(), nil), identity, "p")
+	if err == nil {
+		t.Error("expected error for GET")
+	}
+	r := httptest.NewRequest("POST", "/projects/p/dismiss", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	err = s.projectDismiss(w, r, identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/projects/p" {
+		t.Error(w.Code, w.Header())
+	}
+	data, err = queryProject(dbmap, s.prices, u.ID, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Recommendations) != 0 {
+		t.Error(data.Recommendations)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"google.golang.org/api/bigquery/v2"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+)
+
+// Imports a snapshot file saved by bqcostreport --dump as a complete snapshot of its project for
+// userID, so the reports can show it without access to BigQuery. Creates the project if the
+// user does not have it. The project shows the imported snapshot unless it shows a newer one.
+func (s *server) importSnapshot(userID int64, file *bqscrape.Snapshot) (*bqdb.Snapshot, error) {
+	txn, err := s.dbmap.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// don't forget to rollback
+	defer txn.Rollback()
+
+	project, err := bqdb.GetProjectByID(txn, userID, file.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		project = &bqdb.Project{UserID: userID, ProjectID: file.ProjectID}
+		err = txn.Insert(project)
+		if err != nil {
+			return nil, err
+		}
+	} else if project.IsLoading {
+		return nil, fmt.Errorf("bqcost: project %d %s is loading: import after it finishes",
+			userID, file.ProjectID)
+	}
+
+	snapshot := &bqdb.Snapshot{UserID: userID, ProjectID: file.ProjectID, TimeMs: file.TimeMs,
+		Complete: true}
+	err = txn.Insert(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	storageBillingModels := map[string]string{}
+	for _, dataset := range file.Datasets {
+		err = saveBigqueryDataset(txn, userID, snapshot.ID, dataset)
+		if err != nil {
+			return nil, err
+		}
+		storageBillingModels[dataset.DatasetReference.DatasetId] = dataset.StorageBillingModel
+	}
+	// tables are saved with their dataset's billing model
+	var datasetIDs []string
+	datasetTables := map[string][]*bigquery.Table{}
+	for _, table := range file.Tables {
+		datasetID := table.TableReference.DatasetId
+		if datasetTables[datasetID] == nil {
+			datasetIDs = append(datasetIDs, datasetID)
+		}
+		datasetTables[datasetID] = append(datasetTables[datasetID], table)
+	}
+	for _, datasetID := range datasetIDs {
+		err = s.saveBigqueryTables(txn, userID, snapshot.ID, storageBillingModels[datasetID],
+			datasetTables[datasetID])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = saveBigqueryPartitions(txn, userID, file.ProjectID, snapshot.ID, file.Partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := true
+	if project.SnapshotID != 0 {
+		current, err := bqdb.GetSnapshot(txn, userID, file.ProjectID, project.SnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		latest = current == nil || current.TimeMs <= snapshot.TimeMs
+	}
+	if latest {
+		project.SnapshotID = snapshot.ID
+		_, err = txn.Update(project)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = txn.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	if latest {
+		err = saveRecommendations(s.dbmap, s.prices, userID, file.ProjectID, snapshot.ID)
+		if err != nil {
+			log.Printf("bqcost: warning: not saving recommendations for imported snapshot %d: %s",
+				snapshot.ID, err.Error())
+		}
+	}
+	return snapshot, nil
+}
+
+// Imports the snapshot file at path for the user who logs in with email. They must have logged
+// in before.
+func (s *server) importSnapshotFile(path string, email string) error {
+	user, err := bqdb.GetUserByEmail(s.dbmap, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("bqcost: no user with email %#v: they must log in once before importing",
+			email)
+	}
+	file, err := bqscrape.ReadSnapshotFile(path)
+	if err != nil {
+		return err
+	}
+	snapshot, err := s.importSnapshot(user.ID, file)
+	if err != nil {
+		return err
+	}
+	log.Printf("bqcost: imported %s as snapshot %d of project %s for user %d: %d tables",
+		path, snapshot.ID, snapshot.ProjectID, user.ID, len(file.Tables))
+	return nil
+}
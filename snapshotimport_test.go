@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/bqscrape"
+	"github.com/evanj/bqtools/pricing"
+)
+
+// Writes a snapshot file of project p scraped at scrapeTime, and returns its path.
+func writeTestSnapshotFile(t *testing.T, scrapeTime time.Time) string {
+	f, err := ioutil.TempFile("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := bqscrape.NewSnapshotWriter(f, "p", scrapeTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset := &bigquery.Dataset{
+		DatasetReference:    &bigquery.DatasetReference{ProjectId: "p", DatasetId: "d"},
+		Location:            "US",
+		StorageBillingModel: "PHYSICAL",
+	}
+	tables := []*bigquery.Table{
+		{TableReference: &bigquery.TableReference{ProjectId: "p", DatasetId: "d", TableId: "t"},
+			Location: "US", NumBytes: 1000, NumActivePhysicalBytes: 100},
+		{TableReference: &bigquery.TableReference{ProjectId: "p", DatasetId: "d", TableId: "u"},
+			Location: "US", NumBytes: 2000},
+	}
+	partitions := []*bqscrape.Partition{
+		{DatasetID: "d", TableID: "t", PartitionID: "20240101", NumBytes: 1000},
+	}
+	err = writer.Save(dataset, tables, partitions, bqscrape.Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestImportSnapshot(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", Email: "u@example.com"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrapeTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	path := writeTestSnapshotFile(t, scrapeTime)
+	defer os.Remove(path)
+
+	err = s.importSnapshotFile(path, "other@example.com")
+	if err == nil {
+		t.Error("expected error for unknown user")
+	}
+	err = s.importSnapshotFile(path, "u@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := bqdb.GetSnapshot(dbmap, u.ID, "p", project.SnapshotID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(snapshot.Complete &&
+		snapshot.TimeMs == scrapeTime.UnixNano()/int64(time.Millisecond)) {
+		t.Error(snapshot)
+	}
+	tables, err := bqdb.ListTables(dbmap, u.ID, "p", snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(tables) == 2 && tables[0].StorageBillingModel == "PHYSICAL" &&
+		tables[1].StorageBillingModel == "PHYSICAL") {
+		t.Error(tables)
+	}
+	count, err := dbmap.SelectInt("SELECT COUNT(*) FROM TablePartition WHERE SnapshotID=?",
+		snapshot.ID)
+	if err != nil || count != 1 {
+		t.Error(count, err)
+	}
+
+	// an older snapshot is kept, but the project still shows the newer one
+	olderPath := writeTestSnapshotFile(t, scrapeTime.Add(-time.Hour))
+	defer os.Remove(olderPath)
+	err = s.importSnapshotFile(olderPath, "u@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := bqdb.ListCompleteSnapshots(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	project2, err := bqdb.GetProjectByID(dbmap, u.ID, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(len(snapshots) == 2 && project2.SnapshotID == project.SnapshotID) {
+		t.Error(snapshots, project2)
+	}
+
+	// loading projects are not imported
+	project2.IsLoading = true
+	_, err = dbmap.Update(project2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.importSnapshotFile(path, "u@example.com")
+	if err == nil {
+		t.Error("expected error while loading")
+	}
+}
@@ -0,0 +1,176 @@
+// Package sqlfingerprint groups BigQuery queries that only differ in their literal values,
+// whitespace or comments.
+package sqlfingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Replaces each literal in normalized queries.
+const placeholder = "?"
+
+// Splits query into tokens, without whitespace and comments. Numbers and strings are replaced
+// with placeholder. Unterminated strings and comments extend to the end of query.
+func tokenize(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+
+		case c == '#' || strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += 2 + end + 2
+
+		case c == '\'' || c == '"':
+			i = skipString(query, i)
+			tokens = append(tokens, placeholder)
+
+		case c == '`':
+			// quoted identifiers are names, not literals: keep them
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				tokens = append(tokens, query[i:])
+				return tokens
+			}
+			tokens = append(tokens, query[i:i+1+end+1])
+			i += 1 + end + 1
+
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			i = skipNumber(query, i)
+			tokens = append(tokens, placeholder)
+
+		case isWordStart(c):
+			start := i
+			for i < len(query) && isWordPart(query[i]) {
+				i++
+			}
+			word := query[start:i]
+			// string and bytes prefixes: r'...', b"...", rb'...'
+			if i < len(query) && (query[i] == '\'' || query[i] == '"') && isStringPrefix(word) {
+				i = skipString(query, i)
+				tokens = append(tokens, placeholder)
+				continue
+			}
+			tokens = append(tokens, strings.ToUpper(word))
+
+		default:
+			tokens = append(tokens, query[i:i+1])
+			i++
+		}
+	}
+	return tokens
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c == '@' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || isDigit(c)
+}
+
+func isStringPrefix(word string) bool {
+	switch strings.ToLower(word) {
+	case "r", "b", "rb", "br":
+		return true
+	}
+	return false
+}
+
+// Returns the index after the string starting with the quote at query[start]. Handles triple
+// quoted strings and backslash escapes.
+func skipString(query string, start int) int {
+	quote := query[start : start+1]
+	if strings.HasPrefix(query[start:], quote+quote+quote) {
+		quote = quote + quote + quote
+	}
+	for i := start + len(quote); i < len(query); i++ {
+		if query[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(query[i:], quote) {
+			return i + len(quote)
+		}
+	}
+	return len(query)
+}
+
+// Returns the index after the number starting at query[start], including hex and exponents.
+func skipNumber(query string, start int) int {
+	i := start
+	if strings.HasPrefix(query[i:], "0x") || strings.HasPrefix(query[i:], "0X") {
+		i += 2
+	}
+	for i < len(query) {
+		c := query[i]
+		if isDigit(c) || c == '.' || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F') {
+			i++
+		} else if (c == '+' || c == '-') && (query[i-1] == 'e' || query[i-1] == 'E') {
+			i++
+		} else {
+			break
+		}
+	}
+	return i
+}
+
+// Replaces runs of literals separated by commas with a single placeholder, so IN lists of any
+// length are the same: (?, ?, ?) becomes (?).
+func collapseLists(tokens []string) []string {
+	var output []string
+	for _, token := range tokens {
+		n := len(output)
+		if token == placeholder && n >= 2 && output[n-1] == "," && output[n-2] == placeholder {
+			output = output[:n-1]
+			continue
+		}
+		output = append(output, token)
+	}
+	return output
+}
+
+// Normalize returns query without comments, with whitespace collapsed, unquoted words in upper
+// case, and literals replaced with ?. Table names are case sensitive in BigQuery, so two queries
+// with the same normalized text can read different tables; this is rare in practice.
+func Normalize(query string) string {
+	tokens := collapseLists(tokenize(query))
+	var buf []byte
+	for i, token := range tokens {
+		if i > 0 && needsSpace(tokens[i-1], token) {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, token...)
+	}
+	return string(buf)
+}
+
+// Returns true if the tokens previous and next are separated in the normalized query.
+func needsSpace(previous string, next string) bool {
+	return !(previous == "." || next == "." || previous == "(" || next == ")" || next == ",")
+}
+
+// Fingerprint returns an identifier for query that is the same for all queries with the same
+// normalized text.
+func Fingerprint(query string) string {
+	hash := sha256.Sum256([]byte(Normalize(query)))
+	return hex.EncodeToString(hash[:8])
+}
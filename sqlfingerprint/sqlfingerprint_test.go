@@ -0,0 +1,47 @@
+package sqlfingerprint
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"SELECT 1", "SELECT ?"},
+		{"  select\n\tx  FROM d.t  ", "SELECT X FROM D.T"},
+		{"SELECT a, b FROM `p.d.t` WHERE c = 'x' AND d > 1.5e-3",
+			"SELECT A, B FROM `p.d.t` WHERE C = ? AND D > ?"},
+		{"SELECT * FROM t WHERE id IN (1, 2,3)", "SELECT * FROM T WHERE ID IN (?)"},
+		{"SELECT * FROM t WHERE id IN ('a')", "SELECT * FROM T WHERE ID IN (?)"},
+		// comments
+		{"-- report\nSELECT 1 # one\n/* multi\nline */ FROM t", "SELECT ? FROM T"},
+		{"#standardSQL\nSELECT 1", "SELECT ?"},
+		{"SELECT 1 -- unterminated", "SELECT ?"},
+		{"SELECT 1 /* unterminated", "SELECT ?"},
+		// strings with quotes, escapes, prefixes and triple quotes
+		{`SELECT 'it\'s', "a\"b", r'\d+', b"x", '''multi 'line'''`, "SELECT ?"},
+		{`SELECT 'unterminated`, "SELECT ?"},
+		{"SELECT 0x1F, .5, 10 FROM t1", "SELECT ? FROM T1"},
+		{"SELECT DATE '2020-01-01', TIMESTAMP_SUB(x, INTERVAL 7 DAY)",
+			"SELECT DATE ?, TIMESTAMP_SUB (X, INTERVAL ? DAY)"},
+		// parameters and quoted identifiers are kept
+		{"SELECT @param, `My Table`.a FROM t", "SELECT @PARAM, `My Table`.A FROM T"},
+		{"SELECT x-1", "SELECT X - ?"},
+	}
+	for _, test := range tests {
+		output := Normalize(test.input)
+		if output != test.expected {
+			t.Errorf("Normalize(%#v) = %#v; expected %#v", test.input, output, test.expected)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint("SELECT name FROM users WHERE id = 1")
+	b := Fingerprint("select name\nfrom users -- dashboard\nwhere id = 42")
+	c := Fingerprint("SELECT name FROM users WHERE email = 'x'")
+	if !(len(a) == 16 && a == b && a != c) {
+		t.Error(a, b, c)
+	}
+}
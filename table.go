@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/googlelogin"
+	"github.com/evanj/bqtools/pricing"
+	"github.com/evanj/bqtools/templates"
+)
+
+// Returns the metadata and storage of table, without its partitions.
+func newTableReport(prices *pricing.Catalog, projectID string, snapshot *bqdb.Snapshot,
+	table *bqdb.Table) *templates.TableReport {
+
+	data := &templates.TableReport{
+		ProjectID:             projectID,
+		ID:                    table.DatasetID + "." + table.TableID,
+		Location:              table.Location,
+		Rows:                  table.NumRows,
+		Bytes:                 table.NumBytes,
+		LongTermBytes:         table.NumLongTermBytes,
+		DollarsPerMonth:       tableStorageCost(prices, table, snapshot.TimeMs).Total(),
+		PartitionType:         table.PartitionType,
+		PartitionField:        table.PartitionField,
+		PartitionExpirationMs: table.PartitionExpirationMs,
+		Type:                  table.Type,
+		ViewQuery:             table.ViewQuery,
+		SourceFormat:          table.SourceFormat,
+		BaseTable:             table.BaseTable,
+	}
+	if table.ClusteringFields != "" {
+		data.ClusteringFields = strings.Split(table.ClusteringFields, ",")
+	}
+	if table.SourceURIs != "" {
+		data.SourceURIs = strings.Split(table.SourceURIs, "\n")
+	}
+	return data
+}
+
+// Returns the storage of one table and its partitions in a snapshot. Returns nil, nil if the
+// table does not exist in the snapshot.
+func queryTable(dbmap *gorp.DbMap, prices *pricing.Catalog, userID int64, projectID string,
+	snapshot *bqdb.Snapshot, datasetID string, tableID string) (*templates.TableReport, error) {
+
+	table, err := bqdb.GetTable(dbmap, userID, projectID, snapshot.ID, datasetID, tableID)
+	if err != nil || table == nil {
+		return nil, err
+	}
+	data := newTableReport(prices, projectID, snapshot, table)
+
+	partitions, err := bqdb.ListTablePartitions(dbmap, userID, projectID, snapshot.ID, datasetID,
+		tableID)
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range partitions {
+		data.Partitions = append(data.Partitions, &templates.PartitionUsage{
+			ID:                 partition.PartitionID,
+			Bytes:              partition.NumBytes,
+			Rows:               partition.NumRows,
+			LastModifiedTimeMs: partition.LastModifiedTimeMs,
+			LongTerm:           partition.LongTerm,
+		})
+		if partition.NumBytes > data.MaxPartitionBytes {
+			data.MaxPartitionBytes = partition.NumBytes
+		}
+		if partition.LongTerm {
+			data.LongTermPartitions++
+		}
+	}
+	return data, nil
+}
+
+// Shows the storage of the table in the id parameter (dataset.table) in the latest snapshot,
+// including the size of each partition.
+func (s *server) projectTable(w http.ResponseWriter, r *http.Request,
+	identity *googlelogin.Identity, projectID string) error {
+
+	id := r.FormValue("id")
+	parts := strings.SplitN(id, ".", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("bqcost: invalid table id %#v; expected dataset.table", id)
+	}
+
+	user, snapshot, err := getLatestSnapshot(s.dbmap, identity, projectID)
+	if err != nil {
+		return err
+	}
+
+	data, err := queryTable(s.dbmap, s.prices, user.ID, projectID, snapshot, parts[0], parts[1])
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("bqcost: table %s does not exist in project %s", id, projectID)
+	}
+	return templates.Table(w, data)
+}
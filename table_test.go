@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/evanj/bqtools/bqdb"
+	"github.com/evanj/bqtools/pricing"
+)
+
+func TestProjectTable(t *testing.T) {
+	dbmap := newTestDB()
+	defer dbmap.Db.Close()
+	s := &server{dbmap: dbmap, prices: pricing.Default()}
+
+	u := &bqdb.User{Subject: "subject", AccessToken: "token"}
+	err := dbmap.Insert(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := testIdentity(u)
+	snapshot := &bqdb.Snapshot{UserID: u.ID, ProjectID: "p", TimeMs: 1000, Complete: true}
+	err = dbmap.Insert(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dbmap.Insert(
+		&bqdb.Project{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID},
+		&bqdb.Table{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID, DatasetID: "d",
+			TableID: "t", NumBytes: 400, NumLongTermBytes: 100, PartitionType: "DAY",
+			PartitionField: "day", ClusteringFields: "a,b"},
+		&bqdb.TablePartition{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID,
+			DatasetID: "d", TableID: "t", PartitionID: "20170102", NumBytes: 300},
+		&bqdb.TablePartition{UserID: u.ID, ProjectID: "p", SnapshotID: snapshot.ID,
+			DatasetID: "d", TableID: "t", PartitionID: "20170101", NumBytes: 100, LongTerm: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := queryTable(dbmap, s.prices, u.ID, "p", snapshot, "d", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(data.ID == "d.t" && len(data.ClusteringFields) == 2 && data.ClusteringFields[1] == "b" &&
+		len(data.Partitions) == 2 && data.Partitions[0].ID == "20170101" &&
+		data.MaxPartitionBytes == 300 && data.LongTermPartitions == 1 &&
+		data.DollarsPerMonth > 0) {
+		t.Error(data)
+	}
+	data, err = queryTable(dbmap, s.prices, u.ID, "p", snapshot, "d", "missing")
+	if !(data == nil && err == nil) {
+		t.Error(data, err)
+	}
+
+	w := httptest.NewRecorder()
+	err = s.projectTable(w, httptest.NewRequest("GET", "/projects/p/table?id=d.t", nil), identity, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"DAY by day", "2 partitions", "(25%)"} {
+		if !strings.Contains(body, expected) {
+			t.Error("missing", expected)
+		}
+	}
+
+	for _, id := range []string{"", "d", "d.missing"} {
+		r := httptest.NewRequest("GET", "/projects/p/table?id="+id, nil)
+		err = s.projectTable(httptest.NewRecorder(), r, identity, "p")
+		if err == nil {
+			t.Error("expected error", id)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 // Code generated by go-bindata.
 // sources:
+// source/cold.html
+// source/diff.html
 // source/index.html
+// source/inventory.html
+// source/labels.html
 // source/loading.html
 // source/project.html
+// source/queries.html
 // source/select_project.html
+// source/table.html
 // DO NOT EDIT!
 
 package templates
@@ -71,6 +77,46 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
+var _coldHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xad\x56\xdf\x6f\xdb\x36\x10\x7e\xcf\x5f\x71\x13\xb2\xb7\x85\x4c\xba\x01\x1b\x5c\x59\xc3\xda\x0c\x58\x81\xa4\x4b\x5b\x17\xc3\x1e\x29\x89\xb2\x98\x50\xa4\x4a\x52\x71\x04\xc3\xff\xfb\x8e\xa4\x64\x4b\x89\x93\x21\xd8\x9e\xa8\xe3\x1d\xef\xc7\x77\xdf\x91\x4a\xbf\xbb\xfc\xf3\xfd\xea\xef\x9b\xdf\xa1\x76\x8d\xcc\x4e\xd2\x71\xe1\xac\xc4\x45\x0a\x75\x07\x86\xcb\x65\x62\x5d\x2f\xb9\xad\x39\x77\x09\xd4\x86\x57\xcb\xa4\x76\xae\xb5\x0b\x4a\x8b\x52\xdd\x5a\x52\x48\xdd\x95\x95\x64\x86\x93\x42\x37\x94\xdd\xb2\x07\x2a\x45\x6e\x69\xde\xc9\x86\xd1\x73\xf2\x86\xfc\x48\x0b\x3b\xc8\xa4\x11\x8a\xa0\x94\xfc\x3f\x31\x2a\xad\xdc\x19\xdb\x70\xab\x1b\x4e\x7f\x22\x3f\x93\xf3\x10\x6a\xba\x3d\x8d\xe8\x84\x93\x3c\x7b\x27\xd6\x9f\x3a\x6e\x7a\x58\x69\x2d\xed\x02\xb6\x5b\x72\x63\xf4\x2d\x2f\xdc\x87\xcb\xdd\x0e\x0a\x2d\x4b\xb0\x4e\x1b\xb6\xe6\x29\x8d\x47\x4e\x52\x3a\x00\x93\xeb\xb2\xc7\xc5\xa2\xb5\xd0\x0a\x0a\xc9\xac\xc5\x74\xb9\xd1\x20\xec\x59\x6b\x44\xc3\x4c\x8f\xb1\x00\xd2\x52\xdc\x4f\xf5\x67\xfe\x68\xd0\xcc\x75\x05\x26\xcb\x84\xe2\x66\xd0\xa1\xb6\xbe\x18\x95\x21\xbc\xf7\x7c\x91\xbc\x2e\xef\xfa\x62\x88\x44\x31\x54\x48\x27\x7e\xa4\x74\x48\x3d\x3b\x79\x52\xc5\x20\x26\xd9\xf3\xe9\xcd\x35\xb2\x6b\x94\x3d\x5a\x92\xd7\x80\x3f\xca\x95\xf3\xe9\x2b\x66\x8c\xde\x1c\x2a\x6c\xb3\x94\x0d\xad\xa6\x6d\x2c\xc2\xd2\x79\x41\x58\x30\x2b\xee\xc0\xe9\x47\x85\xa6\x94\x65\x29\x6d\x31\xff\x3d\x5a\xd9\x47\xed\xe0\x33\x36\x08\xb4\x81\xbf\x8c\x70\x18\x16\x84\xf2\x07\x2f\x59\x6f\x11\x1c\xbf\x1c\x40\xc1\x43\x95\x36\x0d\x34\xdc\xd5\xba\x5c\x26\x6b\x24\xde\xa8\x09\xc9\x79\xf3\x05\xa4\x42\xb5\x9d\x1b\x8b\x0a\x42\x02\x81\xab\xcb\x64\x23\x4a\x57\x2f\xe0\x97\xf3\xf6\xe1\x6d\x02\xae\x6f\x71\x4f\x75\x4d\x8e\x38\x81\x62\x0d\x4a\x25\xfa\x48\x00\xd9\xb7\x4c\x2e\x12\xb8\x67\xb2\xc3\xcd\x7d\x46\x49\x06\x69\xde\x39\x77\x40\x3f\x4a\xa3\x2f\xdb\xe5\x8d\xc0\xac\xbe\xb6\x25\x73\xd8\xcf\xa8\x8d\x85\x0f\x69\x52\x5f\xc3\x1e\x86\xed\x56\x54\xa0\x10\x07\x72\xa5\x59\xc9\xcb\xdd\x6e\xb4\x9b\x34\x06\xf5\xa2\x12\x05\x0b\x5d\xc7\xb6\x6c\x98\x51\x42\xad\x93\x2c\x12\xab\x16\x9e\x3f\xb8\x32\x1b\x5c\xe5\x1c\x71\x94\xc1\xdd\x0f\x60\x35\x38\x34\x00\xad\x64\x0f\x45\xcd\x8b\x3b\x0b\x9b\x1a\x0d\x1c\xcb\x71\x7a\x61\xc3\x0d\x07\x0c\xe3\xa0\xd1\x25\x46\xe1\x25\x81\x2b\x74\x88\xfe\xf1\x20\x87\x6f\x18\x42\xa0\x9d\xe9\x14\xe4\x3d\x30\x29\xa1\xb3\xdc\xe0\x06\xff\xd6\x09\x83\x1a\x6f\x95\x16\xba\xe4\x59\x2e\xd6\xde\xbc\x27\xb7\x3a\xb7\x44\xa2\x97\xdf\xa4\x4c\x69\xd0\x41\xcb\x4d\x23\xac\xc5\x12\xc8\x9e\xdd\x11\x01\x2e\x2d\x0e\x4b\x05\xe4\x86\x19\x27\x98\xfc\x23\xd6\xf3\x5f\xa0\x08\xd5\x5a\x87\xfe\x6c\x84\x98\x0c\x4e\xbf\xf8\xbd\x6b\xec\xa4\x0e\x3c\x9b\xee\xee\x76\x31\x95\xdd\x2e\xe0\x23\x1c\x6c\x10\xd0\x4a\x18\xc4\x26\xa2\x89\x7a\x85\x1d\x5a\x8c\xd8\x35\xcc\x83\x7e\xcf\x23\xe2\xc6\x33\x39\xe7\xd8\x5e\xee\x31\x79\x5a\xa6\x9a\x74\xb7\xcd\x3c\xf1\x2d\xe0\xe5\x08\x95\xd1\xcd\x0c\xe7\x38\x03\x93\xe1\x21\xf0\x69\xae\xd6\x18\xc0\xc0\x38\x82\xc1\x8b\xef\xbc\x50\x85\xec\x30\x51\x32\x9b\xb4\x36\x43\x6f\xef\x75\xa7\xb0\xc4\x31\x75\x5f\x36\x0f\x08\x74\x0d\x53\xef\x7a\xc7\xad\x2f\xec\x74\xbb\xc5\xcb\x50\xb9\x0a\x92\xef\xc9\x9b\x2a\x01\x72\xa9\x25\xde\xe0\xf6\x86\x9b\x6b\xbc\x17\xea\xdd\x8e\x36\x7e\x25\x11\xd5\x15\xe6\x83\xcd\xf0\xbc\x85\x2f\xb5\xde\x8c\xac\x69\x34\x82\xc6\x1f\x5a\xae\xac\xb8\xf7\x71\x24\x02\x44\x56\x21\x36\x96\x33\x80\x31\xcb\x32\x24\xb6\xbf\x40\xbd\x30\x1d\x6e\x17\x2f\x72\x80\xc3\x8e\x99\x8a\xc1\x64\x9c\x72\xc7\x1f\xf0\x21\x91\x62\xad\x16\x60\xc4\xba\x76\x6f\x93\xec\x94\x86\xfc\xf1\x69\xa8\x5f\x75\x2e\x40\x73\xf4\x54\x76\xe5\xa7\xe6\x7a\x98\x9a\x17\x4c\x7c\xa7\x8f\xab\x57\x78\x69\x3c\xa3\x09\x68\x7c\xb8\x7c\xac\x45\x79\x52\xb7\xd7\x06\x5c\x26\x40\xc5\xa7\xee\x70\x62\xbb\x3d\x6d\x47\x26\xc1\x62\x09\x53\x5e\xcd\xcc\x0c\x53\x6b\x7e\x68\xd2\xcb\x58\x97\x2f\x62\xfd\x84\x45\xc1\xe9\x53\x2e\x61\xfe\xe5\xab\x3c\x23\x61\xa3\xab\x29\x6d\x8f\x7a\xf1\xa6\x1e\xfd\xb1\x3f\xff\x62\xe6\x7b\xf4\x82\x49\x0c\xea\xdb\xf5\x9c\x51\x2a\x46\xee\x56\x0c\x2a\x76\x36\x30\x38\xa5\x02\x9f\x8c\x63\x8f\xe6\xa1\x2b\x38\x53\xc1\xfa\x57\x51\x2e\xf7\xb1\xe2\x5b\x3a\x13\xe3\x13\x3a\x0f\x3e\xe7\xc3\xe3\x6b\x26\xe8\xa7\x84\x48\x63\xa4\xe7\x7e\x32\xfc\x32\xfb\xd5\xa0\xc3\x8f\x13\x8d\xff\x99\xff\x00\x3c\x5f\x77\xfb\x7f\x0a\x00\x00")
+
+func coldHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_coldHtml,
+		"cold.html",
+	)
+}
+
+func coldHtml() (*asset, error) {
+	bytes, err := coldHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "cold.html", size: 2687, mode: os.FileMode(420), modTime: time.Unix(1792204381, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _diffHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xdd\x58\xdd\x6f\xdb\x36\x10\x7f\xcf\x5f\xc1\x09\x59\xb1\xa2\xb0\xd8\xb4\x03\x06\xb8\xb2\x0a\xd4\xde\xb0\x16\x6b\x93\xb5\x7e\xe9\x23\x6d\x51\x16\x33\x8a\xd4\x48\xaa\xae\x61\xf8\x7f\xdf\x91\xa2\x6c\x3a\xf1\x47\x6c\x6b\xc0\xb0\xbc\x58\x47\xf2\x8e\x77\xbf\xfb\xe2\x65\xb9\xcc\x68\xce\x04\x45\xd1\x98\x4c\x38\x1d\xb1\x3c\xd7\xd1\x6a\x75\x85\xdc\x5f\x62\xec\x22\x9a\x72\xa2\xf5\x20\x72\x44\x94\xfa\x3d\xbb\x5b\x50\x92\x6d\x68\xbb\xa2\x42\xd2\x1d\x41\xda\x2c\x38\x05\x6e\xfa\xdd\xf4\x08\x67\x33\xd1\x47\x8a\xcd\x0a\xf3\x26\x4a\x6f\x79\x86\xde\x2d\x0c\xd5\x09\x36\xc5\x49\x9c\x9f\xe8\xfc\x4c\xce\x61\x41\xc4\x8c\x9e\xcc\xf6\x59\xce\x4f\xbf\xeb\x1a\x7f\x94\xc2\x14\x3b\xf9\x52\x07\x38\x7a\x3f\x7a\xb8\x0b\x74\x80\xa2\xdd\x75\x28\x07\xb0\x4f\x64\xb6\x08\x39\x96\x4b\x65\x6d\x42\xf1\xda\x71\x7b\x9c\x91\x1d\x52\x76\xb9\x8c\x1b\x6c\xe2\xdf\xeb\x92\x08\xf0\x8d\x03\x78\xb5\x02\x15\xb2\x4b\x24\x81\xaf\x3a\x92\x74\xbe\x98\x4a\x31\x61\x72\x14\xfd\xf8\x22\x8b\x50\x2b\xd3\xfa\xf4\x42\x69\xf1\xab\x7c\x23\x6f\x24\x39\x27\x4a\xdf\x51\xe5\xdc\xbe\x47\x74\x9a\xb0\x36\xa3\x72\x82\x72\xd2\xf3\x79\x95\x60\x96\x82\x2b\xe3\xf7\xa3\xc7\x8c\xdb\x31\x61\x3d\x4e\x45\x16\x78\x1b\xf6\xc3\xa0\x00\xd2\xca\x4c\xaf\xda\x73\xc9\x0f\xa3\xdb\xe1\xf8\xeb\xdd\xaf\xa8\x30\x25\x4f\xaf\x92\xf6\xc7\x85\x56\xc2\x99\xf8\x0b\x29\xca\x07\x91\x33\x5b\x17\x94\x9a\x08\x15\x8a\xe6\x83\xa8\x30\xa6\xd2\x7d\x8c\xa7\x99\xb8\xd7\xf1\x94\xcb\x3a\xcb\xc1\x4a\x1a\x4f\x65\x89\xc9\x3d\xf9\x8e\x39\x9b\x68\x3c\xa9\x79\x49\xf0\xcb\xf8\x55\xfc\x1a\x4f\xb5\xa7\xe3\x92\x89\x18\xa8\xa8\x9b\x3b\x72\x40\xb5\x47\xe6\x54\xcb\x92\xe2\x9f\xe3\x5f\xe2\x97\xee\xaa\x70\x39\xbc\xd1\x30\x03\x18\xbc\x63\xb3\x3f\x6b\xaa\x16\x68\x2c\x25\xd7\x7d\x8b\xf0\x9d\x92\xf7\x74\x6a\x2c\xd0\x68\xea\x5c\x67\x53\xdb\x9d\xbe\x4a\xb0\xc7\xa4\xc1\x33\xd1\x70\x90\x49\xd1\x7a\xac\xa0\x4a\x22\xa6\x7b\x10\x01\x25\x51\x0b\x57\x0d\x93\x8c\x7d\x0b\xf7\x7b\x96\xd5\xd7\xc9\x70\x6f\x0a\x7a\x12\xa8\xb5\x6a\x5d\x43\x93\xe2\x66\x5d\x5c\xed\xf5\x56\xf2\x4d\xf4\x64\x95\x8b\x1b\x7f\x09\x86\x5b\x9c\x26\xcd\x47\x82\xbd\xd6\x50\x35\x1e\x1a\xe0\x49\x08\xb7\xbd\x9a\x6d\xef\xf0\xba\x14\x7a\xa7\x35\x76\xc7\x6a\x2c\x88\x52\x72\x8e\xac\x10\x2a\xcc\xda\xb8\xe5\xf2\x3a\x57\xb2\x44\xfd\x01\x8a\x7f\x83\x0f\x17\xd8\x9b\x3d\x23\xdd\xce\x58\x86\xeb\x49\x2e\x55\x89\x4a\x6a\x0a\x99\x0d\xa2\x99\x8d\x10\xe2\xf4\x1d\x44\xb8\x6a\x20\xd0\x78\x1b\x0e\x30\x39\xcf\xc3\xae\xa4\x2b\x12\x58\xcb\xe1\x18\x18\xdb\x7c\x20\x41\x4a\x48\x6a\xab\x56\xb4\xb3\x80\x7e\x11\xa4\xd2\x85\x34\xb6\x2c\xc8\xca\xe1\xf6\x8d\xf0\x1a\x78\x7c\x62\xc2\x2f\xcb\x11\xfd\x1b\x01\x85\x9c\x7d\xe0\x90\x46\x38\xcd\x7c\xb6\xd9\xaa\x35\x66\x25\xb5\x69\xdc\x08\x49\x1f\xe7\x6b\xc3\x03\x49\x6f\xd5\xdd\xe8\x62\xe4\x29\x86\x18\xd9\x89\x19\x46\x76\x6b\x44\x32\xa9\x8d\xd9\x84\x9c\xa7\x82\xac\x41\x66\x51\x81\x32\xba\x9e\x94\x0c\x8c\x1a\xca\xb2\x82\x6c\x4f\x70\x73\x72\x53\xc5\x6c\x34\xac\x3b\x1f\x24\x4b\x3a\x96\x86\x70\x1f\xf9\x07\xde\x28\x6d\xf5\x9e\xb3\xcc\x14\x7d\x44\x6a\x23\xdf\x6c\x3d\x5c\xb6\x8a\xa9\x6d\xc6\x8f\x9a\xf0\xe1\xc6\xbe\xf3\xe9\x71\xce\x63\xe0\x41\xb3\xdf\xa1\x18\xf1\x35\x72\x5f\xfc\xbf\xd5\xde\xd9\x03\x58\x5f\xe7\x99\x6b\x9d\x8e\x6a\x9d\x48\x76\xd8\x78\xac\xf7\x3a\x01\x87\x3a\xef\x11\x11\xd7\x41\xab\x6c\x3a\xa5\x93\x78\xa4\x4f\x76\x8c\x89\xaf\x30\xce\x20\xf8\xbe\x04\x0f\x60\xef\x14\x0d\x90\x77\x29\x16\xbb\x9f\xb3\x4f\x31\x05\x32\xe9\x12\x6b\x1e\xbf\x82\x1a\x91\x27\x18\xb4\x7e\xa6\xb4\x74\x75\xdc\xb5\xae\xdc\xbf\xb5\x85\x77\x2b\xde\x9f\x19\x19\xf8\xfa\x99\x2d\x1c\xc4\x0c\xee\xb5\xed\x74\x1f\xbe\xdc\x7e\x6a\xfc\x5d\xed\xee\x97\xa7\xf6\x3c\xdf\xe9\x36\xbd\x2f\x6c\xe8\xe9\x88\x18\xa2\xa9\x39\x5e\xa5\xba\x9c\xa4\xfe\x2b\x53\xcd\x21\x3e\x37\xf0\x68\x34\x54\x94\x40\x9f\x39\x97\x7d\x04\x3d\x67\x1f\x7b\x8b\x7d\xb7\x53\x55\xeb\xd0\x2e\xa7\xab\xff\xc5\x24\x73\xcc\xde\xc6\xd1\xe7\x31\x7b\x37\x3f\x75\x86\xca\xc0\x47\x13\xf0\xd2\xbf\x33\x46\x05\xd9\xfd\x07\x51\xf0\xf6\x36\x68\xf8\xf0\x11\x6e\xa5\x19\x5a\x56\x1c\x6c\xde\xfa\x6f\x4a\x0b\xaa\xbd\x28\x10\xe4\xd1\x41\x3e\xac\x7f\xda\x00\x36\x94\xb5\x30\xab\xd5\xf3\xa7\xc9\x6e\x41\x0e\x65\x7b\xf0\x42\xd9\x7e\xe9\x24\xd9\x6b\x1f\xec\x99\x32\xec\xcf\xd6\xac\x81\xfd\xd0\x84\x9b\xf1\xf2\x1f\x9b\xfc\xb3\x7a\x5d\x12\x00\x00")
+
+func diffHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_diffHtml,
+		"diff.html",
+	)
+}
+
+func diffHtml() (*asset, error) {
+	bytes, err := diffHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "diff.html", size: 4701, mode: os.FileMode(420), modTime: time.Unix(1792202970, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _indexHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xd4\x58\xdf\x6f\xdb\xc8\x11\x7e\xf7\x5f\x31\x65\x5b\x34\x07\x58\x5c\x49\x89\xcf\xad\x43\x13\xcd\x2f\xa4\x01\xae\xb8\xbb\x3a\x68\xd0\xa7\xc3\x88\x1c\x92\x6b\x2f\x77\x99\x9d\xa1\x64\xf5\xaf\x2f\x76\x49\xca\xb2\x15\xc7\xa9\xd3\x14\x77\x4f\x12\x77\x76\x76\xe7\xfb\xbe\x19\x6a\x46\xd9\xef\x5e\xff\xf8\xea\xfd\xbf\x7e\x7a\x03\x8d\xb4\x26\x3f\xca\xa6\x0f\xc2\x32\x3f\xca\x8c\xb6\x57\xe0\xc9\x9c\x27\x2c\x5b\x43\xdc\x10\x49\x02\x8d\xa7\xea\x3c\x69\x44\x3a\x3e\x53\xaa\x28\xed\x25\xa7\x85\x71\x7d\x59\x19\xf4\x94\x16\xae\x55\x78\x89\xd7\xca\xe8\x15\xab\x55\x6f\x5a\x54\xf3\x74\x99\x3e\x55\x05\x8f\xcf\x69\xab\x6d\x5a\x30\x27\xff\x9b\x3b\x2a\x67\x65\x86\x1b\x62\xd7\x92\x7a\x96\x9e\xa6\xf3\x78\xd5\xfe\xf2\xfe\x8d\xa2\xc5\x50\xfe\x52\xd7\x3f\xf7\xe4\xb7\xf0\xde\x39\xc3\x67\xf0\xca\xb1\xc0\x5a\x73\x8f\x46\xff\x1b\x45\x3b\x9b\xa9\x61\xe7\x51\xa6\x46\x3e\x56\xae\xdc\xe6\x47\x19\x53\x11\xec\x50\x18\x64\x3e\x4f\x1a\xf2\x0e\x34\xcf\x3a\xaf\x5b\xf4\xdb\x24\x3f\x02\xc8\x4a\xbd\xde\xb7\xcf\x82\x6b\xb4\xdc\xb6\x15\xce\x0a\x6a\x4b\x7e\xb4\x01\x64\xcd\x62\x32\xc6\xeb\xc3\xc9\x8b\xe4\x4e\xb8\x99\x6a\x16\x37\x0e\xcb\xc9\x81\xfb\xd5\xce\xe7\x59\x92\x5f\x10\xc1\xa6\xd1\x45\x03\x25\x0a\x32\x09\x1f\x83\xe0\xca\x10\x03\xda\x12\x3e\xf6\xe4\x35\x31\x14\x01\xb9\x34\x04\xad\x63\xc9\x54\xb3\x1c\xc3\x54\xa5\x5e\x47\x2c\xc3\x97\x4c\x8d\xb8\xf3\xa3\x03\x0a\xc6\xc7\x03\xe8\x01\x1e\x59\x09\xf1\xb4\x54\xea\xbe\x85\xbb\x80\xef\xc2\x4d\xf2\x7f\x8e\x1a\x10\xec\x30\x87\x08\xf7\x30\x67\xdd\xf0\x39\xe0\x43\xd9\xc1\x8b\xb0\x26\x84\x9e\x40\xf0\x4a\xdb\x1a\xfa\x6e\x07\x0f\xb8\xc3\x82\x52\xb8\x39\x17\xd0\xa2\xd9\xb2\x0e\x3c\xb4\x61\x37\x3b\x67\xd3\x91\x81\xee\x0b\xf1\x07\x89\x3f\xab\xfb\x27\x84\x37\x7d\x6b\x39\xc9\x0f\x17\x03\x5b\x0d\x9a\x2a\x7c\xba\xaa\x62\x92\x99\xb3\x34\xfb\xd8\xa3\x97\xfd\x44\xf9\x04\xcf\x2b\x77\xbd\xb3\x47\x6e\xf3\x1f\xd0\xd7\xc4\x02\xaf\x47\x8a\x06\x16\x6f\xb6\x44\xb6\x76\x0a\x84\x87\xbd\x03\x82\x7d\x48\x7d\x80\xfd\x35\x7f\x7b\x21\x6e\xcb\x33\x25\xcd\x7f\xb1\x0e\xb1\xdc\xcf\x13\xa1\x6b\x99\xa1\xd1\xb5\x3d\x03\xaf\xeb\x46\x9e\x27\xf9\x1f\xd4\xdf\x9d\x95\xe6\x11\x9e\x2f\xb7\x42\x7c\x5f\x24\x23\x07\xf0\xee\xf5\xe1\x8e\x4c\xdd\x46\x15\x76\x44\xe4\xb7\xc8\x18\x5e\x00\x0f\x92\x51\x4e\x21\xae\xc9\x8b\x2e\xd0\x4c\x61\xb6\xba\x2c\x0d\x3d\x87\x8d\x2e\xa5\x39\x83\xc5\x7c\xde\x5d\x3f\x4f\xee\x1e\x10\x32\xdc\xbb\xda\x13\xf3\xa4\xcc\xee\x59\xf3\x8c\x5b\x34\x26\x81\x35\x9a\x9e\xce\x93\xd3\x93\x04\x5a\xbc\x3e\x4f\x16\xf3\x79\x32\xdd\x7b\xe7\xfc\xd3\x93\x4c\x4d\x27\x1c\x44\xab\xa4\xfc\x0c\x82\xf1\xa4\x65\x38\x08\x3e\xc5\xf8\xe9\xc9\x1f\x1f\x38\xe2\x93\x0a\x2f\xe6\xe9\xe9\xf7\x8f\x70\x3c\x79\xfa\xe7\x74\x0e\x6f\xf5\xcb\x7b\x7c\xf3\x4c\x4f\x9c\x55\x08\x15\xce\xc2\xbb\x61\x85\x4c\x49\x9e\x29\x9d\x43\x49\xeb\x5f\x2a\xe7\x0e\xbd\xef\x26\x00\xc0\xaf\x4b\xe7\xe5\xf2\x61\x9d\x97\xcb\x6f\xa7\xf3\x72\xf9\x28\x9d\x9f\xa6\x8b\xf9\x23\xfc\x16\x27\x27\x5f\x25\xf3\x6f\x53\xe2\x2f\x50\xf8\x1b\x0a\xfc\x28\x7d\xe7\xe9\xf2\xd9\x63\xf4\x5d\xa6\xcb\xaf\xac\xe2\x15\xfa\xdf\x9e\xc4\x8b\x87\x25\x5e\x7c\x3b\x89\x17\x8f\x94\xf8\x51\x25\x7c\x92\x2e\xbe\x42\xe1\x2f\x52\x37\x53\x77\x7e\x91\x33\x15\x5b\x98\x5d\x8b\x34\xb5\xb0\xd3\xd7\x71\xe1\xfe\x36\x1c\x1a\xe4\x59\x44\x53\x90\x15\xf2\x54\xde\xf4\x5b\x38\x4e\x25\x8a\x05\xbd\x24\x93\xef\xaa\x17\x71\x76\x6f\x00\x08\x5f\x4d\x68\xbb\x92\xfc\x2d\x09\x5c\x84\xdd\x54\x66\x0a\xff\xbf\x7d\xb5\x9f\x70\x1e\x74\xd8\x7f\x73\x1b\xd0\x02\x1b\xe7\xaf\x6e\x77\xd5\xb7\x87\x0c\xf0\xf4\xb1\x27\x16\x06\xa1\xb6\x73\x3e\x40\xf3\x84\xe5\xcc\x59\xb3\x05\x2c\x8a\x90\xe1\xe2\x76\x6d\x7a\x0a\xef\x04\x7a\x26\x8e\xdd\xf6\x8e\xae\xdd\x10\x17\xc6\xb7\xb4\x76\xae\x36\xc3\x00\xb7\xd2\x75\x18\x41\xb6\xaa\x74\x05\x2b\x4f\x15\x79\xb2\x05\x29\x4f\x2c\x6a\xbd\x1c\xa4\x64\x55\x93\xec\x8f\x3f\xb1\x5f\x7d\xf1\xd3\xbb\xc0\x67\xb8\xbd\x26\x01\x16\x14\xcd\xa2\x0b\x86\xca\x79\x40\x63\xa6\x29\x40\xdb\x18\x4c\xe7\xdd\x25\x15\x12\x23\x94\x86\x2c\x14\x68\x8a\xde\xa0\x8c\xc1\x62\x5d\x7b\xaa\x51\x08\x58\x9c\xc7\x9a\xa0\x67\xac\xe9\x38\x0e\x14\xdc\xb8\x0d\x03\x02\xbb\xa0\x23\x18\xcd\x02\xae\x8a\x7e\xe2\xba\xf1\xa6\x14\x6e\x46\x86\x7b\x68\xbf\xa0\xa2\xf7\x5a\xb6\xb7\x28\xff\x40\x13\xcd\x43\xa0\xe4\x5b\xcd\xac\x9d\x65\xd8\x10\x14\x68\xcf\xe0\x1f\x9f\xe1\x1c\x9e\x1c\x70\x7d\xa6\x42\x82\x16\x57\x6e\x4d\xbe\x32\x6e\x13\xb9\x8e\x37\x84\x53\xd5\xf2\xe4\x2f\xdf\x9f\x2e\x9f\x9d\xa8\x30\x39\xcd\x5c\x47\x3e\x0e\xbb\x3c\x2b\x1d\xf1\x4c\x1a\x9a\x4d\xba\xcc\x82\xd8\xe1\xde\x19\x17\xae\xa3\x19\x1a\xe3\x36\x49\x3e\x99\xd3\xc9\x1c\xd4\x80\xb8\x25\x68\xf2\x5d\x0a\x1f\x08\xe2\xfa\x04\x2c\xa3\x36\xdf\xa5\x50\xa6\xa8\xcd\x47\x24\xc7\xc0\x6e\x84\x69\x9d\x4c\xf0\xb6\xae\xf7\x93\x62\xe0\x2c\x41\x13\x16\xb0\x12\xf2\xc1\x06\x85\x71\x4c\xc3\xae\x95\x77\x1b\x26\x0f\x4f\xb4\x3d\x4c\xb8\x92\xd6\x64\x02\x40\xde\xcf\x3a\x5d\x92\x15\x2d\xdb\xf0\x92\x15\x57\x38\xc3\xea\xc7\x17\xbd\x34\xcb\x0f\xb4\xba\x20\xbf\x26\xff\x7b\x57\x55\x46\x5b\x4a\xf2\xc1\x70\x06\x59\xe1\x4a\xca\x87\xf0\x7e\x91\x6d\x47\xe7\xce\x86\x1d\x99\x8a\x86\x11\xf6\xdd\xea\xe9\x6d\xcc\xbf\x17\x5d\x07\x6f\x6c\xad\x2d\x05\xb4\x6f\x63\x24\xd0\xa2\xc5\x9a\x18\x78\xcc\x09\xe8\xbb\x32\x66\x63\x48\xb8\x91\x87\x50\xd2\xde\x99\x14\xde\x37\x9a\xe3\x8c\x1b\xb9\x72\xc6\x04\x62\x34\xc7\x5c\xa5\x32\xdc\x81\x5f\x50\x6e\xfc\xd1\x0c\x95\x96\xe4\xaf\x82\x0d\x2e\x7e\xfe\x21\x16\xd1\xf4\xe6\xdd\x53\x23\xcc\xc2\x5d\x2f\x14\x73\x52\xdb\xca\xf9\x36\x66\x49\x30\x87\x72\x08\xec\x47\x9d\x4b\x32\x14\xb7\x4d\x11\xba\x10\x4c\x89\xda\x6c\x61\x85\x61\xaa\x16\x07\xb8\x76\xba\x04\x43\xc3\x34\xbe\x77\x5c\xba\x57\x2e\x5d\xfe\x9a\xb8\xd3\xb2\x9b\xda\x43\x15\x8f\x65\xd6\x79\x2a\xb0\x97\xbd\xaa\x38\x06\x1c\x0a\x86\x6c\x39\xed\x2a\x71\x7b\x1c\x13\x24\x8c\xfe\xb5\x47\x2b\x71\xf8\x7f\xe8\xf5\x15\x13\x69\xa7\xdd\x98\x77\x91\x8b\xad\xeb\xff\x64\x0c\x34\xb8\x0e\x95\x0e\xe2\x7b\x0e\x2f\xb8\xa8\x51\x70\x62\x57\xc9\x06\x3d\xa5\xf0\xde\x81\xa7\xb2\x2f\x02\x13\x28\xe0\x35\x5f\x41\xd5\x7b\x69\xc8\x1f\xc7\xd8\x42\x9e\x04\xc9\x5c\x47\x16\xd8\xf5\xbe\xa0\x14\xde\x55\xbb\x70\x3b\xf4\x68\x9d\x2e\x07\x00\x41\x01\xdf\xdb\x21\x34\xb7\xb1\xa0\x2d\x0b\xda\xe0\xf3\x61\xd0\x07\x0d\xbb\x10\xeb\x0a\x57\x66\x0b\x0d\x99\x0e\xf4\x70\xda\x06\xad\x84\x68\xef\xf1\x7f\xd3\xa2\x36\x37\xd9\x12\x9e\xc4\x9d\xd1\xe5\x5f\x69\x8d\xf6\xd2\x59\xe2\xb4\xc0\x24\x7f\xb3\x46\x0b\x4f\xee\x2c\x7f\x17\xd3\x65\xbc\xc7\x12\x95\xf1\xe2\xf4\x33\x7f\x93\xa8\xf1\x1f\x33\x15\xff\x57\xfc\x4f\x00\x00\x00\xff\xff\x77\xd2\xb2\x7f\x6e\x14\x00\x00")
 
 func indexHtmlBytes() ([]byte, error) {
@@ -91,6 +137,46 @@ func indexHtml() (*asset, error) {
 	return a, nil
 }
 
+var _inventoryHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xcd\x56\x5b\x6f\xd3\x30\x14\x7e\xdf\xaf\x38\x44\x43\x1a\x0f\x8b\xb7\x81\x04\x1a\x69\x10\x5b\x41\xdb\x03\x6c\x6c\x05\x89\x47\x2f\x71\x1b\x0f\xc7\x0e\xb6\xbb\xae\xaa\xf2\xdf\x39\x76\x9c\x36\x59\x3b\xd8\x26\x84\x78\x4a\xec\xef\xdc\x6f\x3e\xc9\xb3\xe1\xd9\xf1\xe8\xfb\xf9\x07\x28\x6c\x29\xd2\xad\xa4\xfd\x30\x9a\xe3\x47\x70\xf9\x03\x34\x13\x83\xc8\xd8\xb9\x60\xa6\x60\xcc\x46\x50\x68\x36\x1e\x44\x85\xb5\x95\x39\x24\x24\xcb\xe5\xb5\x89\x33\xa1\xa6\xf9\x58\x50\xcd\xe2\x4c\x95\x84\x5e\xd3\x5b\x22\xf8\x95\x21\x57\x53\x51\x52\xb2\x17\x1f\xc4\x2f\x49\x66\xc2\x39\x2e\xb9\x8c\xf1\x14\xfd\x1d\x1d\x63\x25\xed\x2e\x9d\x31\xa3\x4a\x46\x5e\xc5\xaf\xe3\x3d\xaf\xaa\x7b\xdd\xd5\x68\xb9\x15\x2c\x3d\xe2\x93\x2f\x53\xa6\xe7\x30\x52\x4a\x98\x43\x58\x2c\xe2\x73\xad\xae\x59\x66\x4f\x87\x75\x0d\x96\x5e\xa1\x31\x40\x65\x0e\x37\x9c\xcd\x4c\x42\x1a\xb6\xad\x84\x84\xe0\x5c\xa9\x7c\x8e\x1f\x83\x1c\x5c\x49\xc8\x04\x35\x06\x4d\x66\x5a\x01\x37\xbb\x95\xe6\x25\xd5\x73\xd4\x07\x90\xe4\xfc\xa6\x8b\xef\x3a\x56\x8f\xf4\xb1\x0c\x0d\xa6\x5c\x32\x1d\x30\x44\x8b\xfd\x16\xf4\xea\x9d\xe4\xfd\xe8\xf1\xb6\x17\xfb\x41\x1b\x41\x75\xde\xa4\xe6\x27\x21\xc1\xfc\x74\x6b\xcd\x93\x70\x8c\xd2\xfb\x4d\xec\x23\x62\x5a\x4a\xb3\xd1\x2d\x87\x80\x63\x65\xd2\x3a\x17\x24\xd5\x5a\xcd\x56\x5e\x56\x69\x42\x43\xca\x49\xd5\x38\x62\x48\xdf\x29\x74\x9a\x66\x3f\xc0\xaa\x3b\xce\x26\x84\xa6\x09\xa9\xd0\xfe\x46\xd6\x62\xc1\xc7\x10\x8f\xe6\x15\x33\x75\xbd\xbc\xdb\xb6\x78\x01\x87\x83\x06\xe9\x02\x55\x2b\xca\xa3\x1d\xc1\xad\x6d\x3e\x98\xcb\x24\xb8\xc3\xd2\x6e\x87\x36\xc5\x00\xb0\xba\xd1\xdd\xa3\x27\x49\x9d\x52\x2c\xa0\x62\x0d\x01\x5f\xf3\x28\x97\xdd\x62\xa9\x0a\x3e\x91\x87\xa0\xf9\xa4\xb0\x6f\xa3\xf4\x58\x4d\xa5\x7d\x34\xd7\x36\xf9\x84\x71\x2e\x1e\xcd\x77\x34\xb7\xcc\xdc\xe5\xc2\x73\xc7\x1b\x87\xf6\xbc\x4d\x6c\xd3\x03\x2b\x86\xc5\x42\x53\x39\x61\x77\x13\x70\x4f\x60\xf2\xd4\x67\x8b\xfd\x6c\xe8\xc1\x67\x09\x53\x6a\xac\x56\x72\x82\x60\xc8\x16\x56\x69\x7b\xc3\x84\x71\x17\x9b\xaa\x65\x95\xca\xba\x26\x5c\xde\x60\xad\x29\x3d\x7f\xe7\x64\x0e\x96\xa2\xa2\xae\x54\xea\x04\xca\xdc\xfd\xda\x7c\xcd\xb8\xdf\x45\xcb\x09\xf1\x4d\xf6\x04\xde\xed\xc5\x02\x87\x83\xb4\x63\x88\x9e\xc7\x07\xe3\x08\xe2\xa1\x12\x38\xd5\xcc\x39\xd3\x3e\x77\x4f\xb3\xe7\x64\x5a\x52\xe9\xb3\xb8\xce\xdf\xcf\xa3\x4b\x93\x77\xbb\x9b\xd9\x6e\x26\xf1\xe8\x9c\x5b\xf6\x14\x4e\xa1\x6e\xd8\xda\x61\xb2\x6c\x36\x3d\x95\x19\xb5\xcc\x05\xb2\x4a\x2f\x0b\x35\xe3\x72\x02\x58\x2b\x80\x5e\x4d\x98\xb1\x48\x27\x98\x84\x65\xc8\x62\xd7\xb0\x7d\x1b\xfe\x46\x9b\xfd\xb3\xb6\xf8\x23\xd7\x85\x9a\x6d\x66\x4a\x7d\x08\xe0\x74\xb8\x19\x1d\x32\x1c\xae\xe2\xa1\x6d\xf8\xa0\x3e\x0c\x21\xff\x43\x23\xfe\x67\xf5\xfa\x10\x7e\x17\xe3\x7b\x38\x37\xbf\x26\xbd\xf9\xe0\x2b\xec\x1d\xcf\xdd\x64\x68\x9e\x97\xf0\xd3\xbc\x27\x9b\x84\x36\xc5\x7e\x44\x0d\xf3\x41\xad\xeb\xfe\xf0\x8a\x2e\x3f\xbf\x3f\xbf\x3c\x39\x1b\x45\x75\x6d\x24\xad\x4c\xa1\x6c\x3b\xaf\x70\x73\x91\x2c\x14\x3c\xa8\xb1\x7b\xc2\x7a\x72\x1c\x15\x38\xe9\x97\x6a\xaa\x33\xf6\xf5\xe2\xd4\xb8\xeb\x70\xfc\xa8\x74\x49\x2d\x72\x26\x99\xca\x99\xb3\x43\xe6\xec\xb6\x4b\x0c\x7b\xce\xf0\x16\x1d\xc3\xc4\xc2\x8e\xef\xb8\x15\xc9\x0b\xd8\x47\x09\x3b\xa1\x13\xbb\x7a\xc0\xc3\xc1\xba\x8e\x2d\xdf\x70\x75\xf0\x5b\x06\xca\x0e\xa2\x57\x75\xf0\x66\xcf\x44\x7d\x92\x56\xfd\xc6\x81\xfa\xd4\x01\xb4\xa4\xf6\x61\x5c\xad\x0b\xa3\x82\x1b\x08\xf9\x84\x82\x1a\x90\x2a\x6c\x3d\x7e\xb6\x6c\xad\x6b\x59\xdf\x7c\xdc\xa7\xb7\xff\x90\xb0\xd1\x91\x66\x09\xfe\x05\x7e\x35\x7f\xad\x1c\x0b\x00\x00")
+
+func inventoryHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_inventoryHtml,
+		"inventory.html",
+	)
+}
+
+func inventoryHtml() (*asset, error) {
+	bytes, err := inventoryHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "inventory.html", size: 2844, mode: os.FileMode(420), modTime: time.Unix(1792203854, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _labelsHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xad\x56\x51\x6f\xd3\x30\x10\x7e\xdf\xaf\x30\xd1\xf6\xb8\xb8\x1d\x48\x48\x5d\x1a\xa4\x31\x24\x10\x02\x86\x54\x90\x78\x74\x93\x6b\xe3\xcd\xb1\x83\xed\x74\x8d\xaa\xfc\x77\xce\x4e\xd2\x24\x5d\x8b\xa8\xc4\xcb\xbc\xf3\xe5\xee\xfb\xbe\xf3\xf9\xdc\xe8\xd5\xfd\xb7\xf7\x8b\x5f\x0f\x1f\x48\x66\x73\x11\x5f\x44\xdd\x02\x2c\xc5\x45\x70\xf9\x44\x34\x88\x79\x60\x6c\x25\xc0\x64\x00\x36\x20\x99\x86\xd5\x3c\xc8\xac\x2d\xcc\x8c\xd2\x24\x95\x8f\x26\x4c\x84\x2a\xd3\x95\x60\x1a\xc2\x44\xe5\x94\x3d\xb2\x2d\x15\x7c\x69\xe8\xb2\x14\x39\xa3\x93\xf0\x26\x7c\x4d\x13\xd3\xda\x61\xce\x65\x88\x56\xf0\x7f\x30\x56\x4a\xda\x6b\xf6\x0c\x46\xe5\x40\xdf\x84\x6f\xc3\x89\x87\x1a\x6e\x0f\x11\x2d\xb7\x02\xe2\x3b\xbe\xfe\x5e\x82\xae\xc8\x42\x29\x61\x66\x64\xb7\x0b\x1f\xb4\x7a\x84\xc4\x7e\xba\xaf\x6b\x92\x28\x63\x0d\x59\x56\x44\xb0\x25\x88\x88\x36\x41\x17\x11\x6d\x4b\xb3\x54\x69\x85\x8b\xc1\xef\xb9\x92\x24\x11\xcc\x18\x24\x0c\x5a\x11\x6e\xae\x0b\xcd\x73\xa6\x2b\x44\x23\x24\x4a\xf9\x66\xe8\xbf\x76\xa1\xde\x33\xf6\x25\x48\x97\x71\x09\xba\xf5\xa1\x37\x9b\x76\x4e\x0f\xef\x32\x4f\x83\x73\x99\x67\xd3\x16\x8b\x22\x98\x27\xd4\xfc\x13\xd1\x96\x7c\x7c\xf1\x42\x47\x6b\x06\xf1\x69\x82\x63\x8f\x28\x73\x69\x8e\x8a\x72\x1e\xe2\x42\x41\x5a\x27\x40\x32\xad\xd5\x73\xaf\xb1\x88\x23\xd6\x1e\x37\x2d\x1a\x19\x86\x8e\x25\xa1\x64\x96\x3c\x11\xab\x0e\xa4\x46\x94\xc5\x11\x2d\x90\x7f\x93\x6b\xb7\xe3\x2b\x12\x7e\x86\xca\xd4\xf5\x7e\xeb\xf2\x09\x2a\x32\x9b\xfb\xfd\xe1\x76\xd1\xe5\xf1\xce\x41\xd6\x8e\xd8\x40\x85\x65\x4b\xb3\x67\x8c\xae\x52\xf4\x86\x4b\xa6\x99\x5c\xc3\x01\xb2\xff\x50\x70\xcf\x09\x7e\x93\x90\x38\x22\xee\x78\x9a\x94\x58\x09\x86\x35\xde\x40\xb0\xdb\x81\x4c\xeb\xfa\x78\x19\x7a\x9a\x75\x4d\xfd\x81\x9a\x77\x98\x67\x8e\x85\x70\x75\xf1\x4b\x53\x06\xc1\xc7\x9c\x7c\xd2\x9e\x32\xed\x39\xb7\xe7\xdf\x59\x28\x0e\x5b\xab\x57\x2a\x60\x28\xd5\x36\xfd\x3e\xd0\x64\xf5\xd0\xf4\x9f\x20\x3c\xfe\xf9\xb7\x5d\xe2\xaf\x39\x22\xc1\x16\x6f\xa7\xe0\x6b\x39\x23\x9a\xaf\x33\x7b\x1b\xc4\x97\xf4\x0b\x36\x4a\x76\x76\xdc\x5d\x65\xc1\x9c\x1d\xb5\x70\x5a\x8f\x87\xb9\xc2\xfa\x7e\x39\xf4\xa2\x3d\x90\xef\xbc\xbe\x3c\x83\x7a\x35\x83\x61\x78\x12\x97\x56\x59\x26\x3c\x47\xdf\x6b\x8b\xbd\x39\x6a\x96\x7d\x1b\xfd\x64\xa2\x3c\xf0\x1d\x29\x7a\xda\x49\xdb\x80\xb6\x3c\x61\xa2\x93\x97\xf3\x34\x15\x70\x4b\x9e\x79\x6a\xb3\x19\x99\x4e\x26\xc5\xf6\x36\x18\x87\xbb\x7b\xa7\xd5\x5a\x83\x31\xdd\xc9\xef\x6d\xec\x4c\x93\x33\x21\x02\xb2\x71\x44\xe6\x81\xbb\x74\xa0\x13\x77\x7f\x07\x52\xb0\xfd\x48\xce\xb6\xf3\x00\x01\x82\x8e\xcb\x01\xe6\xa9\xc8\x88\x76\x68\x07\xaa\xa8\x4d\x4f\xea\x6c\x73\xdf\xb8\xd4\xe4\xd8\x79\x9e\x42\xbb\xfa\x6b\xda\xa3\x7d\xb8\xdb\xe1\x04\x97\x76\x45\x82\xab\xf0\x66\x15\x90\xf0\x5e\x09\x7c\x78\x0c\xe6\xf7\x1d\xea\x1b\xe3\xbc\x9c\xc8\xee\x63\x99\x33\xb9\x2f\xc1\xf9\xf1\x4d\xc3\x9e\x88\x8d\x9b\xe9\xf7\x43\xfa\x29\x01\x78\xf9\x23\xc8\xe3\xb2\x33\x23\x8a\x16\x4e\x05\x61\x00\x3d\xa6\x60\xb2\xbf\xf2\x6b\x9f\xdd\xb7\x9d\x4b\xee\x9c\x71\x3b\x40\x0e\xa1\xc6\xfd\x7f\x6c\xce\x8c\x2e\x00\x9a\x8e\x73\x67\x76\xf8\xfd\xec\xff\xaa\x88\xff\x02\xdb\x4e\x12\x9b\x71\x43\xda\x81\x47\x32\xb6\x81\xe6\x09\x33\xa1\x1f\xf2\x2f\x01\x5f\xbe\x68\x6e\x19\xbd\x6b\xb4\x7d\xa7\x69\xf3\xc3\xe6\x0f\xb5\x4e\xd2\xee\xf0\x08\x00\x00")
+
+func labelsHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_labelsHtml,
+		"labels.html",
+	)
+}
+
+func labelsHtml() (*asset, error) {
+	bytes, err := labelsHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "labels.html", size: 2288, mode: os.FileMode(420), modTime: time.Unix(1792203493, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _loadingHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x6c\x93\x41\x8f\xd3\x3e\x10\xc5\xef\xf9\x14\xb3\x96\xfe\xd2\x9f\x43\xe2\x16\xc4\xa5\x38\x41\xcb\x2e\x17\x24\xd4\x05\xf5\x00\x47\xd7\x99\x36\xee\x3a\x76\x98\x71\xd2\x2d\xab\x7e\x77\xe4\x36\xad\xc2\x2e\xa7\x64\xfc\x92\xf7\x7e\x6f\x94\xa8\x9b\xfb\xe5\xdd\xea\xe7\xc3\x67\x68\x62\xeb\xaa\x4c\x5d\x2e\xa8\xeb\x2a\x53\xce\xfa\x47\x20\x74\xa5\xe0\x78\x70\xc8\x0d\x62\x14\xd0\x10\x6e\x4a\xd1\xc4\xd8\xf1\x42\x4a\x53\xfb\x1d\x17\xc6\x85\xbe\xde\x38\x4d\x58\x98\xd0\x4a\xbd\xd3\x4f\xd2\xd9\x35\xcb\x75\xef\x5a\x2d\x67\xc5\xdb\xe2\x9d\x34\x3c\xce\x45\x6b\x7d\x61\x98\x45\x95\xa9\x68\xa3\xc3\xea\x93\xdd\x7e\xeb\x91\x0e\xb0\x0a\xc1\xf1\x02\xee\x02\x47\x18\x2c\xf7\xda\xd9\xdf\x3a\xda\xe0\x95\x3c\x3f\x99\xa9\x9b\x3c\x87\xd5\xf2\x7e\xb9\x80\xdb\x2f\xb7\x3f\x3e\x42\x9e\x57\x99\x6a\x31\x6a\x48\x4c\x39\xfe\xea\xed\x50\x0a\xc2\x0d\x21\x37\x02\x4c\xf0\x11\x7d\x2c\xc5\xfb\x14\x27\xc7\x6a\xeb\x50\x1f\xaa\x4c\x31\x9a\x64\x0e\xc6\x69\xe6\x52\x34\x48\x01\x2c\xe7\x1d\xd9\x56\xd3\x41\x54\x19\x80\xaa\xed\x30\xd5\xf3\xf4\xea\x49\xf9\x5b\x4b\x39\xda\x7a\xa4\x51\x03\x50\xcd\xfc\x22\x9e\xd8\x93\xf3\x5c\xbc\xe8\xaa\x64\x33\x1f\xcd\x64\x6d\x87\x53\xe2\xf9\x46\xc9\x91\xae\xca\x5e\x81\x8e\xe3\x2b\xc0\xb1\x6c\x4a\x6a\xb1\xb6\x7d\x0b\x2f\xb1\x26\x50\xdc\xaf\x4f\x5c\xa2\xfa\x8e\xba\xb6\x7e\x0b\x1b\x0a\x2d\x5c\xf9\x3a\x87\x9a\x11\xf6\xda\x46\x28\x8a\x62\x42\xda\x51\xd8\x12\x32\x5f\x9c\xae\xb3\xe5\xdc\x69\xda\xe2\x74\x89\x30\x68\xd7\x63\x29\x9e\x9f\x8b\x07\x24\x83\x3e\x1e\x8f\x02\x5a\xfd\x54\x8a\xf9\x6c\x26\xaa\xe9\xf9\x7f\x4a\x5e\xbc\x2e\x51\x49\xff\x8a\xcc\x7a\x8b\xc7\xa3\x92\xdd\xf5\xfc\xff\x55\x63\x53\x22\x98\x9e\x08\x7d\x74\x07\x18\x12\x36\xbb\xb0\x87\x4d\x20\x38\x93\x74\x14\x76\x68\x22\x7f\x80\x3d\x82\x26\x84\x7d\xa0\xc7\x54\x36\x78\xb0\xf1\xcd\xe8\xf8\xaf\x9d\xcb\xf1\x23\x91\xe7\xbf\xe2\x4f\x00\x00\x00\xff\xff\xd0\x3f\x38\xb9\x2d\x03\x00\x00")
 
 func loadingHtmlBytes() ([]byte, error) {
@@ -111,7 +197,7 @@ func loadingHtml() (*asset, error) {
 	return a, nil
 }
 
-var _projectHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xec\x57\x4b\x6f\xe3\xb6\x13\xbf\xfb\x53\xcc\x9f\xc8\x02\xff\x1e\x2c\x3a\x69\x81\x02\x8e\xac\x02\xbb\x6e\xd1\x00\x7d\xa4\x68\x2e\x3d\x8e\xa5\x91\xc4\x94\x22\x5d\x92\x4e\x62\x08\xfa\xee\x05\x29\xc9\x91\x14\x39\x4d\x80\xed\x69\x7b\x92\x87\xf3\x7e\xfd\x48\xd7\x75\x46\xb9\x50\x04\x6c\x2b\xec\x5e\xe2\xf1\xd6\xe8\x7b\x4a\x1d\x6b\x9a\xba\x8e\x7e\x30\x82\x54\x26\x8f\xbf\x60\x45\xfe\x40\xe4\xa0\x08\xa2\x9b\x2d\x4c\x58\xf0\xff\xba\x8e\x6e\xb6\x4d\xf3\x55\x5d\x93\xca\xbc\x6c\xf8\x2c\xe2\xff\x6d\x7f\xfd\x74\xf7\xc7\xed\xf7\x50\xba\x4a\x26\x8b\xb8\xff\x10\x66\xc9\x22\x96\x42\xfd\x09\x86\xe4\x86\x59\x77\x94\x64\x4b\x22\xc7\xa0\x34\x94\x6f\x58\xe9\xdc\xde\xae\x39\x4f\x33\x75\x6f\xa3\x54\xea\x43\x96\x4b\x34\x14\xa5\xba\xe2\x78\x8f\x4f\x5c\x8a\x9d\xe5\xbb\x83\xac\x90\xaf\xa2\xab\xe8\x6b\x9e\xda\x8e\x8e\x2a\xa1\xa2\xd4\x5a\xf6\x79\x7c\xe4\x5a\xb9\x25\x3e\x92\xd5\x15\xf1\x6f\xa2\x6f\xa3\x55\x70\x35\x3c\x1e\x7a\x74\xc2\x49\x4a\x3e\x8a\xe2\xb7\x03\x99\x23\xdc\x69\x2d\xed\x1a\xea\xda\x51\xb5\x97\xe8\x5e\x16\x1b\xa2\xa6\x89\x79\xab\xb6\x88\x79\x57\x9c\x9d\xce\x8e\xc9\x22\xb6\x94\x3a\xa1\x15\xa4\x12\xad\xdd\xb0\x92\x8c\x06\x61\x97\x7b\x23\x2a\x34\x47\x96\x2c\x00\xe2\x4c\x3c\x0c\xf9\x4b\xaf\x1a\x38\x63\x5e\xaa\x95\x43\xa1\xc8\x74\x3c\x80\xb8\xbc\xec\x99\xc1\xbd\xb7\x7c\xc9\xde\x1f\x7b\x79\xd9\x79\xe3\x99\x78\x08\x21\xb5\x3f\x62\xde\x85\x9f\x2c\x5e\x64\xd2\x91\x2c\x39\x1f\xe2\x98\x23\x0f\x95\xb2\xb3\x69\x79\x8e\x0f\x5d\xa1\x31\xfa\x11\xbc\x11\x52\x6e\x98\x65\x72\xa7\x1d\x4a\x0b\xb9\x36\x6f\xcb\xa6\x57\x75\xb8\x93\x74\xaa\x91\x27\x18\x84\x41\xda\xb0\x47\x91\xb9\x72\x0d\x78\x70\xfa\xfa\xe4\xcb\xab\x98\x67\xc2\x93\xe5\x44\xe1\x72\xb5\xda\x3f\x5d\xb3\xe4\xe3\xd1\x91\x8d\xb9\x2b\xc7\xe2\x59\x52\xd7\xd1\x8f\x87\x0a\x55\x10\x08\xa3\x91\x0d\xcc\xf3\xa1\xfd\x37\x3b\xfb\xa4\xad\x9b\xf3\x75\x51\xd7\x7b\x23\x94\xcb\x81\x7d\x88\xae\x72\x06\x51\xa8\x94\x17\x6f\x1a\x5e\x69\xe5\xca\xf3\xfe\x63\x1e\x2a\x32\xdf\xfb\xf7\xf6\xaf\xeb\xda\x73\x1f\x47\xfd\xfb\x09\x4d\x41\xd6\xc1\x16\x1d\x5a\x72\xf6\x1f\xbb\x34\xac\x51\xbb\x51\xc3\xc4\x47\x55\x0b\x22\xc9\xb4\x3a\xaf\x9c\xf6\x35\x76\xf4\xe4\x96\x28\x45\xa1\xd6\x60\x44\x51\xba\x6b\x96\x5c\xf0\x9f\xbb\xa2\xbd\x4f\x6f\x76\x1a\xda\x18\xba\x9c\xe1\x66\xfb\xa2\x83\xe3\x61\xe0\x5d\xa6\x83\xd4\x5b\x14\x79\xd6\xa8\xeb\x8b\x7d\x3b\xec\x37\x5b\x58\x6f\x20\xc0\xf6\x98\xef\xfc\x00\x84\x70\x82\xc0\xdd\x89\x9c\x08\x1a\x54\x05\x41\xd4\x45\xf7\xbb\xd3\x06\x0b\x1a\xc9\xcc\xd4\x39\xeb\x6b\xf0\x40\xc6\x89\x14\x65\x5f\x87\x4a\x64\x99\xa4\x6b\x98\xcc\xed\x48\x1d\x20\xde\x1b\x5d\x18\xb2\xb6\x6f\xf6\x89\x16\x76\x69\x2b\x94\x92\xc1\x03\xca\x03\x6d\x58\x5d\x47\xb7\x64\x52\x3f\x52\x83\x94\x9a\x86\x41\x85\x4f\x1b\x76\xb9\x5a\x4d\x37\xb9\xf7\x79\x4e\x33\xe6\xbd\xb7\x49\x56\xa3\x05\x99\xe4\xd9\xd9\xbe\xf2\xa6\x61\xae\xf1\xe7\xbc\x7d\x78\xd5\xec\xec\xe8\xbd\xd8\xe5\xad\x96\x12\x8d\xbd\x25\x13\x86\x72\x8a\x25\x6f\xb0\xf9\x2a\x16\x75\xfa\x49\x2c\xfa\x76\xe4\x08\x39\x2e\x33\x74\xb8\x43\x4b\x2c\x89\xb9\x48\x20\xc6\xc9\x7d\xbb\x13\xc5\x5f\xfe\x7e\x69\xaf\xdc\xa8\xd0\xba\x90\xed\xa5\x9b\xb5\xc3\xc4\x87\x63\xda\x34\xeb\xee\x79\x11\xc2\xf1\x3f\x62\x8e\xc9\x34\x94\xf1\x2e\xf8\x01\x6d\xdf\x20\x03\xfe\x70\x19\x4e\xd8\xb5\x98\x41\x99\x3b\xcf\xfa\xa2\x30\x26\x64\xfc\x99\x10\xa6\x03\x86\x60\xf2\x3f\x58\x98\xcf\xf3\x4b\x84\x85\x6e\x69\xde\x8b\x09\x41\xed\x0c\x22\x7c\xe7\x70\xb7\xc9\xc8\xa1\x90\xf6\x5f\x41\x07\x98\x7d\xd5\xfa\xcf\xe8\x6d\xcb\xbb\xd7\x3a\x0f\x7f\x70\xfe\x0e\x00\x00\xff\xff\xfe\xe6\x2a\xe7\x57\x0d\x00\x00")
+var _projectHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xed\x5a\xdd\x8f\xdb\xb8\x11\x7f\xdf\xbf\x62\xaa\xcb\x01\xc9\xc3\x4a\xf6\x5e\xaf\x05\x7c\xb6\x83\x6c\xbc\x87\xa4\xd8\x5c\x73\x59\xb7\x40\x1f\x69\x89\xb6\x98\x93\x44\x85\xa4\xbd\x36\x7c\xfe\xdf\x3b\xfc\xd0\xa7\x65\x7b\xbd\x71\x03\x14\x08\xf6\xc1\x4b\x91\x33\x9c\xe1\xcc\xfc\x66\x86\xd2\x76\x1b\xd1\x39\xcb\x28\x78\x13\x26\xf3\x84\x6c\x3e\x0a\xfe\x99\x86\xca\xdb\xed\xb6\x5b\xff\x57\xc1\x68\x16\x25\x9b\xdf\x48\x4a\xf5\x03\x36\x07\x5c\xea\xbf\x9f\x40\x6b\x0a\x5e\xe2\xea\xf7\x93\xdd\xee\xd5\x76\x8b\x8f\xf5\x5a\xf3\x73\x35\xfc\xcb\xe4\x9f\x6f\xa7\xff\xf9\x78\x07\xb1\x4a\x93\xf1\xd5\xb0\xf8\xa1\x24\xc2\x9f\x84\x65\x7f\x80\xa0\xc9\xc8\x93\x6a\x93\x50\x19\x53\xaa\x3c\x88\x05\x9d\x8f\xbc\x58\xa9\x5c\x0e\x82\x20\x8c\xb2\xcf\xd2\x0f\x13\xbe\x8c\xe6\x09\x11\xd4\x0f\x79\x1a\x90\xcf\x64\x1d\x24\x6c\x26\x83\xd9\x32\x49\x49\xd0\xf3\x6f\xfc\x9f\x82\x50\xba\xb1\x9f\xb2\xcc\xc7\x91\x77\x99\x3d\xe6\x3c\x53\xd7\xe4\x91\x4a\x9e\xd2\xe0\xaf\xfe\xdf\xfd\x9e\xd9\xaa\xfe\xb8\xbe\xa3\x62\x2a\xa1\xe3\x5b\xb6\xf8\x7d\x49\xc5\x06\xa6\x9c\x27\x72\x00\xdb\xad\xa2\x29\x1e\xb1\xda\x3f\x6c\xf0\x77\xbb\x61\x60\xc9\xae\x86\x81\x3b\x9c\x19\x8f\x36\xf8\x23\x71\x05\xe3\x19\x84\x09\x91\x12\x45\xa6\x82\x03\x93\xd7\xb9\x60\x29\x11\x1b\xdc\x0f\x60\x18\xb1\x55\x7d\xfe\x5a\x93\x9a\x99\xe6\x5c\x88\x02\x13\xb4\xb6\x70\x73\x38\x1b\xf7\x8b\x49\xb3\xbd\xe6\xdc\xf7\xce\x97\x3d\xee\xbb\xdd\x02\xdc\xce\x88\x64\xff\x19\x06\x4e\xfc\xf1\xd5\x9e\x26\x6e\xe8\x8d\x0f\x8b\x68\x5c\xce\xbf\xe7\x24\x62\xd9\xe2\x4e\x08\x2e\xd0\xa7\x9a\x3a\x65\x5c\xb1\x39\x0b\x89\xe1\x8c\xd2\x47\x24\x5b\x68\xea\x69\x4c\x01\x57\x28\x34\xfd\x5c\xa0\xd5\x61\x4e\x58\x42\x23\xad\x4b\x8b\x61\x29\x73\xe1\xb4\x57\xed\x53\x4b\x96\x69\x26\x3b\xcf\x53\xcf\xe8\x5d\x33\x82\xbc\x1e\x41\x4b\x4f\x33\x55\x3f\xde\xf1\x94\x2b\x92\x48\x98\x73\xf1\xb4\x63\x2c\x48\x15\x99\xa1\x3d\x0a\xe3\xe8\x81\x07\xc6\x83\x47\xde\x23\x8b\x54\x3c\x00\xb2\x54\xfc\x97\x72\x2f\x4d\x22\xaa\x81\x1e\xc6\x2d\x82\x7e\xaf\x97\xaf\x91\x02\x7d\x2d\x3e\xb0\x52\xd1\x35\xfa\x74\xc2\x16\xd9\x00\x04\x5b\xc4\x0a\x97\xdf\x6e\x14\x95\x67\xd2\xbc\xe5\x52\x35\x49\x70\x24\x8e\xc9\x3a\x7e\x83\xee\xb0\xa2\xfb\xfb\x44\xc7\xf6\x41\x6b\xbe\x5b\xa6\x24\xb3\xc4\x46\x52\x13\x4b\xd1\x19\x3c\x5e\x6c\xb7\x18\x4d\x99\x9a\x83\xf7\xa3\x7f\x33\x47\x5b\x58\x6e\x5a\x87\xdd\x2e\x48\xd1\xa8\x71\x93\xe5\x49\x5d\xee\x79\xb6\xb8\x9e\x52\x91\x3e\x53\x1d\x4d\xaf\xc9\x2f\xa5\x50\xc1\xef\x2b\x54\x32\x7e\xfc\x4c\x75\x2e\xa5\x86\x91\xe1\x69\x3a\xe0\xff\x3a\x66\xaa\x78\xca\xc7\x43\xe2\x60\x3f\xc8\x6d\xd4\xc9\xc0\xa5\xad\x20\x21\x33\x9a\x48\xeb\xb6\x12\x66\x1b\x30\x0f\x86\x01\x19\xc3\x9f\x70\x84\x8e\x65\x2b\x8c\x77\xae\x71\x78\xaa\xb7\x93\x40\xb2\x08\x56\x8c\x3e\x1a\x2e\x6a\x93\xd3\xd3\x4c\x10\x46\x22\xbd\x75\xa2\x4f\x83\x0b\xb2\x30\x34\xc3\x20\x1f\x57\xb2\xdf\xad\x73\x2e\x14\x90\x24\x01\xa3\x17\x82\xf2\x11\x8e\xd4\xac\x7e\x8d\x98\x93\x12\x35\x0a\xe5\x0a\xd9\x3f\xfc\xfb\xb4\x24\x4d\xba\x75\x22\xd7\x1e\xee\x1c\xda\x93\x30\x02\x15\x12\xe9\x25\x90\x52\x15\xf3\x68\xe4\xe5\x78\x6a\x1e\x10\x03\xe7\x1d\x6c\xeb\x10\x35\x5b\x2a\x55\xa5\x00\x37\xaa\xa5\x33\x73\x64\x98\x1b\x96\xb3\x94\x21\x8e\x7e\xb2\xe0\x3d\x0c\xec\xca\xca\xb8\x7a\xff\xee\x94\x53\xa6\x8d\x4f\x14\xb3\x78\x8a\x90\x6e\x72\x83\xdc\xcb\x1c\x27\x71\xbd\x0b\xcd\x5b\x4c\xab\xcc\x67\xac\xf4\x2b\x4f\x12\xfe\x88\xa9\x05\x54\x4c\x25\xc5\xdc\xd3\x58\x8d\x1c\x97\xda\xc8\x64\x45\xd1\x4f\x80\x4a\x85\x4a\x2b\x1a\xc1\xbe\xa7\x37\xf7\x79\x20\x2b\x64\x2a\x0b\xaf\xf7\x1b\xa6\xe8\x48\x14\xf5\x40\xb6\xd5\x44\x3d\xe8\x1a\xa1\x7d\x12\xc9\xdd\xde\xed\xc8\xb7\xa0\xd0\x14\xf3\xd0\x1a\xc9\x97\x22\xdc\x43\x76\x3b\xbb\x87\x28\x4d\x1c\x0a\x5a\xf2\x0f\x95\x2d\x8b\x2a\x82\xed\x56\xe8\x94\xdf\x6d\xef\x23\x4a\x9f\x09\x3d\x13\x34\x2d\x11\xf2\x23\x15\x1f\xb4\x09\xba\x01\xc8\x71\x1e\x0f\xa5\x12\x88\xb8\x1a\xff\xa6\xba\xac\xd2\xd0\xe7\x1e\x0d\x67\x42\x3f\xfe\x40\xa5\xc4\x40\xdf\xc7\x44\xcb\x00\x57\x14\xc7\x76\x68\x49\xe3\xc1\xd3\x23\xf2\x85\x8d\xf4\x88\xc9\x94\x49\xe9\xb5\xd9\x20\x23\x96\xe5\x4b\xe5\xe2\x30\x66\x51\x44\x33\x0f\x32\x2c\xf3\x47\x9e\x58\xea\x22\x64\x45\x92\x25\x0e\xb4\x88\x4b\xad\xda\x99\x3c\x9c\x5a\x0d\x3e\xa5\xaa\x5d\xbc\x0e\x41\x86\x4c\x11\x0d\xdb\x80\x31\xb1\x7a\xb5\x01\xa3\xf2\xa7\x0a\x38\xaa\x67\xad\xac\x14\x34\x9d\xa5\x28\x0b\x6b\xf3\x75\x1f\x2c\x13\x8d\x1d\x34\xc1\xa8\x51\x54\x6e\xcb\x66\xcb\x14\xd7\x0f\x39\xce\x78\x16\x96\x0e\xc6\x70\x33\x7e\x87\xcd\x1c\x7d\x6e\x05\xf6\x84\x32\x0f\x6e\x59\x82\x65\xf2\x59\x84\x0f\x09\x57\xf0\x0e\x2d\x28\xcf\x22\xd3\x87\xc0\xda\x85\x65\x1b\x12\x2a\x63\x34\xa0\xa0\x09\x03\x25\x04\x94\x56\x6a\x1d\xd4\xf3\x62\xbd\x1d\x79\xe7\x14\x3b\xf6\x18\xcf\xe7\xa0\xcf\xd2\x1c\xe5\xf9\xa4\xff\xe0\xb3\x2e\xaa\xb1\xcd\x86\x77\x6b\x82\xad\x87\x46\x93\x90\x47\x74\x5c\xd7\xb9\x7f\xd3\x93\x5e\x7d\x45\xe0\x96\x60\x49\x64\xfa\x7e\xbf\xb8\x00\x30\xae\x5c\xdf\xa1\x1e\x2b\xf5\x38\xa9\xc5\x48\x2d\x3e\x1a\xb1\xf0\xc8\xd0\x3d\x7c\x13\x09\xa6\xea\xba\x4c\x7e\xb6\x6d\xab\x61\x38\x80\x7b\xdd\xff\xa1\xf8\x13\xb2\x41\xf6\xa0\x7f\xea\x09\xdb\x1e\xcc\x54\x2c\xb3\x50\x67\xe1\xca\x79\x8e\xb4\x97\x8f\x44\x64\x98\x0e\x75\x7f\xc9\x24\x38\x5c\x05\x74\x3f\x50\x9c\x03\x5a\x7f\x03\x5f\xac\x5f\xe3\x03\x48\xb0\xd1\xd4\xa5\x40\xaa\x0b\xb7\x01\xf0\x2c\xd9\xe8\x21\xa4\xdc\x34\xa6\x21\x4a\x5f\x2e\x27\x02\xbb\xef\x2c\x4c\x96\x11\x8d\xfc\x12\x3e\xf6\x01\xc8\x49\xad\x2b\x61\x67\xf2\xaa\xfe\xd0\xf9\xa6\x36\x51\xf2\x9e\x19\x6f\x84\x72\x7a\xdf\x4f\x07\x1d\x35\x88\x5d\x5b\x06\x03\x10\x85\x1a\x5c\x47\x34\xd5\x35\x2e\xae\x0d\xa9\x6c\x56\x22\xf1\x0d\x76\x09\x39\xfc\x4b\x52\x0d\x05\x38\x2a\x45\xae\xfa\xde\x1a\xf4\x81\x6f\x56\x5a\x87\xa8\x33\x28\x91\xa1\x62\x71\xa8\x7c\x77\xe9\xcc\x29\xea\x8d\xdf\x60\x81\x2c\x68\x4e\x4d\x59\xf5\xa5\xe0\xd3\xa8\xa6\x0f\x4a\xe3\xb6\xdd\x97\xc7\x95\xf6\x9f\x10\x7e\x5a\x32\xfd\x5e\xd8\x3a\x26\xda\xa0\x68\x6d\x49\x57\x54\x90\xa2\x4a\xd7\x15\x1f\xda\x58\x71\xf4\x9b\x48\x02\x25\x61\x0c\x7c\x6e\x5c\xc2\x7f\x8a\x48\x76\xe7\x9a\xed\x6d\x48\x56\x02\xfc\xc6\x4b\x23\x3f\x52\xf4\x20\x74\xe6\x26\xe3\x7a\x4c\x76\xe4\xa6\x82\xdd\xd7\x05\x5e\xee\xe2\x2e\x34\xed\x53\xac\xeb\x5b\x0c\x1c\x98\x51\x9a\x99\x18\x40\x8f\x86\x7b\x86\xe5\xae\xad\x8c\x4b\x91\x51\x5a\xdd\x26\xe9\xae\x66\xa9\x5d\x01\x8f\xf0\xcb\x92\x09\x73\xa0\x14\x2c\x52\xcd\xd8\x42\x2f\xdf\xf8\x9f\xd1\xa7\xfd\x04\xb9\xa0\x8d\x1d\x44\x41\x8e\x3d\x2d\xa6\x7b\x0c\xce\x4a\xeb\x13\x39\xf8\x85\xcc\x48\x2e\x63\xae\xde\x4f\x60\x30\x02\xff\xa1\x1c\x9a\x83\x30\xe1\xb5\x50\xf0\x32\x41\xd9\xfd\x77\x4c\x77\x64\x9b\x57\xd0\x7f\xf6\x29\x55\xd7\x43\x0d\xa0\x7a\xb0\x9d\x1e\xb8\x1d\x5a\x8d\xc4\x91\x1e\x2d\x62\xf3\xb9\xce\xee\x69\xae\x11\xa3\x50\x46\xee\x75\x68\x72\xb5\x00\x73\xef\x33\xf2\xfe\xd6\xeb\x79\x10\x53\x9d\x2a\x46\x5e\xff\x67\x1c\xe8\x0e\xf5\x96\xaf\x47\x5e\x0f\x7a\x80\xd3\x60\x9e\xba\xfc\x32\xe3\x22\xa2\x62\x00\xfd\x7c\x0d\x92\x27\x2c\x82\x1f\xa2\x99\xfe\xfb\x05\x38\x3a\xf7\x1c\x5b\x9c\x01\x72\x90\x0c\x7d\xb3\x71\xff\x94\xf3\x64\x93\xe8\x02\x67\x8e\xa8\xa2\xb1\x33\x33\x77\x56\x82\xff\x81\x5c\x7f\xe8\xf5\xa2\xfe\xec\xa6\x78\x70\xed\x64\xc3\x07\x39\x47\xd8\x91\xa6\x12\x74\xa7\xf1\x36\x26\x42\x7d\x34\x8f\xb1\x26\x84\xa0\x4a\x37\xa8\xd5\x45\x3b\x9f\x71\x61\xfe\xce\xee\xe4\x68\xd9\x10\x7c\x70\x0d\xc0\x79\x74\xdd\xb7\x69\xdd\x5d\xcf\x53\xda\x1e\x77\x64\x27\xdb\x1d\x5b\x07\xd0\x2f\xe6\xfe\xbe\x16\x04\x98\xd0\xeb\xdd\x4a\x5a\x6f\x56\x0a\x80\x28\xdd\xf1\x75\x41\x37\x2a\x7b\xfb\x1a\x15\x19\x77\x94\x08\xa5\x00\x5f\xd9\x6f\x9d\xcd\xf3\xd4\xe5\xd3\x33\xcb\xfc\x5a\x0c\x4f\x88\x22\x92\xaa\x66\x0c\x5f\xd2\x39\xcf\x76\x2e\x0c\x9c\x6c\x71\xa0\xd3\x2e\xa4\x7d\x3f\xb9\xa0\xf7\x39\xa6\x4f\x75\xc2\x42\x76\x84\x11\x85\x55\x55\x52\xc8\x9f\x62\x8f\xd8\x04\x13\x47\x52\x43\xb1\x7e\x1d\xc5\x6e\xda\x20\x86\xb3\x70\x53\x61\xd8\x51\xa0\x2a\xb9\x3f\x07\xb0\xfa\x0d\xc0\x3a\x84\x54\xd5\x61\x1a\xc4\x6a\x3e\x7a\xa6\x23\x5b\xe3\x1e\xba\x0f\x18\xb2\xc2\xd9\xe6\x04\xe6\xe4\x3a\x42\xcb\xcc\xd0\x36\xfa\xba\x9f\x8d\xc1\xc5\xeb\x37\x6b\x76\x2f\x98\x2a\xef\x89\x58\x50\x2c\x98\x9d\xaf\xc9\x6f\x14\x67\xe7\x47\xdf\x45\x53\xc2\x49\xaa\xee\xf7\x25\x27\xc9\x0e\xbe\x9a\x00\xf7\xe6\x22\x3c\x72\x97\x77\xb7\xce\x99\x38\x32\x7f\x18\x63\x1c\x77\x73\xcf\x7e\x09\xfc\x79\xe1\x0a\x23\x57\xc5\xb9\xea\xad\x36\xaf\x74\xf7\x62\xef\x34\xf4\x82\x69\x39\x6c\x2d\x6c\x02\x99\x2b\xcb\xbe\x16\xc8\xa0\xf5\xc6\xad\x8d\x0a\x28\xfc\x02\xcb\x5c\x59\xf8\x6d\x39\xae\xee\xb5\xaa\xeb\x31\xcc\x81\xa6\x53\xac\xa9\xa4\xb1\x26\x25\x6b\x07\x8a\xdd\x6f\xf9\x0e\x51\x0e\x83\x62\xb7\x33\x70\xc9\xf1\xbe\xd1\xac\xe1\x00\x48\x75\xee\xf6\xe3\xff\x43\x2d\xf0\x64\xfa\xa3\xaf\x19\x9f\xcc\xe5\xc4\xdb\xbd\xf2\x0a\xb8\x88\xc5\x23\x4b\x0c\xbf\x2a\x2a\x9f\x99\x1c\xca\xfa\xae\xf8\x20\xa2\x6c\xba\xcc\x37\x11\xfe\x82\xf3\x45\x62\xbf\x8a\x88\x6c\x9c\x04\xf5\x08\xdc\xed\x06\xf5\x72\xd0\xe6\x19\xdd\x8e\x74\x0b\xed\x22\xce\x62\x81\x2e\x3f\x73\x92\x55\x00\xbe\x30\x3c\x4c\x11\x8a\xcf\xc7\x70\xa0\xa4\x7c\x76\xf9\xd6\x91\x58\x6c\x97\xfd\x3d\xad\xfc\x0f\xd3\x8a\x39\xe2\xcb\x96\x9e\x86\xe5\x77\xbc\xee\xd6\xf3\x3b\x5e\x7f\x6b\xbc\x6e\x43\xac\xc3\x8c\x26\xbe\x36\xae\x2e\x6b\xf8\x69\xd1\xe9\x35\x8b\x46\x5d\x40\x6a\x3a\x77\x7d\xe9\xea\x4f\x37\x39\x85\x97\xfa\x23\x3c\xf3\x9f\x37\x7d\x73\x7b\x7f\xe7\xbd\xda\xed\xa0\x13\x45\xf5\xa2\x12\x49\x2f\x0c\xa4\x76\xd0\xfe\xdc\x4b\xff\x34\x3e\xfa\x0a\xdc\x67\x6c\x81\xf9\xf2\xef\xbf\x66\xa5\x2e\x14\x70\x28\x00\x00")
 
 func projectHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -126,12 +212,32 @@ func projectHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "project.html", size: 3415, mode: os.FileMode(420), modTime: time.Unix(1479590702, 0)}
+	info := bindataFileInfo{name: "project.html", size: 10352, mode: os.FileMode(420), modTime: time.Unix(1792208206, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _select_projectHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xac\x53\xb1\x8e\xd4\x30\x10\xed\xf3\x15\x43\x2a\x28\x62\xdf\x01\x12\xd2\xc9\x97\x02\x0e\xa4\x6b\xe0\x10\xd7\x50\x4e\xec\xc9\xc6\x7b\x8e\x1d\x6c\xef\x42\x64\xe5\xdf\x91\xb3\xd9\x25\x7b\x4b\x41\x41\xe5\xd8\x6f\xe6\xbd\xe7\x17\x4f\x4a\x8a\x5a\x6d\x09\xca\x3b\x1d\x06\x83\xe3\x83\x77\x5b\x92\xb1\x9c\xa6\x94\xd8\x27\xaf\xc9\x2a\x33\x7e\xc6\x9e\xf2\x81\x6e\xc1\x12\xb0\x7b\x05\xcf\x20\x78\x99\x12\xbb\x57\xd3\xf4\x2a\x25\xb2\x2a\xd7\xce\x4b\x21\x5e\xdc\x7d\xf9\xf0\xf8\xfd\xe1\x23\x74\xb1\x37\x75\x21\x8e\x0b\xa1\xaa\x0b\x61\xb4\x7d\x02\x4f\xe6\xb6\x0c\x71\x34\x14\x3a\xa2\x58\x42\xe7\xa9\xbd\x2d\xbb\x18\x87\x70\xc3\xb9\x54\x76\x1b\x98\x34\x6e\xa7\x5a\x83\x9e\x98\x74\x3d\xc7\x2d\xfe\xe2\x46\x37\x81\x37\x3b\xd3\x23\xbf\x62\xaf\xd9\x1b\x2e\xc3\xb2\x67\xbd\xb6\x4c\x86\x50\xfe\x1f\x8d\xd6\xd9\x58\xe1\x4f\x0a\xae\x27\xfe\x96\xbd\x63\x57\xb3\xd4\xfa\x78\xad\x18\x75\x34\x54\xbf\xd7\x9b\xaf\x3b\xf2\x23\x3c\x3a\x67\xc2\x0d\x7c\x23\x43\x32\x02\xc2\x70\x88\x58\xf0\x43\x5d\x21\xf8\x92\x46\xe3\xd4\x58\x17\x22\x90\x8c\xda\x59\x90\x06\x43\xb8\x2d\x3b\xf2\x0e\x74\xa8\x06\xaf\x7b\xf4\x63\x59\x17\x00\x42\xe9\xfd\x1a\xaf\x72\xeb\x8c\x9c\x63\xd2\xd9\x88\xda\x92\x5f\x30\x00\xd1\x5d\x1f\xc1\x59\x3e\x33\x5f\x97\xcf\xcc\x0a\xde\x5d\x2f\x64\x5c\xe9\xfd\xac\x78\xf8\x10\x7c\x71\x57\x17\x17\x46\x97\xed\x85\x41\xe9\xcc\xae\xb7\x21\x2b\xf5\xae\xd1\x86\xfe\x6a\x34\xd7\xe4\x92\x0e\x4d\x9b\x57\xd7\xb6\x81\x62\xe5\x2c\x55\x3f\x76\xe8\xe3\xfa\x0e\x16\x4f\x8d\x03\x5a\x32\x27\x04\x40\x0c\x67\x48\x95\xa3\xd5\x76\x53\xd6\x97\xf1\x0f\x75\x71\x6a\x4b\xc9\xa3\xdd\x10\xb0\xe5\xfd\x87\x69\xfa\x43\x89\xe7\x94\x8d\x71\xf2\x29\x5b\x44\x19\xf5\x9e\x8e\x6f\x89\x2f\xc4\x81\x2f\xb3\xb0\x72\x05\x20\xc2\x80\xf6\x9c\x47\xcb\x1c\x96\xd0\xc7\xd3\x16\xa1\xc5\x4a\x61\xc4\x06\x03\x95\xb5\xe0\xba\x16\x3c\xf7\xad\x89\x52\x8a\xd4\x0f\x06\xe3\xe5\xcc\x02\x5b\xbb\xe6\x58\xaf\xae\x77\x18\xc7\x63\x80\xdc\xe2\xfe\x1f\x7e\x30\x5f\x5e\x24\x9f\xa7\xf6\x77\x00\x00\x00\xff\xff\xc5\x3b\xcc\xed\x2c\x04\x00\x00")
+var _queriesHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xad\x56\x51\x6f\xdc\x36\x0c\x7e\xcf\xaf\xe0\x8c\xee\x6d\x91\x93\x76\x43\x81\x9b\xcf\xc3\xda\x14\xe8\x86\xa0\x6b\xda\xbc\xec\x51\xb6\x69\x5b\xa9\x2c\x39\x92\x9c\xc4\xbb\xe5\xbf\x97\x92\xe5\x3b\xdf\x35\x59\x9b\x62\x4f\xb2\x44\x91\x1f\xf9\x51\x24\x9d\xfd\x70\xf6\xd7\xeb\xcb\xbf\xdf\xbf\x81\xd6\x75\x32\x3f\xca\xe6\x05\x79\x45\x8b\x14\xea\x13\x18\x94\xeb\xc4\xba\x51\xa2\x6d\x11\x5d\x02\xad\xc1\x7a\x9d\xb4\xce\xf5\x76\x95\xa6\x65\xa5\xae\x2c\x2b\xa5\x1e\xaa\x5a\x72\x83\xac\xd4\x5d\xca\xaf\xf8\x5d\x2a\x45\x61\xd3\x62\x90\x1d\x4f\x4f\xd8\x73\xf6\x22\x2d\x6d\xdc\xb3\x4e\x28\x46\xbb\xe4\xff\xc1\xa8\xb5\x72\xc7\xfc\x16\xad\xee\x30\xfd\x99\xbd\x64\x27\x01\x6a\x79\xbc\x44\x74\xc2\x49\xcc\x5f\x89\xe6\x62\x40\x33\xc2\xa5\xd6\xd2\xae\x60\xb3\x61\xef\x8d\xbe\xc2\xd2\xfd\x71\x76\x7f\x0f\xd7\x24\x13\x68\xb3\x74\xba\x7d\x94\xa5\x91\x93\x42\x57\x23\x2d\x96\x2e\x0a\xad\xa0\x94\xdc\x5a\xf2\x14\x8d\x06\x61\x8f\x7b\x23\x3a\x6e\x46\x82\x01\xc8\x2a\x71\xb3\x94\x1f\x7b\xd5\x20\xd9\x97\x95\xe4\x27\x17\x0a\x4d\x94\x91\xb4\x3d\x9d\x85\x01\xde\x5b\x3e\x4d\xbe\xd9\xe5\xf6\x34\x82\xa4\x84\x12\x3c\x99\x3e\xb2\x34\x7a\x9d\x1f\x7d\x11\x40\xdc\x26\xf9\xe3\x9e\xed\x4b\xe4\xd0\x29\xfb\x60\x34\x5e\x02\x5e\x15\x95\xdb\x85\xd4\xe7\x19\x8f\x69\x4d\xfb\xc9\x6b\x9b\xee\x47\x40\x11\xf2\xf2\x13\x38\x7d\x10\x59\x96\xf2\x3c\x4b\x7b\xf2\x7a\xb2\xb5\xd9\x88\x1a\xd8\xb9\xe6\x15\x56\xf7\xf7\x3b\xce\xf2\x0f\xd8\x23\x77\x58\xc1\xc5\x44\xc5\x0a\xce\xb9\x75\xde\xda\x19\x1f\x2d\x51\xe4\x97\x1d\x3f\xb3\xa5\x4b\x33\xa8\xd2\xeb\xed\x8c\x2d\x02\x52\xda\x89\x5a\x90\xdc\xb3\x45\x89\xb8\xe5\x46\x09\xd5\x24\xf9\x65\x2b\x2c\xc4\x50\xc0\x70\x45\x8e\x6b\xe8\xb8\x1a\xe7\x4c\xf8\x48\x24\x79\x09\xae\xc5\x0e\xb8\x94\x2b\xd0\x4a\x8e\x7e\x0b\x9d\x26\xc7\x0c\x96\xc4\xd1\xf6\x3a\xbd\x6c\x10\xaa\x94\x03\xc5\xc5\xb6\xc9\x9b\xfc\x44\xb5\xf0\xae\xcf\x2f\x66\x84\x96\xbb\xc9\x68\x25\xea\x1a\x0d\xe9\x7b\xf3\xc2\x80\x14\x0e\x0d\x97\x70\xc3\xe5\x80\xf6\x27\xb8\x6d\xe9\xc0\xf6\xbc\x44\xd0\x86\xd2\xd3\x75\x04\x3d\x61\x36\x46\x0f\x3d\xb1\xe6\x74\x83\xa4\x6c\x58\x20\x7b\x46\x7e\x66\xb5\x71\xb0\x5a\x03\xfb\x48\x1f\x4b\x27\xfc\x1e\x8a\x71\x15\x4f\x22\x9d\x78\x0d\x93\x4a\x52\x8c\x84\x98\x50\xfe\xac\x33\x5a\x35\x79\xd8\x43\x21\xa4\xc4\x8a\xde\xe2\x74\x48\xa1\x49\x8b\x74\x69\x7e\x1d\xbf\x79\xe5\xf5\xa4\x7b\xa0\xc2\xf3\x48\x04\xfc\xfb\x18\xe6\x95\x2e\x96\x90\x94\x59\xfb\x35\xa8\xa0\x12\x6f\x7e\x03\x42\x49\x99\x5b\x20\xf8\xed\xd7\x10\x82\x4a\xbc\xb9\x45\x98\x79\x5c\x3c\xec\xcc\xf1\x82\xaa\x7d\x2e\x7d\xbf\xd9\x16\x90\x97\x4e\x2d\x08\x60\x77\x62\x96\xdb\x70\x05\x42\x1b\x25\x6d\xbc\xa3\xee\x27\x45\xa3\x56\x60\x44\xd3\xba\x5f\xa9\xbc\x02\x9b\xaf\x22\x9b\xae\x7d\x92\xf2\x87\x40\xd0\x13\x95\x5e\x87\x98\x9f\xa8\xf4\x51\x6a\x07\x6f\xf5\x60\x1e\xc6\x0b\x8f\x7f\x3c\x14\xd1\x7e\x41\x86\x97\x06\xb2\x16\xec\x4d\x9d\x7b\xa7\xb1\xd9\x50\xd1\x36\x08\x2c\x16\xd3\x36\x25\x8f\x50\x5b\xfd\x97\xcf\xd4\x63\xde\x0e\x54\xfc\x81\xe2\x89\x61\xdf\xb8\x5c\xf5\x54\x2b\x7f\xd2\x6b\xfc\x0e\xcd\x67\x9b\x0d\xcd\x1e\xe5\x6a\x48\x7e\x64\xcf\xeb\x04\xd8\x99\x96\x34\x2b\xbf\xc7\x16\x79\xe1\x53\x10\x32\xf0\x88\xfa\xfe\x01\x1d\x95\xba\xc2\x7c\xe9\xc3\x8b\x93\x13\x4b\x5e\xbc\xd3\xa6\x23\xf3\xff\x4c\x74\x84\x5b\x87\xaa\x15\xd2\x90\x91\x36\xcf\xec\xd0\xf9\xe1\x99\xbf\xb9\xe3\x5d\x2f\x91\xaa\x2a\x1e\x64\xbd\xf1\xc6\x59\x14\x78\x4b\xfe\x84\x5a\x64\x54\xdd\x77\xef\xc0\xe1\xfd\x97\x11\x9a\x69\x28\xd2\x83\x6c\x7b\x52\x68\x78\x51\x8b\x54\xeb\xe4\x97\x24\x7f\xa7\xb7\x8d\xf9\x16\xa9\x4b\x52\x83\x60\xc1\xf4\x03\xf6\x16\xf5\x1c\xf0\x96\x4f\x8d\xb6\xbe\x90\x17\xad\x7c\x89\x1e\x7b\xf9\x08\xbe\x3b\x58\x68\xf9\x0d\x02\x4d\x1c\x28\x10\x55\x18\x1e\x34\x0a\xe0\x5c\x58\x47\x23\x27\x0c\x8e\xd9\x27\x72\x87\x7a\xaf\x9f\x2a\x30\x58\x34\x74\x80\xd7\x83\x30\x61\x26\x60\xcc\x47\x21\x1a\x7f\x7d\x64\xbe\xc5\x31\x49\x56\x7e\x97\x32\x66\x01\x7a\x34\x9d\xb0\x96\xa6\xda\x7e\xbf\xdf\x05\xf3\xe5\xff\x83\x5f\xf6\xfe\x22\xd2\xf8\x3b\x94\x4e\x3f\x8e\x9f\x01\x7c\xb9\x44\x5d\x50\x0a\x00\x00")
+
+func queriesHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_queriesHtml,
+		"queries.html",
+	)
+}
+
+func queriesHtml() (*asset, error) {
+	bytes, err := queriesHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "queries.html", size: 2640, mode: os.FileMode(420), modTime: time.Unix(1792208206, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _select_projectHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xad\x54\x4d\x6f\xdb\x30\x0c\xbd\xe7\x57\x68\x3e\x6d\x07\x5b\xcd\x36\x60\x40\xe1\xe6\xb0\x75\x03\x0a\x0c\x5b\x87\xf5\xd2\x23\x6d\xd1\xb1\x12\x7d\x78\x92\x9c\xcd\x30\xf2\xdf\x47\xf9\x23\x73\x9a\x1d\x7a\xe8\x21\x60\xa4\x47\x3e\x3e\x52\xa4\xfb\x5e\x60\x25\x0d\xb2\xe4\x56\xfa\x46\x41\x77\xef\xec\x0e\xcb\x90\x1c\x8f\x7d\x9f\x7d\x71\x12\x8d\x50\xdd\x37\xd0\x18\x2f\x64\xc5\xc8\x35\xbb\x13\xec\x09\xc4\x5e\x93\xf7\x9d\x38\x1e\xdf\xf4\x3d\x5d\x47\xdf\xc1\xac\xf2\x57\xb7\xdf\x3f\x3d\x3c\xde\x7f\x66\x75\xd0\x6a\xb3\xca\x67\x83\x20\xc8\x28\x69\xf6\xcc\xa1\xba\x49\x7c\xe8\x14\xfa\x1a\x31\x24\xac\x76\x58\xdd\x24\x75\x08\x8d\xbf\xe6\xbc\x14\x66\xe7\xb3\x52\xd9\x56\x54\x0a\x1c\x66\xa5\xd5\x1c\x76\xf0\x87\x2b\x59\x78\x5e\xb4\x4a\x03\xbf\xca\xde\x66\xef\x78\xe9\xa7\x73\xa6\xa5\xc9\xe8\x94\xbc\x4c\x8e\xca\x9a\x90\xc2\x6f\xf4\x56\x23\x7f\x9f\x7d\xc8\xae\x86\x54\xcb\xeb\x65\xc6\x20\x83\xc2\xcd\x47\xb9\xfd\xd1\xa2\xeb\xd8\x83\xb5\xca\x5f\xb3\x9f\xa8\xa8\xb1\x0c\x58\x33\xb6\x38\xe7\xa3\xdf\x2a\xe7\x53\x37\x0a\x2b\x3a\x32\x9e\x40\x69\x0d\x2b\x15\x78\x4f\x1a\xd1\x59\x26\x7d\xda\x38\xa9\xc1\x75\x94\x80\xb1\x5c\xc8\xc3\x12\x4f\x63\xe8\x80\x9c\x63\x25\x29\x04\x7a\x5e\x37\x61\x84\xd6\xeb\x19\x1c\xd2\x47\xe6\x75\xf2\x44\x2c\x29\x5a\x4f\x64\x9c\xd8\x86\x8c\xe3\x9f\x9c\x4f\xea\x36\xab\x0b\xa1\xd3\xf1\x42\x60\x69\x55\xab\x8d\x8f\x99\xb4\x2d\xa4\xc2\xff\x0a\x8d\x3e\xd1\xa5\x06\x55\x45\x6b\xab\xca\x63\x48\xad\xc1\xf4\x57\x0b\x2e\x2c\x6b\x30\x70\x0a\x6c\xc0\xa0\x3a\x21\x84\x35\x67\x48\x1a\x5b\x2b\xcd\x36\xd9\x5c\xb6\xbf\xa1\x1a\xe6\xb0\xbe\x77\x60\xb6\x34\xdb\xd3\xfc\x7b\x9a\xdd\x13\x25\x9c\x53\x16\xca\x96\xfb\x28\x11\xa8\xde\x03\xce\xb3\xc4\x27\x62\xcf\xa7\x5d\x58\xa8\x22\x12\x4f\xc1\xe7\x3c\xb2\x8c\xcd\xca\xe5\x7c\x5b\x01\xab\x20\x15\x10\xa0\x00\x4f\x4d\xca\xb9\xa4\x5f\x8c\x5b\x12\xf5\x7d\x40\x4d\x9b\x1a\x2e\x77\x96\x65\x4b\xd5\x1c\x36\x8b\xf2\xc6\x75\x9c\x1b\xc8\xa9\x83\xa7\xea\xf3\xca\x3a\xcd\x34\x86\xda\x0a\xd2\x66\x3d\x31\xc1\xf0\x94\x54\x94\xb2\x5b\xdb\x86\x65\x83\x8b\x36\x84\x7f\x8f\x3e\x9e\x12\x16\xba\x06\x69\x04\xda\x42\x4b\xf2\xfe\x6a\xb7\x8c\xc2\x18\x18\x41\xab\x77\xb0\x7b\x24\xc6\x12\x3d\x4d\xd6\x18\x70\x7a\x4a\x1e\x93\x3f\x63\xd6\xf8\xb4\x1c\x7c\xf8\x80\xfc\x05\xd6\x8c\xce\x7f\xb7\x04\x00\x00")
 
 func select_projectHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -146,7 +252,27 @@ func select_projectHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "select_project.html", size: 1068, mode: os.FileMode(420), modTime: time.Unix(1479576707, 0)}
+	info := bindataFileInfo{name: "select_project.html", size: 1207, mode: os.FileMode(420), modTime: time.Unix(1792205887, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _tableHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xad\x57\x59\x6f\xe3\x36\x10\x7e\xcf\xaf\x98\x0a\xbb\x40\xfb\x10\x29\x49\x17\x28\xe0\x28\x2a\x36\xc7\x62\x03\x6c\x9a\x34\x71\x0b\xf4\x91\x96\x68\x8b\x59\x8a\x54\x48\x3a\xb6\xa1\xfa\xbf\x77\x48\xdd\x8e\x9c\x95\x81\x3e\x49\xe4\x1c\xdf\xcc\x70\x0e\x32\xfc\xe9\xfa\xfe\x6a\xfa\xcf\xc3\x0d\xa4\x26\xe3\xd1\x51\x58\x7f\x28\x49\xf0\xc3\x99\xf8\x0e\x8a\xf2\x0b\x4f\x9b\x0d\xa7\x3a\xa5\xd4\x78\x90\x2a\x3a\xbf\xf0\x52\x63\x72\x3d\x09\x82\x38\x11\xcf\xda\x8f\xb9\x5c\x26\x73\x4e\x14\xf5\x63\x99\x05\xe4\x99\xac\x03\xce\x66\x3a\x98\x2d\x79\x46\x82\x13\xff\xcc\xff\x35\x88\x75\xb5\xf6\x33\x26\x7c\x5c\x79\xff\x0f\xc6\x5c\x0a\x73\x4c\x56\x54\xcb\x8c\x06\x9f\xfc\xdf\xfc\x13\x07\xd5\xdd\xee\x22\x1a\x66\x38\x8d\x2e\xd9\xe2\xcf\x25\x55\x1b\x98\x4a\xc9\xf5\x04\x8a\xc2\x7f\x50\xf2\x99\xc6\xe6\xf6\x7a\xbb\x9d\xe0\xd2\x7e\xc3\xa0\xe4\x3e\x0a\x83\x2a\x26\x33\x99\x6c\xf0\xa3\x91\x91\x49\x01\x31\x27\x5a\xa3\xa5\x54\x49\x60\xfa\x38\x57\x2c\x23\x6a\x83\x30\x00\x61\xc2\x5e\xbb\xf4\x63\x2b\xea\x28\x7d\x5a\x8c\x76\x12\x26\xa8\xaa\x68\x48\x4d\x4f\x6b\xa2\x83\xb7\x9a\x4f\xbd\xd1\x26\xa7\xa7\x15\x48\x80\x28\xce\x92\xf2\x27\x0c\x2a\xab\xa3\xa3\x37\x0e\x54\x4b\x2f\xda\x6f\x59\x9f\xc2\x97\x99\xd0\x83\xde\x58\x0a\x58\x51\x2a\x8c\xb5\x5c\x10\xa5\xe4\xaa\x75\x2e\x8f\x42\x52\x1d\x70\x90\x97\xf6\xeb\xa0\xef\x0b\xfa\x4a\xe2\xef\x60\xe4\x8e\x8f\x61\x40\x22\xf8\xb7\x52\x84\xaa\xc8\x4e\xa2\xcc\xd8\xe2\xc5\x46\xa8\xcc\x15\x7f\x21\xe5\x82\x97\xd9\x62\xc8\x8c\xd3\x60\x38\x62\xbf\x23\xf1\x22\xa1\xe8\x2a\x47\x8f\xee\x73\x2a\x80\x09\xa8\xa3\x6d\x31\xc3\x20\xc7\x98\x55\xa0\x4e\x55\x73\x3e\x76\xd1\xf8\x66\xa9\x65\x82\x00\xb4\x3b\x2a\x0a\x4d\x1a\x4d\x37\x39\xc5\x74\x4a\x71\x91\x80\xcb\x75\x94\xa6\x6b\x4c\x51\xce\x16\x62\x02\x8a\x2d\x52\x73\xee\x45\x68\x94\x65\x75\xb9\x97\x20\x32\x8a\x77\xb4\x15\x05\x9b\x83\x7f\x49\x34\x9d\x5a\xe4\xed\x76\x00\xc9\xf1\xd0\x17\x70\x7a\xc0\x7b\xfa\xe3\xf3\xc3\xd3\xd7\xfb\xa9\xb7\xdd\x3e\x09\x92\xeb\x54\x1a\x90\xf3\xa2\xa0\x5c\xa3\xfc\x15\x97\x82\x96\x6b\x91\x38\xd0\x31\x16\x76\x0c\xd8\x63\xa6\xd3\x36\x60\xdc\x95\xd4\x66\x0c\xc8\x87\xa2\xc0\x62\x12\x66\x0e\xde\x47\xff\x6c\xee\x81\x7f\x2d\x39\x16\xbf\x7e\xa0\xea\x0e\x93\x2b\xdd\x6e\x83\xcc\x7e\x07\xf1\x6b\xb4\xcb\x8d\xa1\x7a\xa4\x4f\x5f\x97\x19\x11\x4e\x60\x8f\x53\xb5\xd2\xcf\x58\x2a\xaf\x63\xcf\xd2\x69\x2d\x25\xc6\xe8\xfe\x26\xc5\xe2\x78\x4a\x55\x76\x88\x7a\x2b\x64\x65\x2a\x00\xf8\x19\x09\xf5\x1e\x86\x2b\xc6\x42\xdc\x6e\x3f\xfe\xf2\x2e\xf0\xa3\x5c\x8d\x0d\x94\x65\xfd\xa1\x1b\x31\xb1\xfd\x64\xa4\xc6\x9a\xfd\x07\x5a\x1f\x88\xc2\x7e\x88\x7c\x4c\x2c\xc6\x69\xb6\xc5\xd2\x48\x95\x65\x65\x7b\x40\x7f\x07\x66\x1b\xd8\x61\xfd\xc2\x28\x4f\xfa\xbc\xcd\x56\x59\x37\x68\x02\xd5\xae\x83\x1a\x96\xd1\x2a\xdf\x6b\xa2\xc0\x9a\x6a\x0b\x6a\x5f\x15\xef\x98\xf1\x9e\xc3\x70\xb3\xce\x99\x3a\x24\xa4\x2e\x31\x1a\xf9\x56\xfc\xf0\x82\xe5\x4b\x6d\xa8\x1a\x1d\x72\x45\x30\x32\xe0\xb7\x62\x2e\x70\x36\x61\x74\x4e\x44\xdb\x37\x17\xce\x4c\x6b\x8e\xdd\x8f\x60\x5c\xec\x70\xd5\x6d\xb0\x61\xd9\xd8\x9b\xe6\x5c\x06\xf6\x6f\x46\x57\xae\x77\x37\x0e\xe1\x44\x8d\xaa\x6e\x5e\x4f\x47\x3b\x89\x14\xb5\x36\x74\xd8\xb1\xd1\xe3\x5e\xa3\xac\x8c\x49\x4f\xf7\x93\x5c\x62\x41\xfd\xf5\x78\xab\x7b\xca\x6f\xd6\xe8\xac\x20\x1c\xae\x89\x21\x7d\x90\xe8\x8b\x54\x19\x31\x6e\x5e\x97\xd2\xe5\x86\x43\x6b\xd8\x96\xbc\x75\xb2\x09\xe2\x00\x18\xb2\x72\x16\x85\xb1\x4c\x68\x1d\x3f\xf7\x1f\xe2\x5d\xa8\xab\xa1\x7b\x9c\x61\xd0\x6a\x1f\x74\xaa\xc9\x93\xbe\x53\xed\xf6\x8e\x47\x45\xc1\x71\x48\xf6\xc4\x20\x6f\x16\xe7\xd0\xed\x3f\x5d\x9e\x19\xe3\x9c\x26\x40\x0c\x98\x94\x02\xb7\xbd\x0e\xc3\x96\x01\xa6\x26\xf5\x0f\x19\xb2\xe5\x65\xac\x9f\xac\x9d\xa5\x63\x89\x5c\xbe\x8e\xdb\x7d\x2f\xa5\xdb\x19\x72\x88\x54\xd3\x4f\xdf\x18\xf0\x8d\x68\x03\x77\x32\x61\x73\x46\x93\x61\x96\x27\x23\x15\x59\xd0\x61\x62\xdb\x14\x6e\xaf\x77\x39\xde\x14\x4b\x19\xa8\x77\xae\x27\x45\xf1\xc1\x48\x43\xb8\xf3\x12\x26\x17\x78\xb9\x28\xa7\x48\x9f\x27\x23\xeb\x96\xe3\x8e\xac\x1b\x23\x86\xb8\xab\xec\x1d\xc8\xaa\x3d\x67\xd5\xb4\x94\x57\x8a\x22\x31\xe1\x75\x34\x33\x96\x24\x9c\x9e\xc3\x8a\x25\x26\x9d\xc0\xe9\xc9\x49\xbe\x3e\xf7\xfa\xe2\xae\x90\xe5\x42\x51\xad\xeb\x84\x69\xd6\x78\xfd\xd4\x19\xe1\xbc\x4c\xf3\x3a\x27\x31\x13\x91\xc0\xc4\x5c\x56\xd5\xe0\xc1\x2b\xe1\x4b\xc4\xb7\x0d\xbf\x1c\x98\xd0\xb8\x6c\xc9\xf8\x7f\xe1\x21\xba\x57\x1b\xba\x63\xd0\xb0\x9c\xed\x26\xa5\x21\x3b\x0e\xdb\xbe\xb6\x2f\x04\x95\xe6\x33\xab\x18\xf6\x34\xf7\x06\xab\x3d\x3a\x1c\xf0\xef\xaa\x1d\x7b\xe9\x39\x50\xbe\x73\x17\xd8\x95\x74\x83\x1d\x73\xbd\x4e\xf5\xfd\x6c\xfd\xb3\x69\xda\x42\x3d\x11\x88\xbb\x3d\x75\x67\xc2\x10\x54\xf5\x5c\x4b\xf6\x97\xc3\xdb\x29\xb7\x6f\x9a\x34\xdc\x88\x0f\xfb\x47\x35\x76\xc2\xb6\x18\x75\x59\xb3\xb0\x22\x1a\x04\x5e\xb1\xb9\x24\x09\x4d\x7c\x78\xc4\x0a\xc4\x29\xd8\xe9\x8f\xf8\xdc\x7d\x59\x32\x4c\x0b\xc8\xd1\x4b\xa6\xb5\xbb\x45\x48\x50\x4b\x01\xf6\xf9\xc2\x90\x82\x0f\x10\x93\x32\xe4\x28\x9f\x2b\x7e\x67\x54\x74\x9d\x78\xfb\xc4\xb3\x9f\xde\x43\x2f\xa8\x5e\xac\x41\xf9\xb6\xff\x0f\xd1\x8c\x77\x0a\xf3\x0f\x00\x00")
+
+func tableHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_tableHtml,
+		"table.html",
+	)
+}
+
+func tableHtml() (*asset, error) {
+	bytes, err := tableHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "table.html", size: 4083, mode: os.FileMode(420), modTime: time.Unix(1792203851, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -203,10 +329,16 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
+	"cold.html": coldHtml,
+	"diff.html": diffHtml,
 	"index.html": indexHtml,
+	"inventory.html": inventoryHtml,
+	"labels.html": labelsHtml,
 	"loading.html": loadingHtml,
 	"project.html": projectHtml,
+	"queries.html": queriesHtml,
 	"select_project.html": select_projectHtml,
+	"table.html": tableHtml,
 }
 
 // AssetDir returns the file names below a certain
@@ -249,10 +381,16 @@ type bintree struct {
 	Children map[string]*bintree
 }
 var _bintree = &bintree{nil, map[string]*bintree{
+	"cold.html": &bintree{coldHtml, map[string]*bintree{}},
+	"diff.html": &bintree{diffHtml, map[string]*bintree{}},
 	"index.html": &bintree{indexHtml, map[string]*bintree{}},
+	"inventory.html": &bintree{inventoryHtml, map[string]*bintree{}},
+	"labels.html": &bintree{labelsHtml, map[string]*bintree{}},
 	"loading.html": &bintree{loadingHtml, map[string]*bintree{}},
 	"project.html": &bintree{projectHtml, map[string]*bintree{}},
+	"queries.html": &bintree{queriesHtml, map[string]*bintree{}},
 	"select_project.html": &bintree{select_projectHtml, map[string]*bintree{}},
+	"table.html": &bintree{tableHtml, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -2,19 +2,19 @@
 package templates
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
 	"math"
+	"strings"
+	"time"
 
 	"google.golang.org/api/bigquery/v2"
 
 	"strconv"
 )
 
-// https://cloud.google.com/bigquery/pricing#storage
-const dollarsPerBytePerMonth = 0.02 / 1024.0 / 1024.0 / 1024.0
-
 // Determine the lowest x such that x/divisor rounded to 1 decimal place == 1.0
 func leastRoundedOne(divisor int64) int64 {
 	roundUpValue := float64(divisor) * 0.95
@@ -62,6 +62,12 @@ var index = MustAsset("index.html")
 var selectProject = mustEmbeddedTemplate("select_project.html")
 var loading = mustEmbeddedTemplate("loading.html")
 var project = mustEmbeddedTemplate("project.html")
+var diff = mustEmbeddedTemplate("diff.html")
+var labels = mustEmbeddedTemplate("labels.html")
+var table = mustEmbeddedTemplate("table.html")
+var inventory = mustEmbeddedTemplate("inventory.html")
+var queries = mustEmbeddedTemplate("queries.html")
+var coldStorage = mustEmbeddedTemplate("cold.html")
 
 func Index(w io.Writer) error {
 	// currently not a template
@@ -85,9 +91,21 @@ func Loading(w io.Writer, percent int, message string) error {
 	return loading.Execute(w, &loadingData{percent, message})
 }
 
+// Costs in this package are computed by the caller with the pricing package.
 type StorageUsage struct {
 	Bytes int64
 	ID    string
+	// Included in Bytes.
+	LongTermBytes   int64
+	Location        string
+	DollarsPerMonth float64
+
+	// Datasets only: labels formatted as key:value, and the default table expiration.
+	Labels                   []string
+	DefaultTableExpirationMs int64
+
+	// Tables only: the table type, e.g. TABLE or MATERIALIZED_VIEW.
+	Type string
 }
 
 func (s *StorageUsage) PercentValue(total int64) float64 {
@@ -99,30 +117,527 @@ func (s *StorageUsage) Percent(total int64) string {
 	return strconv.FormatFloat(s.PercentValue(total), 'f', 0, 64)
 }
 
-func (s *StorageUsage) DollarsPerMonth() float64 {
-	return float64(s.Bytes) * dollarsPerBytePerMonth
+func (s *StorageUsage) HumanBytes() string {
+	return HumanBytes(s.Bytes)
+}
+
+// Returns the default table expiration, e.g. "30 days", or "never".
+func (s *StorageUsage) HumanExpiration() string {
+	return humanExpiration(s.DefaultTableExpirationMs)
 }
 
-func (s *StorageUsage) HumanBytes() string {
+func humanExpiration(expirationMs int64) string {
+	if expirationMs <= 0 {
+		return "never"
+	}
+	expiration := time.Duration(expirationMs) * time.Millisecond
+	if expiration >= 24*time.Hour && expiration%(24*time.Hour) == 0 {
+		days := int64(expiration / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return strconv.FormatInt(days, 10) + " days"
+	}
+	return expiration.String()
+}
+
+func (s *StorageUsage) HumanActiveBytes() string {
+	return HumanBytes(s.Bytes - s.LongTermBytes)
+}
+
+func (s *StorageUsage) HumanLongTermBytes() string {
+	return HumanBytes(s.LongTermBytes)
+}
+
+// Storage for one snapshot of a project.
+type SnapshotUsage struct {
+	ID     int64
+	TimeMs int64
+	Bytes  int64
+	// Included in Bytes.
+	LongTermBytes   int64
+	DollarsPerMonth float64
+}
+
+func (s *SnapshotUsage) Time() string {
+	return time.Unix(0, s.TimeMs*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04 MST")
+}
+
+func (s *SnapshotUsage) HumanBytes() string {
 	return HumanBytes(s.Bytes)
 }
 
+// Storage for a dataset over time.
+type DatasetHistory struct {
+	ID string
+	// Bytes in each snapshot in ProjectData.History
+	Bytes []int64
+}
+
+func (d *DatasetHistory) ChartPoints() string {
+	return chartPoints(d.Bytes, sparklineWidth, sparklineHeight)
+}
+
+// Returns the change from the first to the last snapshot, e.g. "+1.0 GiB".
+func (d *DatasetHistory) HumanChange() string {
+	if len(d.Bytes) == 0 {
+		return HumanBytes(0)
+	}
+	return humanChange(d.Bytes[len(d.Bytes)-1] - d.Bytes[0])
+}
+
+// Formats a change in bytes with a sign, e.g. "+1.0 GiB" or "-3 B".
+func humanChange(change int64) string {
+	if change < 0 {
+		return "-" + HumanBytes(-change)
+	}
+	return "+" + HumanBytes(change)
+}
+
+// chart sizes in pixels: must match the svg elements in project.html
+const chartWidth = 600
+const chartHeight = 150
+const sparklineWidth = 100
+const sparklineHeight = 20
+
+// Returns the points attribute for an svg polyline that plots values in a width x height box.
+func chartPoints(values []int64, width int, height int) string {
+	var max int64
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+
+	points := make([]string, len(values))
+	for i, value := range values {
+		x := 0.0
+		if len(values) > 1 {
+			x = float64(i) * float64(width) / float64(len(values)-1)
+		}
+		y := float64(height)
+		if max > 0 {
+			y -= float64(value) * float64(height) / float64(max)
+		}
+		points[i] = strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+	}
+	return strings.Join(points, " ")
+}
+
 type ProjectData struct {
-	ID             string
-	FriendlyName   string
-	TotalBytes     int64
+	ID           string
+	FriendlyName string
+	TotalBytes   int64
+	// Included in TotalBytes.
+	TotalLongTermBytes int64
+	// Dollars per month.
+	ActiveCost     float64
+	LongTermCost   float64
 	DatasetStorage []*StorageUsage
 	TableStorage   []*StorageUsage
+
+	// The snapshot shown on the page.
+	SnapshotID int64
+	// All complete snapshots, oldest first.
+	History        []*SnapshotUsage
+	DatasetHistory []*DatasetHistory
+	// Set if the last refresh failed.
+	LoadingError string
+	// nil if query jobs have not been loaded.
+	QueryCosts *QueryCosts
+	// Not dismissed by the user; largest savings first.
+	Recommendations []*Recommendation
+}
+
+// Total estimated savings of all recommendations.
+func (p *ProjectData) RecommendationSavings() float64 {
+	total := 0.0
+	for _, recommendation := range p.Recommendations {
+		total += recommendation.DollarsPerMonth
+	}
+	return total
+}
+
+func (p *ProjectData) HistoryChartPoints() string {
+	bytes := make([]int64, len(p.History))
+	for i, snapshot := range p.History {
+		bytes[i] = snapshot.Bytes
+	}
+	return chartPoints(bytes, chartWidth, chartHeight)
 }
 
 func (p *ProjectData) TotalCost() float64 {
-	return float64(p.TotalBytes) * dollarsPerBytePerMonth
+	return p.ActiveCost + p.LongTermCost
 }
 
 func (p *ProjectData) HumanBytes() string {
 	return HumanBytes(p.TotalBytes)
 }
 
+func (p *ProjectData) HumanActiveBytes() string {
+	return HumanBytes(p.TotalBytes - p.TotalLongTermBytes)
+}
+
+func (p *ProjectData) HumanLongTermBytes() string {
+	return HumanBytes(p.TotalLongTermBytes)
+}
+
 func Project(w io.Writer, data *ProjectData) error {
 	return project.Execute(w, data)
 }
+
+// Change in storage between two snapshots. For created tables the old values are zero; for
+// deleted tables the new values are zero.
+type StorageChange struct {
+	OldBytes int64
+	NewBytes int64
+	OldRows  int64
+	NewRows  int64
+	// Included in OldBytes and NewBytes.
+	OldLongTermBytes   int64
+	NewLongTermBytes   int64
+	OldDollarsPerMonth float64
+	NewDollarsPerMonth float64
+}
+
+func (c StorageChange) Bytes() int64 {
+	return c.NewBytes - c.OldBytes
+}
+
+func (c StorageChange) Rows() int64 {
+	return c.NewRows - c.OldRows
+}
+
+func (c StorageChange) DollarsPerMonth() float64 {
+	return c.NewDollarsPerMonth - c.OldDollarsPerMonth
+}
+
+func (c StorageChange) HumanBytes() string {
+	return humanChange(c.Bytes())
+}
+
+func (c StorageChange) HumanOldBytes() string {
+	return HumanBytes(c.OldBytes)
+}
+
+func (c StorageChange) HumanNewBytes() string {
+	return HumanBytes(c.NewBytes)
+}
+
+// Includes the computed changes, so API clients don't need to know the pricing.
+func (c StorageChange) MarshalJSON() ([]byte, error) {
+	type fields StorageChange
+	return json.Marshal(struct {
+		fields
+		Bytes           int64
+		Rows            int64
+		DollarsPerMonth float64
+	}{fields(c), c.Bytes(), c.Rows(), c.DollarsPerMonth()})
+}
+
+type TableDiff struct {
+	ID     string
+	Change StorageChange
+}
+
+type DatasetDiff struct {
+	ID     string
+	Change StorageChange
+	// Number of tables that appeared and disappeared.
+	Created int
+	Deleted int
+}
+
+// Compares two snapshots of a project.
+type SnapshotDiff struct {
+	ProjectID string
+	From      *SnapshotUsage
+	To        *SnapshotUsage
+	Total     StorageChange
+
+	// All datasets in either snapshot, largest change first.
+	Datasets []*DatasetDiff
+	// Total number of tables created and deleted. The lists contain the largest tables.
+	CreatedCount int
+	DeletedCount int
+	Created      []*TableDiff
+	Deleted      []*TableDiff
+	// Tables in both snapshots with the largest changes.
+	Changed []*TableDiff
+
+	// All complete snapshots, oldest first, to choose the snapshots to compare.
+	Snapshots []*SnapshotUsage `json:"-"`
+}
+
+func Diff(w io.Writer, data *SnapshotDiff) error {
+	return diff.Execute(w, data)
+}
+
+// Storage of tables with one value of a label.
+type LabelUsage struct {
+	// The tables do not have the label.
+	Unlabeled       bool
+	Value           string
+	Tables          int64
+	Bytes           int64
+	LongTermBytes   int64
+	DollarsPerMonth float64
+}
+
+func (l *LabelUsage) Percent(total int64) string {
+	return strconv.FormatFloat(float64(l.Bytes)*100.0/float64(total), 'f', 0, 64)
+}
+
+func (l *LabelUsage) HumanBytes() string {
+	return HumanBytes(l.Bytes)
+}
+
+// Storage and cost by the values of one table label.
+type LabelReport struct {
+	ProjectID string
+	// All table label keys in the snapshot.
+	Keys []string
+	// The label in this report; empty if there are no labels.
+	Key        string
+	TotalBytes int64
+	// Largest cost first.
+	Values []*LabelUsage
+}
+
+func Labels(w io.Writer, data *LabelReport) error {
+	return labels.Execute(w, data)
+}
+
+// Storage of one partition of a table.
+type PartitionUsage struct {
+	ID                 string
+	Bytes              int64
+	Rows               int64
+	LastModifiedTimeMs int64
+	LongTerm           bool
+}
+
+func (p *PartitionUsage) Percent(total int64) string {
+	return strconv.FormatFloat(float64(p.Bytes)*100.0/float64(total), 'f', 0, 64)
+}
+
+func (p *PartitionUsage) HumanBytes() string {
+	return HumanBytes(p.Bytes)
+}
+
+func (p *PartitionUsage) LastModified() string {
+	return formatDateMs(p.LastModifiedTimeMs)
+}
+
+// Storage of one table and its partitions.
+type TableReport struct {
+	ProjectID string
+	// dataset.table
+	ID       string
+	Location string
+	Rows     int64
+	Bytes    int64
+	// Included in Bytes.
+	LongTermBytes   int64
+	DollarsPerMonth float64
+
+	// Empty if the table is not partitioned.
+	PartitionType         string
+	PartitionField        string
+	PartitionExpirationMs int64
+	ClusteringFields      []string
+
+	// TABLE, VIEW, MATERIALIZED_VIEW, EXTERNAL or SNAPSHOT.
+	Type string
+	// Views and materialized views only; may be truncated.
+	ViewQuery string
+	// External tables only.
+	SourceFormat string
+	SourceURIs   []string
+	// Snapshots and clones: project:dataset.table
+	BaseTable string
+
+	// Sorted by partition ID; empty if partitions were not scraped.
+	Partitions []*PartitionUsage
+	// The largest partition, for scaling the distribution.
+	MaxPartitionBytes int64
+	// Partitions billed at the long-term rate.
+	LongTermPartitions int
+}
+
+func (t *TableReport) HumanBytes() string {
+	return HumanBytes(t.Bytes)
+}
+
+func (t *TableReport) HumanActiveBytes() string {
+	return HumanBytes(t.Bytes - t.LongTermBytes)
+}
+
+func (t *TableReport) HumanLongTermBytes() string {
+	return HumanBytes(t.LongTermBytes)
+}
+
+// Returns the percentage of bytes billed at the long-term rate.
+func (t *TableReport) LongTermPercent() string {
+	if t.Bytes == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(t.LongTermBytes)*100.0/float64(t.Bytes), 'f', 0, 64)
+}
+
+func (t *TableReport) HumanPartitionExpiration() string {
+	return humanExpiration(t.PartitionExpirationMs)
+}
+
+func Table(w io.Writer, data *TableReport) error {
+	return table.Execute(w, data)
+}
+
+// Number and storage of tables of one type.
+type TypeUsage struct {
+	Type            string
+	Tables          int64
+	Bytes           int64
+	DollarsPerMonth float64
+}
+
+func (t *TypeUsage) HumanBytes() string {
+	return HumanBytes(t.Bytes)
+}
+
+// Tables, views and external tables in a project, by type.
+type InventoryReport struct {
+	ProjectID string
+	// Sorted by type.
+	Types []*TypeUsage
+	// The type listed in Tables.
+	Type string
+	// Largest first.
+	Tables []*TableReport
+	// Only the largest tables of Type are listed.
+	Truncated bool
+}
+
+func Inventory(w io.Writer, data *InventoryReport) error {
+	return inventory.Execute(w, data)
+}
+
+// Cost of queries grouped by one attribute.
+type QuerySpend struct {
+	// e.g. the user email or table
+	Name        string
+	Jobs        int64
+	BytesBilled int64
+	SlotMs      int64
+	Dollars     float64
+	// Queries grouped by fingerprint only: one of the queries.
+	Example string
+	// Queries page only: Example with its literals replaced.
+	Normalized string
+}
+
+func (q *QuerySpend) HumanBytesBilled() string {
+	return HumanBytes(q.BytesBilled)
+}
+
+func (q *QuerySpend) SlotHours() string {
+	return strconv.FormatFloat(float64(q.SlotMs)/float64(time.Hour/time.Millisecond), 'f', 1, 64)
+}
+
+// The most expensive queries in a project over Days.
+type QueryCosts struct {
+	Days  int
+	Total *QuerySpend
+	// Most expensive first.
+	Users   []*QuerySpend
+	Queries []*QuerySpend
+	Tables  []*QuerySpend
+	// Loading stopped at the limit on jobs, so older queries are missing.
+	Truncated bool
+}
+
+// Queries in a project over Days, grouped by their normalized SQL.
+type QueryFingerprints struct {
+	ProjectID string
+	Days      int
+	// bytes, jobs or cost
+	Sort string
+	// false if query jobs have not been loaded.
+	Loaded bool
+	// Loading stopped at the limit on jobs, so older queries are missing.
+	Truncated bool
+	Queries   []*QuerySpend
+}
+
+func Queries(w io.Writer, data *QueryFingerprints) error {
+	return queries.Execute(w, data)
+}
+
+// Formats ms since the epoch as a UTC date.
+func formatDateMs(ms int64) string {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format("2006-01-02")
+}
+
+// A table that was not read or written recently.
+type ColdTable struct {
+	Table              *TableReport
+	LastModifiedTimeMs int64
+	// 0 if no loaded query read the table.
+	LastReadTimeMs int64
+}
+
+func (c *ColdTable) LastModified() string {
+	return formatDateMs(c.LastModifiedTimeMs)
+}
+
+func (c *ColdTable) LastRead() string {
+	if c.LastReadTimeMs == 0 {
+		return "never"
+	}
+	return formatDateMs(c.LastReadTimeMs)
+}
+
+// Tables in a project not read or written since CutoffMs, which is Days before the snapshot.
+type ColdStorageReport struct {
+	ProjectID string
+	Days      int
+	CutoffMs  int64
+	// false if query jobs have not been loaded: reads are unknown.
+	Loaded bool
+	// Creation time of the oldest loaded query; 0 if there are none.
+	HistoryStartMs int64
+	// Most expensive first.
+	Tables    []*ColdTable
+	Truncated bool
+	// Totals for all tables, including those not listed.
+	Count           int
+	Bytes           int64
+	DollarsPerMonth float64
+}
+
+func (c *ColdStorageReport) HumanBytes() string {
+	return HumanBytes(c.Bytes)
+}
+
+func (c *ColdStorageReport) HistoryStart() string {
+	return formatDateMs(c.HistoryStartMs)
+}
+
+// Returns true if the loaded queries do not cover all of Days, so some tables may have been read.
+func (c *ColdStorageReport) PartialHistory() bool {
+	return c.HistoryStartMs == 0 || c.HistoryStartMs > c.CutoffMs
+}
+
+func ColdStorage(w io.Writer, data *ColdStorageReport) error {
+	return coldStorage.Execute(w, data)
+}
+
+// A way to reduce costs: see recommend.Finding.
+type Recommendation struct {
+	Rule string
+	// Short description of Rule.
+	Title           string
+	Resource        string
+	Message         string
+	DollarsPerMonth float64
+}
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 
 	bigquery "google.golang.org/api/bigquery/v2"
@@ -110,9 +111,10 @@ func TestLoading(t *testing.T) {
 func TestProject(t *testing.T) {
 	buf := &bytes.Buffer{}
 	data := &ProjectData{
-		"id", "name", 12345,
-		[]*StorageUsage{{12345, "dataset"}},
-		[]*StorageUsage{{5000, "table"}},
+		ID: "id", FriendlyName: "name", TotalBytes: 12345,
+		DatasetStorage: []*StorageUsage{{Bytes: 12345, ID: "dataset", Location: "EU"}},
+		TableStorage: []*StorageUsage{
+			{Bytes: 5000, ID: "table", LongTermBytes: 1000, DollarsPerMonth: 1.234}},
 	}
 	err := Project(buf, data)
 	if err != nil {
@@ -129,4 +131,364 @@ func TestProject(t *testing.T) {
 	if !strings.Contains(buf.String(), "4.9 KiB") {
 		t.Error(buf.String())
 	}
+	// active and long-term bytes
+	if !strings.Contains(buf.String(), "3.9 KiB") || !strings.Contains(buf.String(), "1.0 KiB") {
+		t.Error(buf.String())
+	}
+	if !strings.Contains(buf.String(), "$1.23") || !strings.Contains(buf.String(), ">EU<") {
+		t.Error(buf.String())
+	}
+}
+
+func TestProjectQueryCosts(t *testing.T) {
+	buf := &bytes.Buffer{}
+	data := &ProjectData{
+		ID: "id", FriendlyName: "name",
+		QueryCosts: &QueryCosts{
+			Days:  30,
+			Total: &QuerySpend{Jobs: 2, BytesBilled: 2048, SlotMs: 5400000, Dollars: 1.5},
+			Users: []*QuerySpend{{Name: "a@example.com", Jobs: 2, BytesBilled: 2048, Dollars: 1.5}},
+			Queries: []*QuerySpend{
+				{Name: "fingerprint", Jobs: 2, Dollars: 1.5, Example: "SELECT a < b FROM t"}},
+		},
+	}
+	err := Project(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Last 30 Days", "a@example.com", "$1.50", "2.0 KiB",
+		"1.5", "SELECT a &lt; b FROM t"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error(expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	data.QueryCosts = &QueryCosts{Days: 30, Total: &QuerySpend{}}
+	err = Project(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No queries were run") {
+		t.Error(buf.String())
+	}
+
+	// not loaded
+	buf.Reset()
+	data.QueryCosts = nil
+	err = Project(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Query Costs") ||
+		!strings.Contains(buf.String(), "bigquery.jobs.listAll") {
+		t.Error(buf.String())
+	}
+}
+
+func TestQueries(t *testing.T) {
+	buf := &bytes.Buffer{}
+	data := &QueryFingerprints{ProjectID: "p", Days: 30, Sort: "jobs", Loaded: true,
+		Queries: []*QuerySpend{{Name: "f", Jobs: 7, BytesBilled: 1024, Dollars: 0.25,
+			Example: "SELECT * FROM t WHERE x < 5", Normalized: "SELECT * FROM T WHERE X < ?"}},
+	}
+	err := Queries(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"<strong>runs</strong>", `<a href="?sort=bytes">`,
+		"1.0 KiB", "$0.25", "SELECT * FROM T WHERE X &lt; ?", "SELECT * FROM t WHERE x &lt; 5"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error(expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	data.Loaded = false
+	err = Queries(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "bigquery.jobs.listAll") {
+		t.Error(buf.String())
+	}
+}
+
+func TestHumanExpiration(t *testing.T) {
+	tests := []struct {
+		ms       int64
+		expected string
+	}{
+		{0, "never"},
+		{24 * 60 * 60 * 1000, "1 day"},
+		{90 * 24 * 60 * 60 * 1000, "90 days"},
+		{60 * 60 * 1000, "1h0m0s"},
+	}
+	for i, test := range tests {
+		usage := &StorageUsage{DefaultTableExpirationMs: test.ms}
+		if usage.HumanExpiration() != test.expected {
+			t.Errorf("%d: HumanExpiration(%d) = %#v ; expected %#v",
+				i, test.ms, usage.HumanExpiration(), test.expected)
+		}
+	}
+}
+
+func TestStorageCost(t *testing.T) {
+	data := &ProjectData{ActiveCost: 1.25, LongTermCost: 0.5}
+	if data.TotalCost() != 1.75 {
+		t.Error(data.TotalCost())
+	}
+	change := StorageChange{OldDollarsPerMonth: 1.5, NewDollarsPerMonth: 0.25}
+	if change.DollarsPerMonth() != -1.25 {
+		t.Error(change.DollarsPerMonth())
+	}
+}
+
+func TestChartPoints(t *testing.T) {
+	tests := []struct {
+		values []int64
+		points string
+	}{
+		{nil, ""},
+		{[]int64{0}, "0.0,20.0"},
+		{[]int64{5}, "0.0,0.0"},
+		{[]int64{0, 5, 10}, "0.0,20.0 50.0,10.0 100.0,0.0"},
+	}
+	for i, test := range tests {
+		points := chartPoints(test.values, 100, 20)
+		if points != test.points {
+			t.Errorf("%d: chartPoints(%v) = %#v ; expected %#v", i, test.values, points, test.points)
+		}
+	}
+}
+
+func TestProjectHistory(t *testing.T) {
+	buf := &bytes.Buffer{}
+	data := &ProjectData{
+		ID: "id", FriendlyName: "name", TotalBytes: 2048,
+		DatasetStorage: []*StorageUsage{{Bytes: 2048, ID: "dataset"}},
+		SnapshotID:     2,
+		History: []*SnapshotUsage{
+			{ID: 1, TimeMs: 1479590702000, Bytes: 1024},
+			{ID: 2, TimeMs: 1479677102000, Bytes: 2048},
+		},
+		DatasetHistory: []*DatasetHistory{{"dataset", []int64{1024, 2048}}},
+		LoadingError:   "some error",
+	}
+	err := Project(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`href="?snapshot=1">2016-11-19 21:25 UTC<`,
+		`<strong>2016-11-20 21:25 UTC</strong>`,
+		`points="0.0,75.0 600.0,0.0"`,
+		// html/template escapes +
+		"&#43;1.0 KiB",
+		"some error",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	buf := &bytes.Buffer{}
+	data := &SnapshotDiff{
+		ProjectID: "id",
+		From:      &SnapshotUsage{ID: 1, TimeMs: 1479590702000, Bytes: 2048},
+		To:        &SnapshotUsage{ID: 2, TimeMs: 1479677102000, Bytes: 1024},
+		Total:     StorageChange{OldBytes: 2048, NewBytes: 1024},
+		Datasets: []*DatasetDiff{
+			{ID: "dataset", Change: StorageChange{OldBytes: 2048, NewBytes: 1024}, Deleted: 1}},
+		DeletedCount: 1,
+		Deleted:      []*TableDiff{{"dataset.table", StorageChange{OldBytes: 1024, OldRows: 10}}},
+		Snapshots:    []*SnapshotUsage{{ID: 1, TimeMs: 1479590702000}, {ID: 2, TimeMs: 1479677102000}},
+	}
+	err := Diff(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<option value="2" selected>2016-11-20 21:25 UTC</option>`,
+		"-1.0 KiB",
+		">-10<",
+		"Deleted Tables (1)",
+		"dataset.table",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
+
+func TestStorageChangeJSON(t *testing.T) {
+	change := StorageChange{OldBytes: 10, NewBytes: 4, OldRows: 1, NewRows: 3,
+		NewLongTermBytes: 4, OldDollarsPerMonth: 0.5, NewDollarsPerMonth: 0.25}
+	out, err := json.Marshal(&TableDiff{"t", change})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"ID":"t","Change":{"OldBytes":10,"NewBytes":4,"OldRows":1,"NewRows":3,` +
+		`"OldLongTermBytes":0,"NewLongTermBytes":4,"OldDollarsPerMonth":0.5,` +
+		`"NewDollarsPerMonth":0.25,"Bytes":-6,"Rows":2,"DollarsPerMonth":-0.25}}`
+	if string(out) != expected {
+		t.Error(string(out))
+	}
+}
+
+func TestLabels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := Labels(buf, &LabelReport{ProjectID: "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No tables in this project have labels") {
+		t.Error(buf.String())
+	}
+
+	buf.Reset()
+	data := &LabelReport{ProjectID: "id", Keys: []string{"env", "team"}, Key: "team",
+		TotalBytes: 2048, Values: []*LabelUsage{
+			{Value: "ads", Tables: 3, Bytes: 1536, DollarsPerMonth: 1.5},
+			{Unlabeled: true, Tables: 1, Bytes: 512}}}
+	err = Labels(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`href="/projects/id/labels?key=env"`,
+		`value="75" max="100"`,
+		"$1.50",
+		"<em>unlabeled</em>",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := Table(buf, &TableReport{ProjectID: "p", ID: "d.t", Bytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<td style=\"text-align: right;\">none</td>") ||
+		strings.Contains(buf.String(), "Partitions") {
+		t.Error(buf.String())
+	}
+
+	buf.Reset()
+	data := &TableReport{ProjectID: "p", ID: "d.t", Bytes: 400, LongTermBytes: 100,
+		PartitionType: "DAY", PartitionExpirationMs: 30 * 24 * 60 * 60 * 1000,
+		ClusteringFields: []string{"customer"},
+		Partitions: []*PartitionUsage{
+			{ID: "20170101", Bytes: 100, LongTerm: true},
+			{ID: "20170102", Bytes: 300},
+		},
+		MaxPartitionBytes: 300, LongTermPartitions: 1}
+	err = Table(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"DAY by ingestion time",
+		"30 days",
+		`<span class="tag">customer</span>`,
+		"(25%)",
+		"2 partitions; 1 billed at the long-term rate",
+		`value="33" max="100"`,
+		">75%<",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
+
+func TestInventory(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := Inventory(buf, &InventoryReport{ProjectID: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "This project has no tables") {
+		t.Error(buf.String())
+	}
+
+	buf.Reset()
+	data := &InventoryReport{ProjectID: "p", Type: "VIEW",
+		Types: []*TypeUsage{{Type: "TABLE", Tables: 2, Bytes: 100}, {Type: "VIEW", Tables: 1}},
+		Tables: []*TableReport{{ProjectID: "p", ID: "d.v", Type: "VIEW",
+			ViewQuery: "SELECT 1 < 2"}},
+		Truncated: true}
+	err = Inventory(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<a href="/projects/p/inventory?type=TABLE">TABLE</a>`,
+		"<strong>VIEW</strong>",
+		"<code>SELECT 1 &lt; 2</code>",
+		"Showing the largest 1.",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
+
+func TestColdStorage(t *testing.T) {
+	buf := &bytes.Buffer{}
+	data := &ColdStorageReport{ProjectID: "p", Days: 90, CutoffMs: 1000, Loaded: true,
+		HistoryStartMs: 500, Count: 1, Bytes: 2048, DollarsPerMonth: 1.5,
+		Tables: []*ColdTable{{Table: &TableReport{ID: "d.t", Bytes: 2048, DollarsPerMonth: 1.5},
+			LastModifiedTimeMs: 1479590702000}},
+	}
+	err := ColdStorage(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"in 90 Days", "$1.50", "2.0 KiB", "2016-11-19", "never",
+		"table?id=d.t"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error(expected, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "may have been read") {
+		t.Error(buf.String())
+	}
+
+	buf.Reset()
+	data.HistoryStartMs = 1479590702000
+	err = ColdStorage(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "starts on 2016-11-19") {
+		t.Error(buf.String())
+	}
+}
+
+func TestProjectRecommendations(t *testing.T) {
+	buf := &bytes.Buffer{}
+	data := &ProjectData{
+		ID: "id", FriendlyName: "name",
+		Recommendations: []*Recommendation{
+			{Rule: "r", Title: "Do <this>", Resource: "d.t", Message: "message", DollarsPerMonth: 1.5},
+			{Rule: "r", Title: "Do that", Resource: "d.u", Message: "other", DollarsPerMonth: 0.25},
+		},
+	}
+	err := Project(buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"save an estimated $1.75/month", "Do &lt;this&gt;",
+		`action="/projects/id/dismiss"`, `name="resource" value="d.u"`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error(expected, buf.String())
+		}
+	}
 }
@@ -0,0 +1,243 @@
+// Package xlsx writes Excel workbooks one row at a time, so large spreadsheets can be written
+// without holding them in memory. It only supports text and number cells, without formatting.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Excel limits sheet names to 31 characters, and they cannot contain any of these.
+const maxSheetNameLength = 31
+const invalidSheetNameChars = `[]:*?/\`
+
+// Name of the sheet added to workbooks that have none, since Excel requires one.
+const defaultSheetName = "Sheet1"
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	// every other xml file is a worksheet, so sheets do not need to be listed
+	`<Default Extension="xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const sheetHeaderXML = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+const sheetFooterXML = `</sheetData></worksheet>`
+
+var errNoSheet = errors.New("xlsx: WriteRow called before AddSheet")
+
+// Writer writes a workbook as a zip file. Sheets are written in order: rows are added to the
+// last sheet added.
+type Writer struct {
+	zip        *zip.Writer
+	sheetNames []string
+	// the current sheet, or nil before AddSheet
+	sheet io.Writer
+	rows  int
+}
+
+// NewWriter returns a Writer that writes a workbook to w. Close must be called to finish it.
+func NewWriter(w io.Writer) (*Writer, error) {
+	writer := &Writer{zip: zip.NewWriter(w)}
+	err := writer.writeFile("[Content_Types].xml", contentTypesXML)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.writeFile("_rels/.rels", rootRelsXML)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) writeFile(name string, contents string) error {
+	f, err := w.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, contents)
+	return err
+}
+
+func (w *Writer) endSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(w.sheet, sheetFooterXML)
+	w.sheet = nil
+	return err
+}
+
+// Returns name without characters Excel does not permit, truncated to the maximum length and
+// made unique by adding a number.
+func (w *Writer) validSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalidSheetNameChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = defaultSheetName
+	}
+
+	unique := name
+	for i := 2; ; i++ {
+		unique = truncateRunes(unique, maxSheetNameLength)
+		used := false
+		for _, sheetName := range w.sheetNames {
+			// Excel compares names without case
+			if strings.EqualFold(sheetName, unique) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			return unique
+		}
+		suffix := fmt.Sprintf(" (%d)", i)
+		unique = truncateRunes(name, maxSheetNameLength-len(suffix)) + suffix
+	}
+}
+
+func truncateRunes(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes])
+}
+
+// AddSheet finishes the current sheet and starts a new one. Names that are not valid in Excel
+// are changed. Returns the name of the sheet.
+func (w *Writer) AddSheet(name string) (string, error) {
+	err := w.endSheet()
+	if err != nil {
+		return "", err
+	}
+	name = w.validSheetName(name)
+	w.sheetNames = append(w.sheetNames, name)
+	sheet, err := w.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheetNames)))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.WriteString(sheet, sheetHeaderXML)
+	if err != nil {
+		return "", err
+	}
+	w.sheet = sheet
+	w.rows = 0
+	return name, nil
+}
+
+// Returns the Excel name of a column: A to Z, then AA, AB, ...
+func columnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
+
+// WriteRow adds a row to the current sheet. Values must be strings, ints, int64s or float64s.
+func (w *Writer) WriteRow(values ...interface{}) error {
+	if w.sheet == nil {
+		return errNoSheet
+	}
+	// check the types first so an error does not leave a partial row
+	for _, value := range values {
+		switch value.(type) {
+		case string, int, int64, float64:
+		default:
+			return fmt.Errorf("xlsx: unsupported value type %T", value)
+		}
+	}
+	w.rows++
+	row := strconv.Itoa(w.rows)
+	_, err := io.WriteString(w.sheet, `<row r="`+row+`">`)
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		cell := columnName(i) + row
+		var number string
+		switch v := value.(type) {
+		case string:
+			_, err = io.WriteString(w.sheet, `<c r="`+cell+`" t="inlineStr"><is><t xml:space="preserve">`)
+			if err != nil {
+				return err
+			}
+			err = xml.EscapeText(w.sheet, []byte(v))
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w.sheet, `</t></is></c>`)
+			if err != nil {
+				return err
+			}
+			continue
+		case int:
+			number = strconv.Itoa(v)
+		case int64:
+			number = strconv.FormatInt(v, 10)
+		case float64:
+			number = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		_, err = io.WriteString(w.sheet, `<c r="`+cell+`"><v>`+number+`</v></c>`)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w.sheet, `</row>`)
+	return err
+}
+
+// Close finishes the workbook. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if len(w.sheetNames) == 0 {
+		_, err := w.AddSheet(defaultSheetName)
+		if err != nil {
+			return err
+		}
+	}
+	err := w.endSheet()
+	if err != nil {
+		return err
+	}
+
+	workbook := &strings.Builder{}
+	rels := &strings.Builder{}
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+		` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, name := range w.sheetNames {
+		workbook.WriteString(`<sheet name="`)
+		xml.EscapeText(workbook, []byte(name))
+		fmt.Fprintf(workbook, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+		fmt.Fprintf(rels, `<Relationship Id="rId%d"`+
+			` Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"`+
+			` Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+
+	err = w.writeFile("xl/workbook.xml", workbook.String())
+	if err != nil {
+		return err
+	}
+	err = w.writeFile("xl/_rels/workbook.xml.rels", rels.String())
+	if err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
@@ -0,0 +1,157 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// Returns the contents of each file in an xlsx zip file, and checks that it is valid XML.
+func readFiles(t *testing.T, data []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range reader.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoder := xml.NewDecoder(bytes.NewReader(contents))
+		for {
+			_, err = decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(f.Name, err)
+			}
+		}
+		files[f.Name] = string(contents)
+	}
+	return files
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteRow("x")
+	if err != errNoSheet {
+		t.Error(err)
+	}
+	name, err := w.AddSheet("first")
+	if err != nil || name != "first" {
+		t.Fatal(name, err)
+	}
+	err = w.WriteRow("a <b> & c", 42, int64(1)<<40, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteRow(true)
+	if err == nil {
+		t.Error("expected error for unsupported type")
+	}
+	name, err = w.AddSheet("FIRST")
+	if err != nil || name != "FIRST (2)" {
+		t.Fatal(name, err)
+	}
+	err = w.WriteRow("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readFiles(t, buf.Bytes())
+	if len(files) != 6 {
+		t.Error(files)
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">a &lt;b&gt; &amp; c</t></is></c>`,
+		`<c r="B1"><v>42</v></c>`, `<c r="C1"><v>1099511627776</v></c>`, `<c r="D1"><v>0.5</v></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Error(expected, sheet)
+		}
+	}
+	if !strings.Contains(files["xl/worksheets/sheet2.xml"], "second") {
+		t.Error(files["xl/worksheets/sheet2.xml"])
+	}
+	workbook := files["xl/workbook.xml"]
+	if !(strings.Contains(workbook, `<sheet name="first" sheetId="1" r:id="rId1"/>`) &&
+		strings.Contains(workbook, `<sheet name="FIRST (2)" sheetId="2" r:id="rId2"/>`)) {
+		t.Error(workbook)
+	}
+	if !strings.Contains(files["xl/_rels/workbook.xml.rels"], `Target="worksheets/sheet2.xml"`) {
+		t.Error(files["xl/_rels/workbook.xml.rels"])
+	}
+}
+
+func TestEmptyWorkbook(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readFiles(t, buf.Bytes())
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Sheet1"`) {
+		t.Error(files)
+	}
+}
+
+func TestSheetNames(t *testing.T) {
+	w, err := NewWriter(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("x", 40)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a/b[c]:d*e?f\\g", "a_b_c__d_e_f_g"},
+		{"'quoted'", "quoted"},
+		{"", "Sheet1"},
+		{"", "Sheet1 (2)"},
+		{long, long[:31]},
+		{long, long[:27] + " (2)"},
+	}
+	for _, test := range tests {
+		name, err := w.AddSheet(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != test.expected {
+			t.Errorf("AddSheet(%#v) = %#v; expected %#v", test.input, name, test.expected)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for column, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ",
+		702: "AAA"} {
+		if columnName(column) != expected {
+			t.Error(column, columnName(column), expected)
+		}
+	}
+}